@@ -0,0 +1,197 @@
+// Package agent содержит цикл работы модели с инструментами: отправка сообщений,
+// выполнение вызовов инструментов и повторная отправка с результатами до финального ответа.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/entities/mappers"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+// defaultMaxIterations ограничение количества запросов к модели, если оно не задано.
+const defaultMaxIterations = 10
+
+var (
+	// ErrMaxIterations модель продолжает вызывать инструменты после MaxIterations запросов.
+	ErrMaxIterations = errors.New("agent: max iterations reached")
+	// ErrMaxCost стоимость запросов достигла MaxCost, а модель продолжает вызывать инструменты.
+	ErrMaxCost = errors.New("agent: max cost reached")
+)
+
+// ToolExecutor выполняет вызовы инструментов. Сигнатура совпадает с клиентом mcp-go,
+// поэтому клиент MCP сервера можно передать напрямую.
+type ToolExecutor interface {
+	CallTool(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error)
+}
+
+// ToolExecutorFunc позволяет использовать функцию как ToolExecutor.
+type ToolExecutorFunc func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error)
+
+// CallTool вызывает функцию.
+func (f ToolExecutorFunc) CallTool(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+	return f(ctx, request)
+}
+
+// ToolHandlers выполняет вызовы инструментов обработчиками по названию инструмента.
+type ToolHandlers map[string]ToolExecutorFunc
+
+// CallTool находит обработчик инструмента и вызывает его.
+func (h ToolHandlers) CallTool(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+	handler, exists := h[request.Params.Name]
+	if !exists {
+		return nil, fmt.Errorf("unknown tool %q", request.Params.Name)
+	}
+	return handler(ctx, request)
+}
+
+// Limits ограничивает работу агента.
+type Limits struct {
+	MaxIterations int             // Максимальное количество запросов к модели (по умолчанию 10)
+	MaxCost       decimal.Decimal // Максимальная стоимость запросов в рублях (0 - без ограничения)
+	Timeout       time.Duration   // Максимальное время работы, включая выполнение инструментов (0 - без ограничения)
+}
+
+// Result содержит итог работы агента.
+type Result struct {
+	Messages      []*entities.Message                  // Вся переписка: исходные сообщения, ответы модели и результаты инструментов
+	Response      *entities.ProviderMessageResponseDTO // Последний ответ модели
+	Iterations    int                                  // Количество запросов к модели
+	TotalTokens   int64                                // Сумма токенов всех запросов
+	PriceInRubles decimal.Decimal                      // Сумма стоимости всех запросов
+}
+
+// Runner отправляет сообщения модели и выполняет вызовы инструментов, пока модель не ответит без них.
+type Runner struct {
+	provider    provider.Provider    // Провайдер модели
+	model       entities.ModelName   // Модель
+	executor    ToolExecutor         // Исполнитель вызовов инструментов
+	limits      Limits               // Ограничения
+	toolsMapper *mappers.ToolsMapper // Маппер для форматирования результатов инструментов
+}
+
+// NewRunner создает агента для модели modelName провайдера p.
+func NewRunner(p provider.Provider, modelName entities.ModelName, executor ToolExecutor, limits Limits) *Runner {
+	if limits.MaxIterations <= 0 {
+		limits.MaxIterations = defaultMaxIterations
+	}
+	return &Runner{
+		provider:    p,
+		model:       modelName,
+		executor:    executor,
+		limits:      limits,
+		toolsMapper: mappers.NewToolsMapper(),
+	}
+}
+
+// Run отправляет сообщения модели с опциями opts (обычно с options.WithMCPTools), выполняет вызовы инструментов
+// параллельно и отправляет результаты обратно, пока модель не ответит без вызовов инструментов.
+// Ошибка инструмента передается модели как результат с IsError, чтобы она могла ее учесть.
+// При превышении ограничений возвращается ErrMaxIterations, ErrMaxCost или context.DeadlineExceeded
+// вместе с результатом, накопленным к этому моменту.
+func (r *Runner) Run(ctx context.Context, messages []*entities.Message, opts ...options.SendMessageOption) (*Result, error) {
+	if r.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.limits.Timeout)
+		defer cancel()
+	}
+
+	result := &Result{
+		Messages:      append([]*entities.Message(nil), messages...),
+		PriceInRubles: decimal.Zero,
+	}
+
+	for {
+		if result.Iterations >= r.limits.MaxIterations {
+			return result, fmt.Errorf("%w: %d", ErrMaxIterations, r.limits.MaxIterations)
+		}
+		if r.limits.MaxCost.IsPositive() && result.PriceInRubles.GreaterThanOrEqual(r.limits.MaxCost) {
+			return result, fmt.Errorf("%w: spent %s of %s rubles", ErrMaxCost, result.PriceInRubles, r.limits.MaxCost)
+		}
+
+		response, err := r.provider.SendMessage(ctx, result.Messages, r.model, opts...)
+		if err != nil {
+			return result, fmt.Errorf("iteration %d: %w", result.Iterations+1, err)
+		}
+		result.Iterations++
+		result.Response = response
+		result.TotalTokens += response.TotalTokens
+		result.PriceInRubles = result.PriceInRubles.Add(response.PriceInRubles)
+		result.Messages = append(result.Messages, &entities.Message{
+			MessageText: response.MessageText,
+			AuthorType:  entities.AuthorTypeRobot,
+			MessageType: entities.MessageText,
+			ToolCalls:   response.ToolCalls,
+			ToolCallIDs: response.ToolCallIDs,
+		})
+
+		if len(response.ToolCalls) == 0 {
+			return result, nil
+		}
+		if len(response.ToolCallIDs) != len(response.ToolCalls) {
+			return result, fmt.Errorf("iteration %d: response has %d tool calls but %d tool_call_ids", result.Iterations, len(response.ToolCalls), len(response.ToolCallIDs))
+		}
+
+		toolMessages, err := r.callTools(ctx, response)
+		if err != nil {
+			return result, err
+		}
+		result.Messages = append(result.Messages, toolMessages...)
+	}
+}
+
+// callTools выполняет вызовы инструментов из ответа параллельно и возвращает сообщения с результатами
+// в порядке вызовов.
+func (r *Runner) callTools(ctx context.Context, response *entities.ProviderMessageResponseDTO) ([]*entities.Message, error) {
+	results := make([]mcpgo.CallToolResult, len(response.ToolCalls))
+
+	var wg sync.WaitGroup
+	for i, call := range response.ToolCalls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.callTool(ctx, call)
+		}()
+	}
+	wg.Wait()
+
+	// Результаты инструментов, прерванных по таймауту, модели не отправляем
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	toolMessages := make([]*entities.Message, len(results))
+	for i, toolResult := range results {
+		content, err := r.toolsMapper.MCPToolResultToContent(toolResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format result of tool %s: %w", response.ToolCalls[i].Params.Name, err)
+		}
+		toolMessages[i] = &entities.Message{
+			MessageText: content,
+			AuthorType:  entities.AuthorTypeTool,
+			MessageType: entities.MessageText,
+			ToolCallIDs: []string{response.ToolCallIDs[i]},
+		}
+	}
+	return toolMessages, nil
+}
+
+// callTool выполняет один вызов инструмента. Ошибка исполнителя превращается в результат с IsError.
+func (r *Runner) callTool(ctx context.Context, call mcpgo.CallToolRequest) mcpgo.CallToolResult {
+	toolResult, err := r.executor.CallTool(ctx, call)
+	if err != nil {
+		return mappers.CreateErrorContent(err.Error())
+	}
+	if toolResult == nil {
+		return mcpgo.CallToolResult{}
+	}
+	return *toolResult
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/entities/mappers"
+	"github.com/Murolando/m_ai_provider/provider"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+func TestRunnerToolLoop(t *testing.T) {
+	mock := provider.NewMockProvider(&entities.ModelInfo{Alias: "gpt-4o", PriceInRubles: decimal.NewFromInt(1000)})
+	toolCalls := provider.MockToolCalls(
+		provider.MockToolCall("get_weather", map[string]interface{}{"city": "Москва"}),
+		provider.MockToolCall("get_time", map[string]interface{}{"city": "Москва"}),
+	)
+	toolCalls.TotalTokens = 1000
+	answer := provider.MockText("В Москве +5, сейчас 12:00")
+	answer.TotalTokens = 500
+	mock.Reply(toolCalls, answer)
+
+	// Оба инструмента ждут друг друга, поэтому тест завершится только при параллельном выполнении
+	started := make(chan struct{}, 2)
+	wait := func(ctx context.Context) error {
+		started <- struct{}{}
+		for len(started) < 2 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil
+	}
+	executor := ToolHandlers{
+		"get_weather": func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			if err := wait(ctx); err != nil {
+				return nil, err
+			}
+			result := mappers.CreateSuccessContent("+5 в городе " + request.GetArguments()["city"].(string))
+			return &result, nil
+		},
+		"get_time": func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			if err := wait(ctx); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("time service unavailable")
+		},
+	}
+
+	runner := NewRunner(mock, "gpt-4o", executor, Limits{Timeout: 5 * time.Second})
+	result, err := runner.Run(context.Background(), []*entities.Message{{MessageText: "Погода и время в Москве?", AuthorType: entities.AuthorTypeUser}})
+	if err != nil {
+		t.Fatalf("Run() failed with error: %v", err)
+	}
+
+	if result.Iterations != 2 || result.Response.MessageText != "В Москве +5, сейчас 12:00" {
+		t.Errorf("Expected final answer after 2 iterations, got %d: %+v", result.Iterations, result.Response)
+	}
+	if result.TotalTokens != 1500 || !result.PriceInRubles.Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("Expected 1500 tokens for 1.5 rubles, got %d for %s", result.TotalTokens, result.PriceInRubles)
+	}
+
+	expected := []struct {
+		authorType string
+		text       string
+		toolCallID string
+	}{
+		{entities.AuthorTypeUser, "Погода и время в Москве?", ""},
+		{entities.AuthorTypeRobot, "", "call_1"},
+		{entities.AuthorTypeTool, "+5 в городе Москва", "call_1"},
+		{entities.AuthorTypeTool, "Error: time service unavailable", "call_2"},
+		{entities.AuthorTypeRobot, "В Москве +5, сейчас 12:00", ""},
+	}
+	if len(result.Messages) != len(expected) {
+		t.Fatalf("Expected %d messages in transcript, got %d", len(expected), len(result.Messages))
+	}
+	for i, want := range expected {
+		message := result.Messages[i]
+		if message.AuthorType != want.authorType || message.MessageText != want.text {
+			t.Errorf("Message %d: expected %s %q, got %s %q", i, want.authorType, want.text, message.AuthorType, message.MessageText)
+		}
+		if want.toolCallID != "" && (len(message.ToolCallIDs) == 0 || message.ToolCallIDs[0] != want.toolCallID) {
+			t.Errorf("Message %d: expected tool call ID %s, got %v", i, want.toolCallID, message.ToolCallIDs)
+		}
+	}
+
+	// Второй запрос к модели содержит результаты инструментов
+	if requests := mock.Requests(); len(requests) != 2 || len(requests[1].Messages) != 4 {
+		t.Errorf("Expected tool results in second request, got %+v", requests)
+	}
+}
+
+func TestRunnerLimits(t *testing.T) {
+	messages := []*entities.Message{{MessageText: "Какая погода?", AuthorType: entities.AuthorTypeUser}}
+	newMock := func() *provider.MockProvider {
+		toolCalls := provider.MockToolCalls(provider.MockToolCall("get_weather", nil))
+		toolCalls.PriceInRubles = decimal.NewFromInt(2)
+		return provider.NewMockProvider().Script(&provider.MockResponse{Response: toolCalls, Repeat: true})
+	}
+	executor := ToolExecutorFunc(func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		result := mappers.CreateSuccessContent("+5")
+		return &result, nil
+	})
+
+	result, err := NewRunner(newMock(), "gpt-4o", executor, Limits{MaxIterations: 3}).Run(context.Background(), messages)
+	if !errors.Is(err, ErrMaxIterations) || result.Iterations != 3 {
+		t.Errorf("Expected ErrMaxIterations after 3 iterations, got %v after %d", err, result.Iterations)
+	}
+
+	result, err = NewRunner(newMock(), "gpt-4o", executor, Limits{MaxCost: decimal.NewFromInt(5)}).Run(context.Background(), messages)
+	if !errors.Is(err, ErrMaxCost) || result.Iterations != 3 || !result.PriceInRubles.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("Expected ErrMaxCost after spending 6 rubles, got %v after %d iterations and %s rubles", err, result.Iterations, result.PriceInRubles)
+	}
+
+	blocking := ToolExecutorFunc(func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	result, err = NewRunner(newMock(), "gpt-4o", blocking, Limits{Timeout: 50 * time.Millisecond}).Run(context.Background(), messages)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(result.Messages) != 2 || result.Messages[1].AuthorType != entities.AuthorTypeRobot {
+		t.Errorf("Expected transcript to end with the tool call, got %d messages", len(result.Messages))
+	}
+}
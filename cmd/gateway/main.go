@@ -0,0 +1,148 @@
+// Команда gateway запускает OpenAI-совместимый HTTP шлюз поверх провайдера из конфигурации.
+//
+//	gateway -config gateway.yaml
+//
+// Пример конфигурации (переменные окружения ${NAME} подставляются при чтении):
+//
+//	addr: ":8080"
+//	provider:
+//	  name: openrouter
+//	  api_key: ${OPENROUTER_API_KEY}
+//	clients:
+//	  - name: billing
+//	    api_key: ${BILLING_GATEWAY_KEY}
+//	    models: [gpt-4o-mini, gpt-4o]
+//	  - name: analytics
+//	    api_key: ${ANALYTICS_GATEWAY_KEY}
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/gateway"
+	"github.com/Murolando/m_ai_provider/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// shutdownTimeout время на завершение активных запросов при остановке.
+const shutdownTimeout = 30 * time.Second
+
+// config конфигурация шлюза.
+type config struct {
+	Addr     string         `yaml:"addr"`     // Адрес HTTP сервера
+	Provider providerConfig `yaml:"provider"` // Провайдер, через который выполняются запросы
+	Clients  []clientConfig `yaml:"clients"`  // Клиенты шлюза
+}
+
+// providerConfig параметры создания провайдера через реестр (provider.New).
+type providerConfig struct {
+	Name    string            `yaml:"name"`     // Название провайдера в реестре
+	APIKey  string            `yaml:"api_key"`  // API ключ провайдера
+	BaseURL string            `yaml:"base_url"` // Базовый URL API
+	Extra   map[string]string `yaml:"extra"`    // Дополнительные параметры провайдера
+}
+
+// clientConfig клиент шлюза.
+type clientConfig struct {
+	Name   string   `yaml:"name"`    // Название клиента
+	APIKey string   `yaml:"api_key"` // Ключ клиента
+	Models []string `yaml:"models"`  // Разрешенные модели (пусто - все)
+}
+
+func main() {
+	configPath := flag.String("config", "gateway.yaml", "path to gateway config")
+	addr := flag.String("addr", "", "listen address (overrides config)")
+	flag.Parse()
+
+	if err := run(*configPath, *addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run читает конфигурацию и обслуживает запросы до сигнала остановки.
+func run(configPath string, addr string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if addr != "" {
+		cfg.Addr = addr
+	}
+
+	p, err := provider.New(cfg.Provider.Name, provider.Config{
+		APIKey:  cfg.Provider.APIKey,
+		BaseURL: cfg.Provider.BaseURL,
+		Extra:   cfg.Provider.Extra,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create provider %s: %w", cfg.Provider.Name, err)
+	}
+
+	clients := make([]gateway.Client, len(cfg.Clients))
+	for i, client := range cfg.Clients {
+		clients[i] = gateway.Client{Name: client.Name, APIKey: client.APIKey}
+		for _, model := range client.Models {
+			clients[i].Models = append(clients[i].Models, entities.ModelName(model))
+		}
+	}
+	handler, err := gateway.NewServer(p, clients)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Addr: cfg.Addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("gateway: serving %s provider on %s for %d clients", cfg.Provider.Name, cfg.Addr, len(clients))
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loadConfig читает конфигурацию и подставляет переменные окружения.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &config{Addr: ":8080"}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if cfg.Provider.Name == "" {
+		return nil, fmt.Errorf("config %s: provider.name is required", path)
+	}
+	if len(cfg.Clients) == 0 {
+		return nil, fmt.Errorf("config %s: at least one client is required", path)
+	}
+	return cfg, nil
+}
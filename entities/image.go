@@ -0,0 +1,58 @@
+package entities
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ImageDetailLow низкая детализация изображения (быстрее и дешевле).
+	ImageDetailLow = "low"
+	// ImageDetailHigh высокая детализация изображения (медленнее и дороже).
+	ImageDetailHigh = "high"
+	// ImageDetailAuto автоматический выбор детализации.
+	ImageDetailAuto = "auto"
+
+	// ModalityText текстовая модальность модели.
+	ModalityText = "text"
+	// ModalityImage модальность изображений.
+	ModalityImage = "image"
+)
+
+// ImageContent представляет изображение во входном сообщении.
+// Задается либо URL (https://... или data URI), либо сырыми байтами Data с MIMEType.
+type ImageContent struct {
+	URL      string `json:"url,omitempty"`       // URL изображения или data URI
+	Data     []byte `json:"data,omitempty"`      // Сырые байты изображения
+	MIMEType string `json:"mime_type,omitempty"` // MIME тип для Data, например image/png
+	Detail   string `json:"detail,omitempty"`    // Уровень детализации: low, high, auto (пусто - по умолчанию провайдера)
+}
+
+// DataURL возвращает адрес изображения для передачи провайдеру.
+// Для URL возвращает его как есть, для Data собирает data URI в base64.
+func (i ImageContent) DataURL() (string, error) {
+	switch i.Detail {
+	case "", ImageDetailLow, ImageDetailHigh, ImageDetailAuto:
+	default:
+		return "", fmt.Errorf("unknown image detail %q", i.Detail)
+	}
+
+	if i.URL != "" {
+		if len(i.Data) > 0 {
+			return "", fmt.Errorf("image must have either url or data, not both")
+		}
+		if !strings.HasPrefix(i.URL, "https://") && !strings.HasPrefix(i.URL, "http://") && !strings.HasPrefix(i.URL, "data:image/") {
+			return "", fmt.Errorf("image url must be http(s) url or data:image/ URI")
+		}
+		return i.URL, nil
+	}
+
+	if len(i.Data) == 0 {
+		return "", fmt.Errorf("image has neither url nor data")
+	}
+	if !strings.HasPrefix(i.MIMEType, "image/") {
+		return "", fmt.Errorf("image data has invalid mime type %q", i.MIMEType)
+	}
+	return "data:" + i.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(i.Data), nil
+}
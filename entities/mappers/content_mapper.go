@@ -0,0 +1,149 @@
+package mappers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrUnsupportedContent возвращается для MCP контента, который нельзя передать модели (аудио, бинарные ресурсы).
+var ErrUnsupportedContent = errors.New("unsupported MCP content")
+
+// textBlobMIMETypes бинарные ресурсы с этими типами передаются модели как текст.
+var textBlobMIMETypes = map[string]bool{
+	"application/json":       true,
+	"application/xml":        true,
+	"application/yaml":       true,
+	"application/x-yaml":     true,
+	"application/javascript": true,
+	"application/sql":        true,
+}
+
+// ResourcesToMessage собирает содержимое MCP ресурсов в одно сообщение пользователя.
+// Текстовые ресурсы оборачиваются в <resource uri="..."> с адресом ресурса, изображения добавляются в Images,
+// бинарные ресурсы с текстовым типом (text/*, application/json и т.п.) декодируются как текст.
+func ResourcesToMessage(resources []mcpgo.ResourceContents) (*entities.Message, error) {
+	message := &entities.Message{AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText}
+	var texts []string
+	for i, resource := range resources {
+		text, image, err := resourceContent(resource)
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+		if image != nil {
+			message.Images = append(message.Images, *image)
+		} else {
+			texts = append(texts, text)
+		}
+	}
+	if len(message.Images) > 0 {
+		message.MessageType = entities.MessageImage
+	}
+	message.MessageText = strings.Join(texts, "\n\n")
+	return message, nil
+}
+
+// PromptMessagesToMessages конвертирует сообщения MCP промпта во внутренние сообщения.
+// Встроенные ресурсы конвертируются так же, как в ResourcesToMessage.
+func PromptMessagesToMessages(promptMessages []mcpgo.PromptMessage) ([]*entities.Message, error) {
+	messages := make([]*entities.Message, len(promptMessages))
+	for i, promptMessage := range promptMessages {
+		message := &entities.Message{AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText}
+		if promptMessage.Role == mcpgo.RoleAssistant {
+			message.AuthorType = entities.AuthorTypeRobot
+		}
+
+		if text, ok := mcpgo.AsTextContent(promptMessage.Content); ok {
+			message.MessageText = text.Text
+		} else if image, ok := mcpgo.AsImageContent(promptMessage.Content); ok {
+			imageContent, err := decodeImage(image.Data, image.MIMEType)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			message.MessageType = entities.MessageImage
+			message.Images = []entities.ImageContent{*imageContent}
+		} else if embedded, ok := mcpgo.AsEmbeddedResource(promptMessage.Content); ok {
+			text, image, err := resourceContent(embedded.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			if image != nil {
+				message.MessageType = entities.MessageImage
+				message.Images = []entities.ImageContent{*image}
+			} else {
+				message.MessageText = text
+			}
+		} else {
+			return nil, fmt.Errorf("message %d: %w: %T", i, ErrUnsupportedContent, promptMessage.Content)
+		}
+		messages[i] = message
+	}
+	return messages, nil
+}
+
+// resourceContent возвращает текст ресурса или изображение, если ресурс - изображение.
+func resourceContent(resource mcpgo.ResourceContents) (string, *entities.ImageContent, error) {
+	switch r := resource.(type) {
+	case *mcpgo.TextResourceContents:
+		return formatResourceText(r.URI, r.MIMEType, r.Text), nil, nil
+	case mcpgo.TextResourceContents:
+		return formatResourceText(r.URI, r.MIMEType, r.Text), nil, nil
+	case *mcpgo.BlobResourceContents:
+		return blobResourceContent(r)
+	case mcpgo.BlobResourceContents:
+		return blobResourceContent(&r)
+	default:
+		return "", nil, fmt.Errorf("%w: %T", ErrUnsupportedContent, resource)
+	}
+}
+
+// blobResourceContent декодирует бинарный ресурс как изображение или текст.
+func blobResourceContent(resource *mcpgo.BlobResourceContents) (string, *entities.ImageContent, error) {
+	mimeType := strings.ToLower(resource.MIMEType)
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		image, err := decodeImage(resource.Blob, resource.MIMEType)
+		if err != nil {
+			return "", nil, fmt.Errorf("resource %s: %w", resource.URI, err)
+		}
+		return "", image, nil
+	case isTextMIMEType(mimeType):
+		data, err := base64.StdEncoding.DecodeString(resource.Blob)
+		if err != nil {
+			return "", nil, fmt.Errorf("resource %s: failed to decode blob: %w", resource.URI, err)
+		}
+		return formatResourceText(resource.URI, resource.MIMEType, string(data)), nil, nil
+	default:
+		return "", nil, fmt.Errorf("%w: resource %s has binary type %q", ErrUnsupportedContent, resource.URI, resource.MIMEType)
+	}
+}
+
+// decodeImage декодирует изображение из base64.
+func decodeImage(data string, mimeType string) (*entities.ImageContent, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return &entities.ImageContent{Data: decoded, MIMEType: mimeType}, nil
+}
+
+// isTextMIMEType проверяет, что бинарный ресурс содержит текст.
+func isTextMIMEType(mimeType string) bool {
+	if mediaType, _, found := strings.Cut(mimeType, ";"); found {
+		mimeType = strings.TrimSpace(mediaType)
+	}
+	return strings.HasPrefix(mimeType, "text/") || strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml") ||
+		textBlobMIMETypes[mimeType]
+}
+
+// formatResourceText оборачивает текст ресурса в тег с адресом, чтобы модель могла сослаться на источник.
+func formatResourceText(uri string, mimeType string, text string) string {
+	if mimeType == "" {
+		return fmt.Sprintf("<resource uri=%q>\n%s\n</resource>", uri, text)
+	}
+	return fmt.Sprintf("<resource uri=%q mime_type=%q>\n%s\n</resource>", uri, mimeType, text)
+}
@@ -0,0 +1,50 @@
+package mappers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestResourcesToMessage(t *testing.T) {
+	message, err := ResourcesToMessage([]mcpgo.ResourceContents{
+		&mcpgo.TextResourceContents{URI: "file:///notes.txt", Text: "Заметки"},
+		mcpgo.BlobResourceContents{URI: "file:///readme.md", MIMEType: "text/markdown; charset=utf-8", Blob: "IyDQn9GA0L7QtdC60YI="},
+	})
+	if err != nil {
+		t.Fatalf("ResourcesToMessage() failed with error: %v", err)
+	}
+	expected := "<resource uri=\"file:///notes.txt\">\nЗаметки\n</resource>\n\n" +
+		"<resource uri=\"file:///readme.md\" mime_type=\"text/markdown; charset=utf-8\">\n# Проект\n</resource>"
+	if message.AuthorType != entities.AuthorTypeUser || message.MessageType != entities.MessageText || message.MessageText != expected {
+		t.Errorf("Expected text resources in user message, got %+v", message)
+	}
+
+	_, err = ResourcesToMessage([]mcpgo.ResourceContents{mcpgo.BlobResourceContents{URI: "file:///app.bin", Blob: "AA=="}})
+	if !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("Expected ErrUnsupportedContent for binary resource, got %v", err)
+	}
+}
+
+func TestPromptMessagesToMessages(t *testing.T) {
+	messages, err := PromptMessagesToMessages([]mcpgo.PromptMessage{
+		mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewImageContent("iVA=", "image/png")),
+		mcpgo.NewPromptMessage(mcpgo.RoleAssistant, mcpgo.NewTextContent("Это график")),
+	})
+	if err != nil {
+		t.Fatalf("PromptMessagesToMessages() failed with error: %v", err)
+	}
+	if messages[0].MessageType != entities.MessageImage || len(messages[0].Images) != 1 || messages[0].Images[0].MIMEType != "image/png" {
+		t.Errorf("Expected image message, got %+v", messages[0])
+	}
+	if messages[1].AuthorType != entities.AuthorTypeRobot || messages[1].MessageText != "Это график" {
+		t.Errorf("Expected assistant message, got %+v", messages[1])
+	}
+
+	_, err = PromptMessagesToMessages([]mcpgo.PromptMessage{mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewAudioContent("", "audio/wav"))})
+	if !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("Expected ErrUnsupportedContent for audio, got %v", err)
+	}
+}
@@ -16,6 +16,11 @@ const (
 	AuthorTypeRobot = "terminator"
 	// AuthorTypeTool представляет тип автора сообщения - результат выполнения инструмента.
 	AuthorTypeTool = "tool"
+	// AuthorTypeSystem представляет тип автора сообщения - системная инструкция (system prompt).
+	AuthorTypeSystem = "system"
+	// AuthorTypeDeveloper представляет тип автора сообщения - инструкция разработчика.
+	// Провайдеры без отдельной роли developer передают такие сообщения как системные.
+	AuthorTypeDeveloper = "developer"
 
 	// MessageText представляет тип сообщения - текст.
 	MessageText = "message_text"
@@ -35,9 +40,20 @@ const (
 
 // ModelInfo содержит информацию о модели AI.
 type ModelInfo struct {
-	Name          string          `json:"name"`            // Человекочитаемое название модели
-	Alias         ModelName       `json:"alias"`           // Алиас модели для использования в API
-	PriceInRubles decimal.Decimal `json:"price_in_rubles"` // Цена модели в рублях
+	Name            string          `json:"name"`                       // Человекочитаемое название модели
+	Alias           ModelName       `json:"alias"`                      // Алиас модели для использования в API
+	PriceInRubles   decimal.Decimal `json:"price_in_rubles"`            // Цена модели в рублях
+	InputModalities []string        `json:"input_modalities,omitempty"` // Поддерживаемые входные модальности (text, image, ...)
+}
+
+// SupportsInputModality проверяет, принимает ли модель на вход указанную модальность.
+func (m *ModelInfo) SupportsInputModality(modality string) bool {
+	for _, inputModality := range m.InputModalities {
+		if inputModality == modality {
+			return true
+		}
+	}
+	return false
 }
 
 // Message представляет сообщение в чате.
@@ -49,6 +65,8 @@ type Message struct {
 
 	ToolCalls   []mcpgo.CallToolRequest `json:"tool_calls,omitempty"`                       // Вызовы инструментов (для AuthorTypeRobot)
 	ToolCallIDs []string                `json:"tool_call_ids,omitempty"`                    // ID вызовов инструментов (для AuthorTypeRobot)
+
+	Images []ImageContent `json:"images,omitempty"` // Изображения (для AuthorTypeUser, MessageType = MessageImage)
 }
 
 // ProviderMessageResponseDTO содержит ответ от AI провайдера.
@@ -61,4 +79,6 @@ type ProviderMessageResponseDTO struct {
 	ToolCalls    []mcpgo.CallToolRequest `json:"tool_calls,omitempty"`     // Вызовы инструментов в MCP формате
 	ToolCallIDs  []string                `json:"tool_call_ids,omitempty"`  // ID вызовов инструментов от модели
 	FinishReason *string                 `json:"finish_reason,omitempty"`  // Причина завершения (stop, tool_calls, length, etc.)
+
+	Provider string `json:"provider,omitempty"` // Провайдер, выполнивший запрос (заполняет provider.Router)
 }
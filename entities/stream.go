@@ -0,0 +1,29 @@
+package entities
+
+const (
+	// StreamChunkText представляет chunk с частью текста ответа.
+	StreamChunkText = "text"
+	// StreamChunkToolCall представляет chunk с частью вызова инструмента.
+	StreamChunkToolCall = "tool_call"
+	// StreamChunkFinal представляет последний chunk с итоговым ответом, токенами и стоимостью.
+	StreamChunkFinal = "final"
+	// StreamChunkError представляет chunk с ошибкой, после него поток закрывается.
+	StreamChunkError = "error"
+)
+
+// ToolCallDelta содержит часть вызова инструмента, полученную в streaming режиме.
+type ToolCallDelta struct {
+	Index          int    `json:"index"`                     // Порядковый номер вызова инструмента в ответе
+	ID             string `json:"id,omitempty"`              // ID вызова (приходит в первом chunk для этого вызова)
+	Name           string `json:"name,omitempty"`            // Имя инструмента (приходит в первом chunk для этого вызова)
+	ArgumentsDelta string `json:"arguments_delta,omitempty"` // Часть JSON строки аргументов
+}
+
+// StreamChunk представляет один элемент потокового ответа от AI провайдера.
+type StreamChunk struct {
+	Type          string                      `json:"type"`                      // Тип chunk: text, tool_call, final, error
+	TextDelta     string                      `json:"text_delta,omitempty"`      // Часть текста ответа (для StreamChunkText)
+	ToolCallDelta *ToolCallDelta              `json:"tool_call_delta,omitempty"` // Часть вызова инструмента (для StreamChunkToolCall)
+	Response      *ProviderMessageResponseDTO `json:"response,omitempty"`        // Итоговый ответ с токенами, стоимостью и FinishReason (для StreamChunkFinal)
+	Err           error                       `json:"-"`                         // Ошибка генерации (для StreamChunkError)
+}
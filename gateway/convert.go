@@ -0,0 +1,318 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+// chatCompletionRequest запрос /v1/chat/completions.
+type chatCompletionRequest struct {
+	openai.ChatCompletionRequest
+	MaxCompletionTokens *int `json:"max_completion_tokens,omitempty"` // Замена max_tokens в новых версиях API OpenAI
+}
+
+// Usage содержит использование токенов и стоимость запроса.
+// Провайдеры сообщают только общее количество токенов, поэтому prompt_tokens и completion_tokens равны 0.
+type Usage struct {
+	PromptTokens     int64           `json:"prompt_tokens"`     // Количество токенов в запросе (не передается провайдерами)
+	CompletionTokens int64           `json:"completion_tokens"` // Количество токенов в ответе (не передается провайдерами)
+	TotalTokens      int64           `json:"total_tokens"`      // Суммарное количество токенов
+	PriceInRubles    decimal.Decimal `json:"price_in_rubles"`   // Стоимость запроса в рублях
+}
+
+// chatCompletionResponse ответ /v1/chat/completions.
+type chatCompletionResponse struct {
+	ID      string                        `json:"id"`      // Идентификатор ответа
+	Object  string                        `json:"object"`  // Всегда "chat.completion"
+	Created int64                         `json:"created"` // Unix-время создания ответа
+	Model   string                        `json:"model"`   // Модель из запроса
+	Choices []openai.ChatCompletionChoice `json:"choices"` // Единственный вариант ответа
+	Usage   *Usage                        `json:"usage"`   // Токены и стоимость
+}
+
+// chatCompletionChunk chunk потокового ответа /v1/chat/completions.
+type chatCompletionChunk struct {
+	ID      string                              `json:"id"`              // Идентификатор ответа, общий для всех chunk
+	Object  string                              `json:"object"`          // Всегда "chat.completion.chunk"
+	Created int64                               `json:"created"`         // Unix-время создания ответа
+	Model   string                              `json:"model"`           // Модель из запроса
+	Choices []openai.ChatCompletionStreamChoice `json:"choices"`         // Изменения ответа
+	Usage   *Usage                              `json:"usage,omitempty"` // Токены и стоимость (только в последнем chunk)
+}
+
+// convertRequest конвертирует запрос в формате OpenAI во внутренние сообщения и опции.
+func (s *Server) convertRequest(request *chatCompletionRequest) ([]*entities.Message, []options.SendMessageOption, error) {
+	if request.N != nil && *request.N != 1 {
+		return nil, nil, fmt.Errorf("n must be 1")
+	}
+	if request.Logprobs != nil && *request.Logprobs {
+		return nil, nil, fmt.Errorf("logprobs are not supported")
+	}
+	if len(request.Messages) == 0 {
+		return nil, nil, fmt.Errorf("messages must not be empty")
+	}
+
+	messages := make([]*entities.Message, len(request.Messages))
+	for i, chatMessage := range request.Messages {
+		message, err := s.convertMessage(chatMessage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		messages[i] = message
+	}
+
+	var opts []options.SendMessageOption
+	if request.Temperature != nil {
+		opts = append(opts, options.WithTemperature(*request.Temperature))
+	}
+	if request.MaxCompletionTokens != nil {
+		opts = append(opts, options.WithMaxTokens(*request.MaxCompletionTokens))
+	} else if request.MaxTokens != nil {
+		opts = append(opts, options.WithMaxTokens(*request.MaxTokens))
+	}
+	if request.TopP != nil {
+		opts = append(opts, options.WithTopP(*request.TopP))
+	}
+	if request.Stop != nil {
+		stop, err := parseStop(request.Stop)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, options.WithStop(stop...))
+	}
+	if request.Seed != nil {
+		opts = append(opts, options.WithSeed(*request.Seed))
+	}
+	if request.PresencePenalty != nil {
+		opts = append(opts, options.WithPresencePenalty(*request.PresencePenalty))
+	}
+	if request.FrequencyPenalty != nil {
+		opts = append(opts, options.WithFrequencyPenalty(*request.FrequencyPenalty))
+	}
+	if len(request.LogitBias) > 0 {
+		opts = append(opts, options.WithLogitBias(request.LogitBias))
+	}
+
+	if request.ResponseFormat != nil {
+		switch request.ResponseFormat.Type {
+		case openai.ResponseFormatText:
+		case openai.ResponseFormatJSONObject:
+			opts = append(opts, options.WithJSONObject())
+		case openai.ResponseFormatJSONSchema:
+			if request.ResponseFormat.JSONSchema == nil {
+				return nil, nil, fmt.Errorf("response_format.json_schema is required for type json_schema")
+			}
+			schema, ok := request.ResponseFormat.JSONSchema.Schema.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("response_format.json_schema.schema must be an object")
+			}
+			strict := request.ResponseFormat.JSONSchema.Strict != nil && *request.ResponseFormat.JSONSchema.Strict
+			opts = append(opts, options.WithJSONSchema(request.ResponseFormat.JSONSchema.Name, schema, strict))
+		default:
+			return nil, nil, fmt.Errorf("unknown response_format type %q", request.ResponseFormat.Type)
+		}
+	}
+
+	// tool_choice "none" запрещает вызовы инструментов, поэтому инструменты модели не передаются
+	toolChoice, _ := request.ToolChoice.(string)
+	if request.ToolChoice != nil && toolChoice != "auto" && toolChoice != "none" {
+		return nil, nil, fmt.Errorf("only \"auto\" and \"none\" tool_choice are supported")
+	}
+	if len(request.Tools) > 0 && toolChoice != "none" {
+		tools, err := s.toolsMapper.OpenAIToolsToMCP(request.Tools)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tools: %w", err)
+		}
+		opts = append(opts, options.WithMCPTools(tools))
+	}
+	return messages, opts, nil
+}
+
+// convertMessage конвертирует сообщение в формате OpenAI во внутреннее сообщение.
+func (s *Server) convertMessage(chatMessage openai.ChatMessage) (*entities.Message, error) {
+	message := &entities.Message{MessageType: entities.MessageText}
+	switch chatMessage.Role {
+	case openai.RoleSystem:
+		message.AuthorType = entities.AuthorTypeSystem
+	case openai.RoleDeveloper:
+		message.AuthorType = entities.AuthorTypeDeveloper
+	case openai.RoleUser:
+		message.AuthorType = entities.AuthorTypeUser
+	case openai.RoleAssistant:
+		message.AuthorType = entities.AuthorTypeRobot
+		for i, toolCall := range chatMessage.ToolCalls {
+			call, err := s.toolsMapper.OpenAIToolCallToMCP(toolCall)
+			if err != nil {
+				return nil, fmt.Errorf("tool_calls[%d]: %w", i, err)
+			}
+			message.ToolCalls = append(message.ToolCalls, call)
+			message.ToolCallIDs = append(message.ToolCallIDs, toolCall.ID)
+		}
+	case openai.RoleTool:
+		if chatMessage.ToolCallID == nil || *chatMessage.ToolCallID == "" {
+			return nil, fmt.Errorf("tool_call_id is required for tool messages")
+		}
+		message.AuthorType = entities.AuthorTypeTool
+		message.ToolCallIDs = []string{*chatMessage.ToolCallID}
+	default:
+		return nil, fmt.Errorf("unknown role %q", chatMessage.Role)
+	}
+
+	switch content := chatMessage.Content.(type) {
+	case nil:
+	case string:
+		message.MessageText = content
+	case []interface{}:
+		if err := convertContentParts(content, message); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("content must be a string or an array of content parts")
+	}
+	return message, nil
+}
+
+// convertContentParts разбирает части мультимодального сообщения: текст объединяется, изображения
+// по URL и в формате data URI добавляются в Images.
+func convertContentParts(content []interface{}, message *entities.Message) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to read content parts: %w", err)
+	}
+	var parts []openai.ContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("failed to parse content parts: %w", err)
+	}
+
+	var texts []string
+	for i, part := range parts {
+		switch part.Type {
+		case openai.ContentTypeText:
+			if part.Text != nil {
+				texts = append(texts, *part.Text)
+			}
+		case openai.ContentTypeImageURL:
+			if part.ImageURL == nil {
+				return fmt.Errorf("content[%d]: image_url is required", i)
+			}
+			image, err := parseImageURL(part.ImageURL)
+			if err != nil {
+				return fmt.Errorf("content[%d]: %w", i, err)
+			}
+			message.Images = append(message.Images, image)
+			message.MessageType = entities.MessageImage
+		default:
+			return fmt.Errorf("content[%d]: unsupported content type %q", i, part.Type)
+		}
+	}
+	message.MessageText = strings.Join(texts, "\n")
+	return nil
+}
+
+// parseImageURL конвертирует изображение в формате OpenAI. Data URI декодируется в Data и MIMEType.
+func parseImageURL(imageURL *openai.ImageURL) (entities.ImageContent, error) {
+	image := entities.ImageContent{URL: imageURL.URL}
+	if imageURL.Detail != nil {
+		image.Detail = *imageURL.Detail
+	}
+
+	rest, isDataURI := strings.CutPrefix(imageURL.URL, "data:")
+	if !isDataURI {
+		return image, nil
+	}
+	mediaType, encoded, found := strings.Cut(rest, ",")
+	mimeType, isBase64 := strings.CutSuffix(mediaType, ";base64")
+	if !found || !isBase64 {
+		return image, fmt.Errorf("image data URI must be base64 encoded")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return image, fmt.Errorf("failed to decode image data URI: %w", err)
+	}
+	image.URL = ""
+	image.Data = decoded
+	image.MIMEType = mimeType
+	return image, nil
+}
+
+// parseStop разбирает stop: строку или массив строк.
+func parseStop(stop interface{}) ([]string, error) {
+	switch value := stop.(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		sequences := make([]string, len(value))
+		for i, item := range value {
+			sequence, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("stop must be a string or an array of strings")
+			}
+			sequences[i] = sequence
+		}
+		return sequences, nil
+	default:
+		return nil, fmt.Errorf("stop must be a string or an array of strings")
+	}
+}
+
+// convertResponse конвертирует ответ провайдера в формат OpenAI.
+func (s *Server) convertResponse(model string, response *entities.ProviderMessageResponseDTO) (*chatCompletionResponse, error) {
+	message := openai.ChatMessage{Role: openai.RoleAssistant}
+	for i, call := range response.ToolCalls {
+		toolCall, err := s.toolsMapper.MCPToolCallToOpenAI(call)
+		if err != nil {
+			return nil, fmt.Errorf("tool call %d: %w", i, err)
+		}
+		// Сохраняем ID провайдера, чтобы клиент вернул результат с тем же tool_call_id
+		if i < len(response.ToolCallIDs) && response.ToolCallIDs[i] != "" {
+			toolCall.ID = response.ToolCallIDs[i]
+		}
+		message.ToolCalls = append(message.ToolCalls, toolCall)
+	}
+	if response.MessageText != "" || len(message.ToolCalls) == 0 {
+		message.Content = response.MessageText
+	}
+
+	return &chatCompletionResponse{
+		ID:      newCompletionID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai.ChatCompletionChoice{{Index: 0, Message: message, FinishReason: finishReason(response)}},
+		Usage:   newUsage(response),
+	}, nil
+}
+
+// finishReason возвращает причину завершения ответа (stop, если провайдер ее не передал).
+func finishReason(response *entities.ProviderMessageResponseDTO) *string {
+	if response.FinishReason != nil && *response.FinishReason != "" {
+		return response.FinishReason
+	}
+	reason := openai.FinishReasonStop
+	if len(response.ToolCalls) > 0 {
+		reason = openai.FinishReasonToolCalls
+	}
+	return &reason
+}
+
+// newUsage создает usage с токенами и стоимостью ответа.
+func newUsage(response *entities.ProviderMessageResponseDTO) *Usage {
+	return &Usage{TotalTokens: response.TotalTokens, PriceInRubles: response.PriceInRubles}
+}
+
+// newCompletionID генерирует идентификатор ответа.
+func newCompletionID() string {
+	bytes := make([]byte, 12)
+	rand.Read(bytes)
+	return "chatcmpl-" + hex.EncodeToString(bytes)
+}
@@ -0,0 +1,284 @@
+// Package gateway содержит HTTP сервер с OpenAI-совместимым API (/v1/chat/completions, /v1/models)
+// поверх любого Provider. Клиенты авторизуются своими ключами и получают доступ только к разрешенным моделям,
+// а в usage ответа кроме токенов передается стоимость запроса в рублях.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/entities/mappers"
+	"github.com/Murolando/m_ai_provider/provider"
+)
+
+// maxRequestBodyBytes ограничение размера тела запроса (изображения передаются в base64).
+const maxRequestBodyBytes = 32 << 20
+
+// defaultOwner значение owned_by для моделей провайдера без названия.
+const defaultOwner = "m_ai_provider"
+
+// Client описывает клиента шлюза.
+type Client struct {
+	Name   string               // Название клиента
+	APIKey string               // Ключ клиента, передается в заголовке Authorization: Bearer
+	Models []entities.ModelName // Разрешенные модели (пусто - все модели провайдера)
+}
+
+// allows проверяет, разрешена ли клиенту модель.
+func (c *Client) allows(modelName entities.ModelName) bool {
+	if len(c.Models) == 0 {
+		return true
+	}
+	for _, allowed := range c.Models {
+		if allowed == modelName {
+			return true
+		}
+	}
+	return false
+}
+
+// clientContextKey ключ клиента в контексте запроса.
+type clientContextKey struct{}
+
+// ClientFromContext возвращает клиента, от имени которого выполняется запрос к провайдеру.
+// Позволяет обертке над провайдером учитывать расходы или лимиты по клиентам.
+func ClientFromContext(ctx context.Context) (*Client, bool) {
+	client, ok := ctx.Value(clientContextKey{}).(*Client)
+	return client, ok
+}
+
+// Server OpenAI-совместимый HTTP сервер поверх провайдера. Реализует http.Handler.
+type Server struct {
+	provider    provider.Provider    // Провайдер, через который выполняются запросы
+	clients     []*Client            // Клиенты шлюза
+	owner       string               // Значение owned_by в списке моделей
+	toolsMapper *mappers.ToolsMapper // Маппер инструментов между OpenAI и MCP форматами
+	mux         *http.ServeMux
+}
+
+// NewServer создает шлюз для провайдера p. У каждого клиента должен быть уникальный непустой ключ.
+func NewServer(p provider.Provider, clients []Client) (*Server, error) {
+	s := &Server{
+		provider:    p,
+		owner:       defaultOwner,
+		toolsMapper: mappers.NewToolsMapper(),
+		mux:         http.NewServeMux(),
+	}
+	if namer, ok := provider.Capability[provider.Namer](p); ok {
+		s.owner = namer.Name()
+	}
+
+	keys := make(map[string]string, len(clients))
+	for i := range clients {
+		client := clients[i]
+		if client.APIKey == "" {
+			return nil, fmt.Errorf("client %q has empty API key", client.Name)
+		}
+		if name, exists := keys[client.APIKey]; exists {
+			return nil, fmt.Errorf("clients %q and %q have the same API key", name, client.Name)
+		}
+		keys[client.APIKey] = client.Name
+		s.clients = append(s.clients, &client)
+	}
+
+	s.mux.HandleFunc("POST /v1/chat/completions", s.authorize(s.handleChatCompletions))
+	s.mux.HandleFunc("GET /v1/models", s.authorize(s.handleModels))
+	return s, nil
+}
+
+// ServeHTTP обрабатывает HTTP запрос.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorize находит клиента по ключу из заголовка Authorization и передает его обработчику через контекст.
+func (s *Server) authorize(next func(w http.ResponseWriter, r *http.Request, client *Client)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || key == "" {
+			writeError(w, http.StatusUnauthorized, errorTypeAuthentication, "missing_api_key", "missing API key in Authorization header")
+			return
+		}
+
+		// Сравниваем со всеми ключами за постоянное время, чтобы не раскрывать ключи по времени ответа
+		var client *Client
+		for _, candidate := range s.clients {
+			if subtle.ConstantTimeCompare([]byte(candidate.APIKey), []byte(key)) == 1 {
+				client = candidate
+			}
+		}
+		if client == nil {
+			writeError(w, http.StatusUnauthorized, errorTypeAuthentication, "invalid_api_key", "invalid API key")
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), clientContextKey{}, client)), client)
+	}
+}
+
+// modelObject описывает модель в ответе /v1/models.
+type modelObject struct {
+	ID              string   `json:"id"`                         // Алиас модели
+	Object          string   `json:"object"`                     // Всегда "model"
+	Created         int64    `json:"created"`                    // Время создания (неизвестно, 0)
+	OwnedBy         string   `json:"owned_by"`                   // Название провайдера
+	Name            string   `json:"name"`                       // Человекочитаемое название модели
+	PriceInRubles   string   `json:"price_in_rubles"`            // Цена модели в рублях
+	InputModalities []string `json:"input_modalities,omitempty"` // Поддерживаемые входные модальности
+}
+
+// modelList ответ /v1/models.
+type modelList struct {
+	Object string        `json:"object"` // Всегда "list"
+	Data   []modelObject `json:"data"`   // Модели, доступные клиенту
+}
+
+// handleModels возвращает модели провайдера, разрешенные клиенту.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request, client *Client) {
+	models, err := s.provider.ListModels()
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	list := modelList{Object: "list", Data: make([]modelObject, 0, len(models))}
+	for _, model := range models {
+		if !client.allows(model.Alias) {
+			continue
+		}
+		list.Data = append(list.Data, modelObject{
+			ID:              string(model.Alias),
+			Object:          "model",
+			OwnedBy:         s.owner,
+			Name:            model.Name,
+			PriceInRubles:   model.PriceInRubles.String(),
+			InputModalities: model.InputModalities,
+		})
+	}
+	sort.Slice(list.Data, func(i, j int) bool { return list.Data[i].ID < list.Data[j].ID })
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleChatCompletions выполняет запрос /v1/chat/completions через провайдера.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request, client *Client) {
+	var request chatCompletionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_json", "failed to parse request body: "+err.Error())
+		return
+	}
+	if request.Model == "" {
+		writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "missing_model", "model is required")
+		return
+	}
+	modelName := entities.ModelName(request.Model)
+	if !client.allows(modelName) {
+		writeError(w, http.StatusForbidden, errorTypePermission, "model_not_allowed", fmt.Sprintf("model %s is not allowed for this API key", request.Model))
+		return
+	}
+
+	messages, opts, err := s.convertRequest(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if request.Stream != nil && *request.Stream {
+		s.streamChatCompletion(w, r, &request, messages, opts)
+		return
+	}
+
+	response, err := s.provider.SendMessage(r.Context(), messages, modelName, opts...)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	completion, err := s.convertResponse(request.Model, response)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, errorTypeAPI, "invalid_response", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// Типы ошибок в формате OpenAI.
+const (
+	errorTypeInvalidRequest = "invalid_request_error"
+	errorTypeAuthentication = "authentication_error"
+	errorTypePermission     = "permission_error"
+	errorTypeNotFound       = "not_found_error"
+	errorTypeRateLimit      = "rate_limit_error"
+	errorTypeAPI            = "api_error"
+)
+
+// errorBody ответ с ошибкой в формате OpenAI.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+// errorDetail описание ошибки.
+type errorDetail struct {
+	Message string `json:"message"`        // Текст ошибки
+	Type    string `json:"type"`           // Тип ошибки
+	Code    string `json:"code,omitempty"` // Код ошибки
+}
+
+// writeJSON отправляет ответ в формате JSON.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError отправляет ошибку в формате OpenAI.
+func writeError(w http.ResponseWriter, status int, errorType string, code string, message string) {
+	writeJSON(w, status, errorBody{Error: errorDetail{Message: message, Type: errorType, Code: code}})
+}
+
+// writeProviderError отправляет ошибку провайдера с HTTP статусом по ее категории.
+func writeProviderError(w http.ResponseWriter, err error) {
+	status, errorType, code := classifyError(err)
+	var providerErr *provider.ProviderError
+	if errors.As(err, &providerErr) && providerErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(providerErr.RetryAfter.Seconds())))
+	}
+	writeError(w, status, errorType, code, err.Error())
+}
+
+// classifyError возвращает HTTP статус, тип и код ошибки в формате OpenAI.
+// Ошибки авторизации у провайдера - проблема шлюза, а не клиента, поэтому они возвращаются как 502.
+func classifyError(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, provider.ErrRateLimit):
+		return http.StatusTooManyRequests, errorTypeRateLimit, "rate_limit_exceeded"
+	case errors.Is(err, provider.ErrQuotaExceeded):
+		return http.StatusTooManyRequests, errorTypeRateLimit, "insufficient_quota"
+	case errors.Is(err, provider.ErrModelNotFound):
+		return http.StatusNotFound, errorTypeNotFound, "model_not_found"
+	case errors.Is(err, provider.ErrContextLength):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "context_length_exceeded"
+	case errors.Is(err, provider.ErrContentFilter):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "content_filter"
+	case errors.Is(err, provider.ErrUnsupportedParameter):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "unsupported_parameter"
+	case errors.Is(err, provider.ErrUnsupportedModality):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "unsupported_modality"
+	case errors.Is(err, provider.ErrBadRequest):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request"
+	case errors.Is(err, provider.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, errorTypeAPI, "provider_unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, errorTypeAPI, "timeout"
+	case errors.Is(err, provider.ErrAuth), errors.Is(err, provider.ErrServer), errors.Is(err, provider.ErrTransport), errors.Is(err, provider.ErrDecode):
+		return http.StatusBadGateway, errorTypeAPI, "upstream_error"
+	default:
+		return http.StatusInternalServerError, errorTypeAPI, "internal_error"
+	}
+}
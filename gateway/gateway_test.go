@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+	"github.com/shopspring/decimal"
+)
+
+// newTestGateway запускает шлюз с тестовым провайдером и двумя клиентами:
+// billing с доступом только к gpt-4o-mini и admin с доступом ко всем моделям.
+func newTestGateway(t *testing.T) (*httptest.Server, *provider.MockProvider) {
+	t.Helper()
+	mock := provider.NewMockProvider(
+		&entities.ModelInfo{Name: "GPT-4o", Alias: "gpt-4o", PriceInRubles: decimal.NewFromInt(1000)},
+		&entities.ModelInfo{Name: "GPT-4o mini", Alias: "gpt-4o-mini", PriceInRubles: decimal.NewFromInt(60)},
+	)
+	handler, err := NewServer(mock, []Client{
+		{Name: "billing", APIKey: "billing-key", Models: []entities.ModelName{"gpt-4o-mini"}},
+		{Name: "admin", APIKey: "admin-key"},
+	})
+	if err != nil {
+		t.Fatalf("NewServer() failed with error: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, mock
+}
+
+// doRequest отправляет запрос к шлюзу с ключом key.
+func doRequest(t *testing.T, method string, url string, key string, body string) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestGatewayAuthAndModels(t *testing.T) {
+	server, _ := newTestGateway(t)
+
+	for _, key := range []string{"", "unknown-key"} {
+		if response := doRequest(t, http.MethodGet, server.URL+"/v1/models", key, ""); response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for key %q, got %d", key, response.StatusCode)
+		}
+	}
+
+	var list modelList
+	response := doRequest(t, http.MethodGet, server.URL+"/v1/models", "billing-key", "")
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode models: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].ID != "gpt-4o-mini" || list.Data[0].PriceInRubles != "60" || list.Data[0].OwnedBy != "Mock" {
+		t.Errorf("Expected only allowed model for billing client, got %+v", list.Data)
+	}
+
+	response = doRequest(t, http.MethodGet, server.URL+"/v1/models", "admin-key", "")
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil || len(list.Data) != 2 {
+		t.Errorf("Expected all models for admin client, got %+v (%v)", list.Data, err)
+	}
+
+	body := `{"model": "gpt-4o", "messages": [{"role": "user", "content": "Привет"}]}`
+	if response := doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "billing-key", body); response.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for model outside allowlist, got %d", response.StatusCode)
+	}
+
+	if _, err := NewServer(provider.NewMockProvider(), []Client{{Name: "a", APIKey: "key"}, {Name: "b", APIKey: "key"}}); err == nil {
+		t.Error("Expected error for duplicate API keys")
+	}
+}
+
+func TestGatewayChatCompletion(t *testing.T) {
+	server, mock := newTestGateway(t)
+	answer := provider.MockToolCalls(provider.MockToolCall("get_weather", map[string]interface{}{"city": "Москва"}))
+	answer.TotalTokens = 120
+	answer.PriceInRubles = decimal.NewFromFloat(0.12)
+	mock.Reply(answer)
+
+	body := `{
+		"model": "gpt-4o-mini",
+		"temperature": 0.2,
+		"stop": "\n\n",
+		"messages": [
+			{"role": "system", "content": "Ты синоптик"},
+			{"role": "user", "content": [{"type": "text", "text": "Что на фото?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,iVA="}}]},
+			{"role": "assistant", "tool_calls": [{"id": "call_prev", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Казань\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_prev", "content": "+3"},
+			{"role": "user", "content": "А в Москве?"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}}]
+	}`
+	response := doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "billing-key", body)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", response.StatusCode)
+	}
+
+	var completion chatCompletionResponse
+	if err := json.NewDecoder(response.Body).Decode(&completion); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	choice := completion.Choices[0]
+	if *choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "call_1" {
+		t.Errorf("Expected tool call with provider ID, got %+v", choice)
+	}
+	if choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Москва"}` || choice.Message.Content != nil {
+		t.Errorf("Expected tool call arguments without content, got %+v", choice.Message)
+	}
+	if completion.Usage.TotalTokens != 120 || !completion.Usage.PriceInRubles.Equal(decimal.NewFromFloat(0.12)) {
+		t.Errorf("Expected usage with price, got %+v", completion.Usage)
+	}
+
+	sent := mock.Requests()[0]
+	expectedAuthors := []string{entities.AuthorTypeSystem, entities.AuthorTypeUser, entities.AuthorTypeRobot, entities.AuthorTypeTool, entities.AuthorTypeUser}
+	for i, authorType := range expectedAuthors {
+		if sent.Messages[i].AuthorType != authorType {
+			t.Errorf("Message %d: expected author %s, got %s", i, authorType, sent.Messages[i].AuthorType)
+		}
+	}
+	if image := sent.Messages[1].Images; len(image) != 1 || image[0].MIMEType != "image/png" || string(image[0].Data) != "\x89P" {
+		t.Errorf("Expected decoded data URI image, got %+v", image)
+	}
+	if sent.Messages[2].ToolCallIDs[0] != "call_prev" || sent.Messages[3].ToolCallIDs[0] != "call_prev" {
+		t.Errorf("Expected tool call IDs to be kept, got %v and %v", sent.Messages[2].ToolCallIDs, sent.Messages[3].ToolCallIDs)
+	}
+	params := options.ExtractGenerationParams(sent.Options)
+	if params.Temperature == nil || *params.Temperature != 0.2 || len(params.Stop) != 1 {
+		t.Errorf("Expected temperature and stop options, got %+v", params)
+	}
+	if tools, ok := options.ExtractMCPToolsOption(sent.Options); !ok || len(tools) != 1 || tools[0].Name != "get_weather" {
+		t.Errorf("Expected tools option, got %+v", tools)
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	server, mock := newTestGateway(t)
+	mock.ReplyError(&provider.ProviderError{Kind: provider.ErrRateLimit, Provider: "Mock", RetryAfter: 7 * time.Second})
+
+	body := `{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "Привет"}]}`
+	response := doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "admin-key", body)
+	var errResponse errorBody
+	json.NewDecoder(response.Body).Decode(&errResponse)
+	if response.StatusCode != http.StatusTooManyRequests || response.Header.Get("Retry-After") != "7" || errResponse.Error.Type != errorTypeRateLimit {
+		t.Errorf("Expected 429 with Retry-After, got %d %q %+v", response.StatusCode, response.Header.Get("Retry-After"), errResponse)
+	}
+
+	mock.ReplyError(&provider.ProviderError{Kind: provider.ErrCircuitOpen, Provider: "Mock", RetryAfter: 20 * time.Second})
+	response = doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "admin-key", body)
+	if response.StatusCode != http.StatusServiceUnavailable || response.Header.Get("Retry-After") != "20" {
+		t.Errorf("Expected 503 with Retry-After for open circuit, got %d %q", response.StatusCode, response.Header.Get("Retry-After"))
+	}
+
+	invalid := []string{
+		`{"model": "gpt-4o-mini", "messages": []}`,
+		`{"model": "gpt-4o-mini", "n": 2, "messages": [{"role": "user", "content": "Привет"}]}`,
+		`{"model": "gpt-4o-mini", "messages": [{"role": "tool", "content": "+5"}]}`,
+		`{"model": "gpt-4o-mini", "tool_choice": "required", "messages": [{"role": "user", "content": "Привет"}]}`,
+		`{"messages": [{"role": "user", "content": "Привет"}]}`,
+	}
+	for _, body := range invalid {
+		if response := doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "admin-key", body); response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, response.StatusCode)
+		}
+	}
+}
+
+func TestGatewayStreaming(t *testing.T) {
+	server, mock := newTestGateway(t)
+	answer := provider.MockText("Привет из потока")
+	answer.TotalTokens = 30
+	answer.PriceInRubles = decimal.NewFromFloat(0.03)
+	mock.Reply(answer)
+
+	body := `{"model": "gpt-4o", "stream": true, "messages": [{"role": "user", "content": "Привет"}]}`
+	response := doRequest(t, http.MethodPost, server.URL+"/v1/chat/completions", "admin-key", body)
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected event stream, got %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	var text strings.Builder
+	var last chatCompletionChunk
+	done := false
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, found := strings.CutPrefix(scanner.Text(), "data: ")
+		if !found {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Failed to decode chunk %s: %v", data, err)
+		}
+		if chunk.Choices[0].Delta.Content != nil {
+			text.WriteString(*chunk.Choices[0].Delta.Content)
+		}
+		last = chunk
+	}
+
+	if !done || text.String() != "Привет из потока" {
+		t.Errorf("Expected full text and [DONE], got %q (done=%v)", text.String(), done)
+	}
+	if last.Choices[0].FinishReason == nil || *last.Choices[0].FinishReason != "stop" || last.Usage == nil || !last.Usage.PriceInRubles.Equal(decimal.NewFromFloat(0.03)) {
+		t.Errorf("Expected final chunk with finish reason and price, got %+v", last)
+	}
+}
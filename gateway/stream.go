@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+// streamChatCompletion отправляет ответ провайдера потоком Server-Sent Events в формате OpenAI.
+// Если поток не удалось открыть, ошибка возвращается обычным JSON ответом. Ошибка посреди потока
+// отправляется событием с полем error, после него поток завершается без [DONE].
+func (s *Server) streamChatCompletion(w http.ResponseWriter, r *http.Request, request *chatCompletionRequest, messages []*entities.Message, opts []options.SendMessageOption) {
+	chunks, err := s.provider.SendMessageStream(r.Context(), messages, entities.ModelName(request.Model), opts...)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writer := &eventWriter{w: w, id: newCompletionID(), model: request.Model, created: time.Now().Unix()}
+	writer.send(openai.NewRoleDelta(openai.RoleAssistant), nil, nil)
+
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			writer.send(openai.NewContentDelta(chunk.TextDelta), nil, nil)
+		case entities.StreamChunkToolCall:
+			delta := chunk.ToolCallDelta
+			toolCall := openai.ToolCallDelta{Index: &delta.Index}
+			if delta.ID != "" {
+				toolType := openai.ToolTypeFunction
+				toolCall.ID = &delta.ID
+				toolCall.Type = &toolType
+			}
+			toolCall.Function = openai.NewFunctionCallDelta(delta.Name, delta.ArgumentsDelta)
+			writer.send(openai.ChatCompletionStreamDelta{ToolCalls: []openai.ToolCallDelta{toolCall}}, nil, nil)
+		case entities.StreamChunkFinal:
+			writer.send(openai.ChatCompletionStreamDelta{}, finishReason(chunk.Response), newUsage(chunk.Response))
+			writer.done()
+			return
+		case entities.StreamChunkError:
+			_, errorType, code := classifyError(chunk.Err)
+			writer.event(errorBody{Error: errorDetail{Message: chunk.Err.Error(), Type: errorType, Code: code}})
+			return
+		}
+	}
+}
+
+// eventWriter пишет события потокового ответа.
+type eventWriter struct {
+	w       http.ResponseWriter
+	id      string // Идентификатор ответа
+	model   string // Модель из запроса
+	created int64  // Unix-время создания ответа
+}
+
+// send отправляет chunk с изменениями ответа.
+func (e *eventWriter) send(delta openai.ChatCompletionStreamDelta, finishReason *string, usage *Usage) {
+	e.event(chatCompletionChunk{
+		ID:      e.id,
+		Object:  openai.ObjectChatCompletionChunk,
+		Created: e.created,
+		Model:   e.model,
+		Choices: []openai.ChatCompletionStreamChoice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+		Usage:   usage,
+	})
+}
+
+// event отправляет событие с JSON телом.
+func (e *eventWriter) event(body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(e.w, "data: %s\n\n", data)
+	e.flush()
+}
+
+// done отправляет признак завершения потока.
+func (e *eventWriter) done() {
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	e.flush()
+}
+
+// flush отправляет клиенту записанные данные.
+func (e *eventWriter) flush() {
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -3,6 +3,7 @@ module github.com/Murolando/m_ai_provider
 go 1.25.5
 
 require (
+	github.com/invopop/jsonschema v0.13.0
 	github.com/mark3labs/mcp-go v0.44.0
 	github.com/revrost/go-openrouter v1.1.5
 	github.com/shopspring/decimal v1.4.0
@@ -13,7 +14,7 @@ require (
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
-	github.com/invopop/jsonschema v0.13.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
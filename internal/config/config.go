@@ -25,6 +25,18 @@ var HydraNamesMap map[entities.ModelName]string
 // OpenRouterNamesMap содержит маппинг внутренних названий моделей на названия в OpenRouter.
 var OpenRouterNamesMap map[entities.ModelName]string
 
+// AnthropicNamesMap содержит маппинг внутренних названий моделей на названия в Anthropic API.
+var AnthropicNamesMap map[entities.ModelName]string
+
+// GeminiNamesMap содержит маппинг внутренних названий моделей на названия в Gemini API.
+var GeminiNamesMap map[entities.ModelName]string
+
+// GigaChatNamesMap содержит маппинг внутренних названий моделей на названия в GigaChat API.
+var GigaChatNamesMap map[entities.ModelName]string
+
+// YandexGPTNamesMap содержит маппинг внутренних названий моделей на URI моделей YandexGPT без каталога.
+var YandexGPTNamesMap map[entities.ModelName]string
+
 func init() {
 	var config Config
 	if err := yaml.Unmarshal(modelsConfigData, &config); err != nil {
@@ -35,6 +47,10 @@ func init() {
 	// Инициализируем маппинги для провайдеров
 	HydraNamesMap = make(map[entities.ModelName]string)
 	OpenRouterNamesMap = make(map[entities.ModelName]string)
+	AnthropicNamesMap = make(map[entities.ModelName]string)
+	GeminiNamesMap = make(map[entities.ModelName]string)
+	GigaChatNamesMap = make(map[entities.ModelName]string)
+	YandexGPTNamesMap = make(map[entities.ModelName]string)
 
 	// Заполняем маппинг для Hydra
 	if hydraMappings, exists := config.ProviderMappings["hydra"]; exists {
@@ -49,4 +65,32 @@ func init() {
 			OpenRouterNamesMap[entities.ModelName(internalName)] = externalName
 		}
 	}
+
+	// Заполняем маппинг для Anthropic
+	if anthropicMappings, exists := config.ProviderMappings["anthropic"]; exists {
+		for internalName, externalName := range anthropicMappings {
+			AnthropicNamesMap[entities.ModelName(internalName)] = externalName
+		}
+	}
+
+	// Заполняем маппинг для Gemini
+	if geminiMappings, exists := config.ProviderMappings["gemini"]; exists {
+		for internalName, externalName := range geminiMappings {
+			GeminiNamesMap[entities.ModelName(internalName)] = externalName
+		}
+	}
+
+	// Заполняем маппинг для GigaChat
+	if gigachatMappings, exists := config.ProviderMappings["gigachat"]; exists {
+		for internalName, externalName := range gigachatMappings {
+			GigaChatNamesMap[entities.ModelName(internalName)] = externalName
+		}
+	}
+
+	// Заполняем маппинг для YandexGPT
+	if yandexMappings, exists := config.ProviderMappings["yandexgpt"]; exists {
+		for internalName, externalName := range yandexMappings {
+			YandexGPTNamesMap[entities.ModelName(internalName)] = externalName
+		}
+	}
 }
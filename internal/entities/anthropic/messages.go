@@ -0,0 +1,129 @@
+// Package anthropic содержит структуры Anthropic Messages API.
+package anthropic
+
+import "encoding/json"
+
+const (
+	// APIVersion версия API, передаваемая в заголовке anthropic-version.
+	APIVersion = "2023-06-01"
+
+	// RoleUser роль пользователя (в том числе для результатов инструментов).
+	RoleUser = "user"
+	// RoleAssistant роль ассистента.
+	RoleAssistant = "assistant"
+
+	// ContentTypeText текстовый блок.
+	ContentTypeText = "text"
+	// ContentTypeImage блок с изображением.
+	ContentTypeImage = "image"
+	// ContentTypeToolUse блок вызова инструмента моделью.
+	ContentTypeToolUse = "tool_use"
+	// ContentTypeToolResult блок с результатом вызова инструмента.
+	ContentTypeToolResult = "tool_result"
+
+	// ImageSourceBase64 изображение передается в base64.
+	ImageSourceBase64 = "base64"
+	// ImageSourceURL изображение передается ссылкой.
+	ImageSourceURL = "url"
+
+	// ToolChoiceAuto модель сама решает, вызывать ли инструменты.
+	ToolChoiceAuto = "auto"
+	// ToolChoiceTool модель обязана вызвать указанный инструмент.
+	ToolChoiceTool = "tool"
+
+	// StopReasonEndTurn естественное завершение генерации.
+	StopReasonEndTurn = "end_turn"
+	// StopReasonMaxTokens достигнут лимит max_tokens.
+	StopReasonMaxTokens = "max_tokens"
+	// StopReasonStopSequence генерация остановлена стоп-последовательностью.
+	StopReasonStopSequence = "stop_sequence"
+	// StopReasonToolUse модель вызвала инструмент.
+	StopReasonToolUse = "tool_use"
+	// StopReasonRefusal модель отказалась отвечать из соображений безопасности.
+	StopReasonRefusal = "refusal"
+)
+
+// MessagesRequest представляет запрос к Anthropic Messages API.
+// Справочник: https://docs.anthropic.com/en/api/messages
+type MessagesRequest struct {
+	Model         string      `json:"model"`                    // ID модели (обязательный)
+	Messages      []Message   `json:"messages"`                 // История диалога, роли user и assistant (обязательный)
+	MaxTokens     int         `json:"max_tokens"`               // Максимальное количество токенов в ответе (обязательный)
+	System        string      `json:"system,omitempty"`         // Системный промпт
+	Temperature   *float64    `json:"temperature,omitempty"`    // "Креативность" ответа (от 0.0 до 1.0)
+	TopP          *float64    `json:"top_p,omitempty"`          // Ядерная выборка
+	StopSequences []string    `json:"stop_sequences,omitempty"` // Последовательности для остановки генерации
+	Stream        bool        `json:"stream,omitempty"`         // true для получения ответа потоком
+	Tools         []Tool      `json:"tools,omitempty"`          // Доступные инструменты
+	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`    // Управление выбором инструментов
+}
+
+// Message представляет одно сообщение диалога.
+type Message struct {
+	Role    string         `json:"role"`    // user или assistant
+	Content []ContentBlock `json:"content"` // Блоки содержимого
+}
+
+// ContentBlock представляет блок содержимого сообщения или ответа.
+type ContentBlock struct {
+	Type      string          `json:"type"`                  // text, image, tool_use, tool_result
+	Text      string          `json:"text,omitempty"`        // Текст (для type="text")
+	Source    *ImageSource    `json:"source,omitempty"`      // Источник изображения (для type="image")
+	ID        string          `json:"id,omitempty"`          // ID вызова (для type="tool_use")
+	Name      string          `json:"name,omitempty"`        // Имя инструмента (для type="tool_use")
+	Input     json.RawMessage `json:"input,omitempty"`       // Аргументы вызова (для type="tool_use")
+	ToolUseID string          `json:"tool_use_id,omitempty"` // ID вызова (для type="tool_result")
+	Content   string          `json:"content,omitempty"`     // Результат вызова (для type="tool_result")
+}
+
+// ImageSource описывает изображение: base64 данные или URL.
+type ImageSource struct {
+	Type      string `json:"type"`                 // base64 или url
+	MediaType string `json:"media_type,omitempty"` // MIME тип (для type="base64")
+	Data      string `json:"data,omitempty"`       // Данные в base64 (для type="base64")
+	URL       string `json:"url,omitempty"`        // Ссылка на изображение (для type="url")
+}
+
+// Tool описывает инструмент, доступный модели.
+type Tool struct {
+	Name        string      `json:"name"`                  // Имя инструмента
+	Description string      `json:"description,omitempty"` // Описание инструмента
+	InputSchema interface{} `json:"input_schema"`          // JSON Schema аргументов
+}
+
+// ToolChoice управляет выбором инструментов моделью.
+type ToolChoice struct {
+	Type string `json:"type"`           // auto, any, tool или none
+	Name string `json:"name,omitempty"` // Имя инструмента (для type="tool")
+}
+
+// MessagesResponse представляет ответ Messages API.
+type MessagesResponse struct {
+	ID         string         `json:"id"`          // Уникальный идентификатор ответа
+	Model      string         `json:"model"`       // Модель, которая обработала запрос
+	Role       string         `json:"role"`        // Всегда assistant
+	Content    []ContentBlock `json:"content"`     // Блоки ответа: текст и вызовы инструментов
+	StopReason string         `json:"stop_reason"` // Причина завершения генерации
+	Usage      Usage          `json:"usage"`       // Использование токенов
+}
+
+// Usage содержит информацию об использовании токенов.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`                // Входные токены без кэша
+	OutputTokens             int64 `json:"output_tokens"`               // Токены ответа
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"` // Входные токены, записанные в кэш
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`     // Входные токены, прочитанные из кэша
+}
+
+// ModelsResponse представляет страницу списка моделей.
+type ModelsResponse struct {
+	Data    []Model `json:"data"`     // Модели на странице
+	HasMore bool    `json:"has_more"` // Есть ли следующая страница
+	LastID  string  `json:"last_id"`  // ID последней модели для запроса следующей страницы
+}
+
+// Model описывает модель из списка моделей.
+type Model struct {
+	ID          string `json:"id"`           // Идентификатор модели
+	DisplayName string `json:"display_name"` // Человекочитаемое название
+}
@@ -0,0 +1,44 @@
+package anthropic
+
+const (
+	// EventMessageStart начало ответа, содержит usage входных токенов.
+	EventMessageStart = "message_start"
+	// EventContentBlockStart начало блока содержимого (текст или вызов инструмента).
+	EventContentBlockStart = "content_block_start"
+	// EventContentBlockDelta часть блока содержимого.
+	EventContentBlockDelta = "content_block_delta"
+	// EventContentBlockStop конец блока содержимого.
+	EventContentBlockStop = "content_block_stop"
+	// EventMessageDelta изменения ответа: причина завершения и usage токенов ответа.
+	EventMessageDelta = "message_delta"
+	// EventMessageStop конец ответа.
+	EventMessageStop = "message_stop"
+	// EventPing keep-alive событие.
+	EventPing = "ping"
+	// EventError ошибка посреди потока.
+	EventError = "error"
+
+	// DeltaTypeText часть текста.
+	DeltaTypeText = "text_delta"
+	// DeltaTypeInputJSON часть JSON аргументов вызова инструмента.
+	DeltaTypeInputJSON = "input_json_delta"
+)
+
+// StreamEvent представляет событие потокового ответа Messages API.
+// Заполнены только поля, относящиеся к типу события.
+type StreamEvent struct {
+	Type         string            `json:"type"`                    // Тип события
+	Message      *MessagesResponse `json:"message,omitempty"`       // Начало ответа (для message_start)
+	Index        int               `json:"index"`                   // Индекс блока (для content_block_*)
+	ContentBlock *ContentBlock     `json:"content_block,omitempty"` // Начало блока (для content_block_start)
+	Delta        *StreamDelta      `json:"delta,omitempty"`         // Изменения (для content_block_delta и message_delta)
+	Usage        *Usage            `json:"usage,omitempty"`         // Накопленный usage (для message_delta)
+}
+
+// StreamDelta содержит изменения блока или ответа.
+type StreamDelta struct {
+	Type        string `json:"type,omitempty"`         // text_delta или input_json_delta (для content_block_delta)
+	Text        string `json:"text,omitempty"`         // Часть текста
+	PartialJSON string `json:"partial_json,omitempty"` // Часть JSON аргументов вызова инструмента
+	StopReason  string `json:"stop_reason,omitempty"`  // Причина завершения (для message_delta)
+}
@@ -0,0 +1,159 @@
+// Package gemini содержит структуры Google Gemini API (generateContent).
+package gemini
+
+const (
+	// RoleUser роль пользователя (в том числе для результатов функций).
+	RoleUser = "user"
+	// RoleModel роль модели.
+	RoleModel = "model"
+
+	// FunctionCallingModeAuto модель сама решает, вызывать ли функции.
+	FunctionCallingModeAuto = "AUTO"
+
+	// FinishReasonStop естественное завершение генерации.
+	FinishReasonStop = "STOP"
+	// FinishReasonMaxTokens достигнут лимит maxOutputTokens.
+	FinishReasonMaxTokens = "MAX_TOKENS"
+	// FinishReasonSafety ответ заблокирован фильтром безопасности.
+	FinishReasonSafety = "SAFETY"
+	// FinishReasonRecitation ответ заблокирован из-за цитирования.
+	FinishReasonRecitation = "RECITATION"
+	// FinishReasonBlocklist ответ содержит запрещенные термины.
+	FinishReasonBlocklist = "BLOCKLIST"
+	// FinishReasonProhibitedContent ответ содержит запрещенный контент.
+	FinishReasonProhibitedContent = "PROHIBITED_CONTENT"
+	// FinishReasonSPII ответ содержит чувствительные персональные данные.
+	FinishReasonSPII = "SPII"
+
+	// MIMETypeJSON MIME тип ответа в формате JSON.
+	MIMETypeJSON = "application/json"
+)
+
+// GenerateContentRequest представляет запрос к generateContent и streamGenerateContent.
+// Справочник: https://ai.google.dev/api/generate-content
+type GenerateContentRequest struct {
+	Contents          []Content         `json:"contents"`                    // История диалога (обязательный)
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"` // Системная инструкция
+	Tools             []Tool            `json:"tools,omitempty"`             // Доступные функции
+	ToolConfig        *ToolConfig       `json:"toolConfig,omitempty"`        // Управление вызовом функций
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`  // Параметры генерации
+}
+
+// Content представляет сообщение диалога.
+type Content struct {
+	Role  string `json:"role,omitempty"` // user или model
+	Parts []Part `json:"parts"`          // Части сообщения
+}
+
+// Part представляет часть сообщения: текст, изображение, вызов функции или ее результат.
+type Part struct {
+	Text             string            `json:"text,omitempty"`             // Текст
+	Thought          bool              `json:"thought,omitempty"`          // Часть содержит рассуждения модели
+	InlineData       *Blob             `json:"inlineData,omitempty"`       // Данные в base64
+	FileData         *FileData         `json:"fileData,omitempty"`         // Ссылка на файл
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`     // Вызов функции моделью
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"` // Результат вызова функции
+}
+
+// Blob содержит данные файла в base64.
+type Blob struct {
+	MimeType string `json:"mimeType"` // MIME тип данных
+	Data     string `json:"data"`     // Данные в base64
+}
+
+// FileData содержит ссылку на файл.
+type FileData struct {
+	MimeType string `json:"mimeType,omitempty"` // MIME тип файла
+	FileURI  string `json:"fileUri"`            // URI файла
+}
+
+// FunctionCall представляет вызов функции моделью.
+type FunctionCall struct {
+	ID   string                 `json:"id,omitempty"`   // ID вызова (возвращают не все модели)
+	Name string                 `json:"name"`           // Имя функции
+	Args map[string]interface{} `json:"args,omitempty"` // Аргументы функции
+}
+
+// FunctionResponse представляет результат вызова функции.
+type FunctionResponse struct {
+	ID       string                 `json:"id,omitempty"` // ID вызова
+	Name     string                 `json:"name"`         // Имя функции
+	Response map[string]interface{} `json:"response"`     // Результат вызова (JSON объект)
+}
+
+// Tool описывает набор функций, доступных модели.
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations,omitempty"` // Объявления функций
+}
+
+// FunctionDeclaration описывает функцию.
+type FunctionDeclaration struct {
+	Name        string      `json:"name"`                  // Имя функции
+	Description string      `json:"description,omitempty"` // Описание функции
+	Parameters  interface{} `json:"parameters,omitempty"`  // Схема аргументов в подмножестве OpenAPI 3.0
+}
+
+// ToolConfig управляет вызовом функций.
+type ToolConfig struct {
+	FunctionCallingConfig *FunctionCallingConfig `json:"functionCallingConfig,omitempty"` // Настройки вызова функций
+}
+
+// FunctionCallingConfig задает режим вызова функций.
+type FunctionCallingConfig struct {
+	Mode string `json:"mode"` // AUTO, ANY или NONE
+}
+
+// GenerationConfig содержит параметры генерации.
+type GenerationConfig struct {
+	Temperature      *float64    `json:"temperature,omitempty"`      // "Креативность" ответа (от 0.0 до 2.0)
+	TopP             *float64    `json:"topP,omitempty"`             // Ядерная выборка
+	MaxOutputTokens  *int        `json:"maxOutputTokens,omitempty"`  // Максимальное количество токенов в ответе
+	StopSequences    []string    `json:"stopSequences,omitempty"`    // Последовательности для остановки генерации
+	Seed             *int        `json:"seed,omitempty"`             // Seed для детерминированной генерации
+	PresencePenalty  *float64    `json:"presencePenalty,omitempty"`  // Штраф за наличие токенов
+	FrequencyPenalty *float64    `json:"frequencyPenalty,omitempty"` // Штраф за частоту токенов
+	ResponseMimeType string      `json:"responseMimeType,omitempty"` // MIME тип ответа (application/json для JSON)
+	ResponseSchema   interface{} `json:"responseSchema,omitempty"`   // Схема ответа в подмножестве OpenAPI 3.0
+}
+
+// GenerateContentResponse представляет ответ generateContent или chunk потока streamGenerateContent.
+type GenerateContentResponse struct {
+	Candidates     []Candidate     `json:"candidates"`               // Варианты ответа
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"` // Результат проверки запроса фильтрами
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`  // Использование токенов
+	ModelVersion   string          `json:"modelVersion,omitempty"`   // Версия модели
+}
+
+// Candidate представляет вариант ответа.
+type Candidate struct {
+	Content      Content `json:"content"`                // Содержимое ответа
+	FinishReason string  `json:"finishReason,omitempty"` // Причина завершения генерации
+	Index        int     `json:"index"`                  // Индекс варианта
+}
+
+// PromptFeedback содержит причину блокировки запроса.
+type PromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"` // Причина блокировки (пусто, если запрос не заблокирован)
+}
+
+// UsageMetadata содержит информацию об использовании токенов.
+type UsageMetadata struct {
+	PromptTokenCount        int64 `json:"promptTokenCount"`        // Входные токены (включая кэшированные)
+	CandidatesTokenCount    int64 `json:"candidatesTokenCount"`    // Токены ответа
+	ThoughtsTokenCount      int64 `json:"thoughtsTokenCount"`      // Токены рассуждений
+	CachedContentTokenCount int64 `json:"cachedContentTokenCount"` // Входные токены из кэша
+	TotalTokenCount         int64 `json:"totalTokenCount"`         // Все токены запроса
+}
+
+// ModelsResponse представляет страницу списка моделей.
+type ModelsResponse struct {
+	Models        []Model `json:"models"`        // Модели на странице
+	NextPageToken string  `json:"nextPageToken"` // Токен следующей страницы
+}
+
+// Model описывает модель из списка моделей.
+type Model struct {
+	Name                       string   `json:"name"`                       // Имя ресурса, например models/gemini-2.5-flash
+	DisplayName                string   `json:"displayName"`                // Человекочитаемое название
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"` // Поддерживаемые методы (generateContent, ...)
+}
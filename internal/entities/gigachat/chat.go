@@ -0,0 +1,117 @@
+// Package gigachat содержит структуры GigaChat API.
+package gigachat
+
+const (
+	// RoleSystem роль системной инструкции.
+	RoleSystem = "system"
+	// RoleUser роль пользователя.
+	RoleUser = "user"
+	// RoleAssistant роль модели.
+	RoleAssistant = "assistant"
+	// RoleFunction роль результата вызова функции.
+	RoleFunction = "function"
+
+	// FunctionCallAuto модель сама решает, вызывать ли функцию.
+	FunctionCallAuto = "auto"
+
+	// FinishReasonStop естественное завершение генерации.
+	FinishReasonStop = "stop"
+	// FinishReasonLength достигнут лимит max_tokens.
+	FinishReasonLength = "length"
+	// FinishReasonFunctionCall модель вызвала функцию.
+	FinishReasonFunctionCall = "function_call"
+	// FinishReasonBlacklist запрос попал под тематические ограничения.
+	FinishReasonBlacklist = "blacklist"
+
+	// ScopePersonal scope для физических лиц.
+	ScopePersonal = "GIGACHAT_API_PERS"
+)
+
+// ChatRequest представляет запрос к /chat/completions.
+// Справочник: https://developers.sber.ru/docs/ru/gigachat/api/reference/rest/post-chat
+type ChatRequest struct {
+	Model             string      `json:"model"`                        // Название модели (обязательный)
+	Messages          []Message   `json:"messages"`                     // История диалога
+	Functions         []Function  `json:"functions,omitempty"`          // Доступные функции
+	FunctionCall      interface{} `json:"function_call,omitempty"`      // "auto", "none" или {"name": ...}
+	Temperature       *float64    `json:"temperature,omitempty"`        // "Креативность" ответа
+	TopP              *float64    `json:"top_p,omitempty"`              // Ядерная выборка
+	MaxTokens         *int        `json:"max_tokens,omitempty"`         // Максимальное количество токенов в ответе
+	RepetitionPenalty *float64    `json:"repetition_penalty,omitempty"` // Штраф за повторы (1.0 - без штрафа)
+	Stream            bool        `json:"stream,omitempty"`             // true для ответа потоком (SSE)
+}
+
+// Message представляет сообщение диалога.
+type Message struct {
+	Role             string        `json:"role,omitempty"`               // system, user, assistant или function
+	Content          string        `json:"content"`                      // Текст сообщения (для role=function - JSON результата)
+	FunctionCall     *FunctionCall `json:"function_call,omitempty"`      // Вызов функции (для role=assistant)
+	Name             string        `json:"name,omitempty"`               // Имя функции (для role=function)
+	FunctionsStateID string        `json:"functions_state_id,omitempty"` // ID состояния вызова функции
+}
+
+// FunctionCall представляет вызов функции моделью.
+type FunctionCall struct {
+	Name      string                 `json:"name"`      // Имя функции
+	Arguments map[string]interface{} `json:"arguments"` // Аргументы в виде JSON объекта
+}
+
+// Function описывает функцию, доступную модели.
+type Function struct {
+	Name        string      `json:"name"`                  // Имя функции
+	Description string      `json:"description,omitempty"` // Описание функции
+	Parameters  interface{} `json:"parameters"`            // JSON Schema аргументов
+}
+
+// ChatResponse представляет ответ /chat/completions.
+type ChatResponse struct {
+	Choices []Choice `json:"choices"`         // Варианты ответа
+	Model   string   `json:"model"`           // Модель, которая обработала запрос
+	Usage   *Usage   `json:"usage,omitempty"` // Информация об использовании токенов
+}
+
+// Choice представляет вариант ответа.
+type Choice struct {
+	Index        int     `json:"index"`         // Индекс варианта
+	Message      Message `json:"message"`       // Сообщение модели
+	FinishReason string  `json:"finish_reason"` // Причина завершения
+}
+
+// StreamResponse представляет chunk потокового ответа.
+type StreamResponse struct {
+	Choices []StreamChoice `json:"choices"`         // Варианты ответа
+	Usage   *Usage         `json:"usage,omitempty"` // Информация об использовании токенов (в последнем chunk)
+}
+
+// StreamChoice представляет часть варианта ответа в потоке.
+type StreamChoice struct {
+	Index        int     `json:"index"`                   // Индекс варианта
+	Delta        Message `json:"delta"`                   // Новая часть сообщения
+	FinishReason string  `json:"finish_reason,omitempty"` // Причина завершения (в последнем chunk)
+}
+
+// Usage содержит информацию об использовании токенов.
+type Usage struct {
+	PromptTokens          int64 `json:"prompt_tokens"`           // Входные токены
+	CompletionTokens      int64 `json:"completion_tokens"`       // Токены ответа
+	TotalTokens           int64 `json:"total_tokens"`            // Все токены
+	PrecachedPromptTokens int64 `json:"precached_prompt_tokens"` // Входные токены из кэша (не тарифицируются)
+}
+
+// TokenResponse представляет ответ OAuth сервера.
+// Справочник: https://developers.sber.ru/docs/ru/gigachat/api/reference/rest/post-token
+type TokenResponse struct {
+	AccessToken string `json:"access_token"` // Токен доступа
+	ExpiresAt   int64  `json:"expires_at"`   // Время истечения в миллисекундах Unix
+}
+
+// ModelsResponse представляет ответ /models.
+type ModelsResponse struct {
+	Data []Model `json:"data"` // Доступные модели
+}
+
+// Model описывает модель GigaChat.
+type Model struct {
+	ID      string `json:"id"`       // Название модели, например GigaChat-2-Pro
+	OwnedBy string `json:"owned_by"` // Владелец модели
+}
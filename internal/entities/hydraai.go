@@ -24,6 +24,17 @@ type HydraChatCompletionResponse struct {
 	Usage             HydraChatCompletionUsage    `json:"usage"`                        // Информация о токенах и стоимости (Hydra-специфично)
 }
 
+// HydraChatCompletionStreamResponse представляет streaming chunk от HydraAI API.
+// Использует базовые OpenAI структуры с переопределением Usage для Hydra-специфичных полей.
+type HydraChatCompletionStreamResponse struct {
+	ID      string                              `json:"id"`              // Уникальный идентификатор чат-сессии
+	Object  string                              `json:"object"`          // Тип объекта, обычно "chat.completion.chunk"
+	Created int64                               `json:"created"`         // Unix-время создания ответа
+	Model   string                              `json:"model"`           // Модель, которая обработала запрос
+	Choices []openai.ChatCompletionStreamChoice `json:"choices"`         // Массив с дельтами вариантов ответа
+	Usage   *HydraChatCompletionUsage           `json:"usage,omitempty"` // Информация о токенах и стоимости (только в последнем chunk)
+}
+
 // HydraChatCompletionUsage содержит информацию об использовании токенов и стоимости для HydraAI.
 // Расширяет базовую OpenAI Usage структуру дополнительными полями.
 type HydraChatCompletionUsage struct {
@@ -0,0 +1,117 @@
+// Package ollama содержит структуры Ollama API.
+package ollama
+
+const (
+	// RoleSystem роль системной инструкции.
+	RoleSystem = "system"
+	// RoleUser роль пользователя.
+	RoleUser = "user"
+	// RoleAssistant роль модели.
+	RoleAssistant = "assistant"
+	// RoleTool роль результата вызова инструмента.
+	RoleTool = "tool"
+
+	// FormatJSON ответ в виде произвольного JSON.
+	FormatJSON = "json"
+
+	// DoneReasonStop естественное завершение генерации.
+	DoneReasonStop = "stop"
+	// DoneReasonLength достигнут лимит num_predict.
+	DoneReasonLength = "length"
+)
+
+// ChatRequest представляет запрос к /api/chat.
+// Справочник: https://github.com/ollama/ollama/blob/main/docs/api.md
+type ChatRequest struct {
+	Model    string      `json:"model"`             // Название модели (обязательный)
+	Messages []Message   `json:"messages"`          // История диалога
+	Tools    []Tool      `json:"tools,omitempty"`   // Доступные инструменты
+	Format   interface{} `json:"format,omitempty"`  // "json" или JSON Schema ответа
+	Options  *Options    `json:"options,omitempty"` // Параметры генерации
+	Stream   bool        `json:"stream"`            // true для ответа потоком (NDJSON)
+}
+
+// Message представляет сообщение диалога.
+type Message struct {
+	Role      string     `json:"role"`                 // system, user, assistant или tool
+	Content   string     `json:"content"`              // Текст сообщения
+	Images    []string   `json:"images,omitempty"`     // Изображения в base64 без префикса data URI
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Вызовы инструментов (для role=assistant)
+	ToolName  string     `json:"tool_name,omitempty"`  // Имя инструмента (для role=tool)
+}
+
+// ToolCall представляет вызов инструмента моделью.
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"` // Вызываемая функция
+}
+
+// ToolCallFunction содержит имя и аргументы вызова.
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`      // Имя функции
+	Arguments map[string]interface{} `json:"arguments"` // Аргументы функции
+}
+
+// Tool описывает инструмент в формате OpenAI.
+type Tool struct {
+	Type     string       `json:"type"`     // Всегда "function"
+	Function ToolFunction `json:"function"` // Описание функции
+}
+
+// ToolFunction описывает функцию.
+type ToolFunction struct {
+	Name        string      `json:"name"`                  // Имя функции
+	Description string      `json:"description,omitempty"` // Описание функции
+	Parameters  interface{} `json:"parameters,omitempty"`  // JSON Schema аргументов
+}
+
+// Options содержит параметры генерации.
+type Options struct {
+	Temperature      *float64 `json:"temperature,omitempty"`       // "Креативность" ответа
+	TopP             *float64 `json:"top_p,omitempty"`             // Ядерная выборка
+	NumPredict       *int     `json:"num_predict,omitempty"`       // Максимальное количество токенов в ответе
+	Stop             []string `json:"stop,omitempty"`              // Последовательности для остановки генерации
+	Seed             *int     `json:"seed,omitempty"`              // Seed для детерминированной генерации
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`  // Штраф за наличие токенов
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"` // Штраф за частоту токенов
+}
+
+// ChatResponse представляет ответ /api/chat или строку потока.
+type ChatResponse struct {
+	Model           string  `json:"model"`             // Модель, которая обработала запрос
+	Message         Message `json:"message"`           // Ответ модели (в потоке - его часть)
+	Done            bool    `json:"done"`              // Последняя строка потока
+	DoneReason      string  `json:"done_reason"`       // Причина завершения (только при done=true)
+	PromptEvalCount int64   `json:"prompt_eval_count"` // Входные токены (только при done=true)
+	EvalCount       int64   `json:"eval_count"`        // Токены ответа (только при done=true)
+	Error           string  `json:"error,omitempty"`   // Ошибка посреди потока
+}
+
+// TagsResponse представляет ответ /api/tags со списком локальных моделей.
+type TagsResponse struct {
+	Models []Model `json:"models"` // Установленные модели
+}
+
+// Model описывает установленную модель.
+type Model struct {
+	Name    string       `json:"name"`    // Название с тегом, например llama3.2:latest
+	Model   string       `json:"model"`   // Идентификатор модели
+	Details ModelDetails `json:"details"` // Параметры модели
+}
+
+// ModelDetails содержит параметры модели.
+type ModelDetails struct {
+	Family        string   `json:"family"`         // Семейство модели
+	Families      []string `json:"families"`       // Все семейства (clip или mllama означают поддержку изображений)
+	ParameterSize string   `json:"parameter_size"` // Размер модели, например 3.2B
+}
+
+// EmbeddingsRequest представляет запрос к /api/embeddings.
+type EmbeddingsRequest struct {
+	Model  string `json:"model"`  // Название модели
+	Prompt string `json:"prompt"` // Текст для эмбеддинга
+}
+
+// EmbeddingsResponse представляет ответ /api/embeddings.
+type EmbeddingsResponse struct {
+	Embedding []float64 `json:"embedding"` // Вектор эмбеддинга
+}
@@ -11,6 +11,7 @@ type ChatCompletionRequest struct {
 	TopP             *float64               `json:"top_p,omitempty"`             // Ядерная выборка (от 0.0 до 1.0, по умолчанию 1.0)
 	N                *int                   `json:"n,omitempty"`                 // Количество вариантов ответа (по умолчанию 1)
 	Stream           *bool                  `json:"stream,omitempty"`            // true для получения ответа в виде потока
+	StreamOptions    *StreamOptions         `json:"stream_options,omitempty"`    // Настройки потока (только при stream=true)
 	Stop             interface{}            `json:"stop,omitempty"`              // Строка или массив строк для остановки генерации
 	PresencePenalty  *float64               `json:"presence_penalty,omitempty"`  // Штраф за наличие токенов (от -2.0 до 2.0)
 	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty"` // Штраф за частоту токенов (от -2.0 до 2.0)
@@ -48,7 +49,16 @@ type ImageURL struct {
 
 // ResponseFormat определяет формат ответа от модели.
 type ResponseFormat struct {
-	Type string `json:"type"` // "text" или "json_object"
+	Type       string              `json:"type"`                  // "text", "json_object" или "json_schema"
+	JSONSchema *ResponseJSONSchema `json:"json_schema,omitempty"` // JSON Schema ответа (для type="json_schema")
+}
+
+// ResponseJSONSchema описывает JSON Schema, которой должен соответствовать ответ модели.
+type ResponseJSONSchema struct {
+	Name        string      `json:"name"`                  // Имя схемы (a-z, A-Z, 0-9, _ и -)
+	Description *string     `json:"description,omitempty"` // Описание ожидаемого ответа
+	Schema      interface{} `json:"schema"`                // JSON Schema ответа
+	Strict      *bool       `json:"strict,omitempty"`      // true для строгого соблюдения схемы
 }
 
 // ChatCompletionResponse представляет ответ от OpenAI Chat Completions API.
@@ -112,6 +122,7 @@ type TopLogprob struct {
 // Константы для ролей сообщений
 const (
 	RoleSystem    = "system"    // Системное сообщение
+	RoleDeveloper = "developer" // Инструкция разработчика (новые модели OpenAI)
 	RoleUser      = "user"      // Сообщение пользователя
 	RoleAssistant = "assistant" // Сообщение ассистента
 	RoleTool      = "tool"      // Результат выполнения инструмента
@@ -135,6 +146,7 @@ const (
 const (
 	ResponseFormatText       = "text"        // Обычный текстовый ответ
 	ResponseFormatJSONObject = "json_object" // Ответ в формате JSON
+	ResponseFormatJSONSchema = "json_schema" // Ответ в формате JSON по заданной схеме
 )
 
 // Константы для уровня детализации изображений
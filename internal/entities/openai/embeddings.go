@@ -0,0 +1,20 @@
+package openai
+
+// EmbeddingRequest представляет запрос к OpenAI Embeddings API.
+// Справочник: https://platform.openai.com/docs/api-reference/embeddings
+type EmbeddingRequest struct {
+	Model string   `json:"model"` // ID модели эмбеддингов (обязательный)
+	Input []string `json:"input"` // Тексты для эмбеддинга (обязательный)
+}
+
+// EmbeddingResponse представляет ответ Embeddings API.
+type EmbeddingResponse struct {
+	Data  []Embedding `json:"data"`            // Эмбеддинги в порядке индексов входа
+	Usage *Usage      `json:"usage,omitempty"` // Информация об использовании токенов
+}
+
+// Embedding представляет эмбеддинг одного текста.
+type Embedding struct {
+	Index     int       `json:"index"`     // Индекс текста во входном массиве
+	Embedding []float64 `json:"embedding"` // Вектор эмбеддинга
+}
@@ -12,6 +12,11 @@ type ChatCompletionStreamResponse struct {
 	Usage             *Usage                        `json:"usage,omitempty"`              // Информация об использовании токенов (только в последнем chunk)
 }
 
+// StreamOptions содержит настройки streaming ответа.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"` // true, чтобы получить usage в последнем chunk
+}
+
 // ChatCompletionStreamChoice представляет один вариант ответа в streaming режиме.
 type ChatCompletionStreamChoice struct {
 	Index        int                        `json:"index"`                  // Индекс варианта ответа в массиве choices
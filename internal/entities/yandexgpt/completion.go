@@ -0,0 +1,141 @@
+// Package yandexgpt содержит структуры Yandex Foundation Models API и IAM.
+package yandexgpt
+
+const (
+	// RoleSystem роль системной инструкции.
+	RoleSystem = "system"
+	// RoleUser роль пользователя (в том числе результатов инструментов).
+	RoleUser = "user"
+	// RoleAssistant роль модели.
+	RoleAssistant = "assistant"
+
+	// StatusPartial часть ответа в потоке.
+	StatusPartial = "ALTERNATIVE_STATUS_PARTIAL"
+	// StatusTruncatedFinal ответ обрезан по лимиту maxTokens.
+	StatusTruncatedFinal = "ALTERNATIVE_STATUS_TRUNCATED_FINAL"
+	// StatusFinal естественное завершение генерации.
+	StatusFinal = "ALTERNATIVE_STATUS_FINAL"
+	// StatusContentFilter ответ заблокирован фильтром контента.
+	StatusContentFilter = "ALTERNATIVE_STATUS_CONTENT_FILTER"
+	// StatusToolCalls модель вызвала инструменты.
+	StatusToolCalls = "ALTERNATIVE_STATUS_TOOL_CALLS"
+)
+
+// CompletionRequest представляет запрос к /foundationModels/v1/completion.
+// Справочник: https://yandex.cloud/ru/docs/foundation-models/text-generation/api-ref/TextGeneration/completion
+type CompletionRequest struct {
+	ModelURI          string            `json:"modelUri"`             // URI модели, например gpt://<folder>/yandexgpt/latest
+	CompletionOptions CompletionOptions `json:"completionOptions"`    // Параметры генерации
+	Messages          []Message         `json:"messages"`             // История диалога
+	Tools             []Tool            `json:"tools,omitempty"`      // Доступные инструменты
+	JSONObject        bool              `json:"jsonObject,omitempty"` // Ответ в виде произвольного JSON
+	JSONSchema        *JSONSchema       `json:"jsonSchema,omitempty"` // Ответ по JSON Schema
+}
+
+// CompletionOptions содержит параметры генерации.
+type CompletionOptions struct {
+	Stream      bool     `json:"stream"`                // true для ответа потоком
+	Temperature *float64 `json:"temperature,omitempty"` // "Креативность" ответа
+	MaxTokens   string   `json:"maxTokens,omitempty"`   // Максимальное количество токенов в ответе (int64 строкой)
+}
+
+// Message представляет сообщение диалога: текст, вызовы инструментов или их результаты.
+type Message struct {
+	Role           string          `json:"role"`                     // system, user или assistant
+	Text           string          `json:"text,omitempty"`           // Текст сообщения
+	ToolCallList   *ToolCallList   `json:"toolCallList,omitempty"`   // Вызовы инструментов (для role=assistant)
+	ToolResultList *ToolResultList `json:"toolResultList,omitempty"` // Результаты инструментов (для role=user)
+}
+
+// ToolCallList содержит вызовы инструментов.
+type ToolCallList struct {
+	ToolCalls []ToolCall `json:"toolCalls"` // Вызовы инструментов
+}
+
+// ToolCall представляет вызов инструмента.
+type ToolCall struct {
+	FunctionCall FunctionCall `json:"functionCall"` // Вызов функции
+}
+
+// FunctionCall содержит имя и аргументы вызова.
+type FunctionCall struct {
+	Name      string                 `json:"name"`      // Имя функции
+	Arguments map[string]interface{} `json:"arguments"` // Аргументы функции
+}
+
+// ToolResultList содержит результаты инструментов.
+type ToolResultList struct {
+	ToolResults []ToolResult `json:"toolResults"` // Результаты инструментов
+}
+
+// ToolResult представляет результат инструмента.
+type ToolResult struct {
+	FunctionResult FunctionResult `json:"functionResult"` // Результат функции
+}
+
+// FunctionResult содержит результат вызова функции.
+type FunctionResult struct {
+	Name    string `json:"name"`    // Имя функции
+	Content string `json:"content"` // Результат в виде текста
+}
+
+// Tool описывает инструмент.
+type Tool struct {
+	Function Function `json:"function"` // Описание функции
+}
+
+// Function описывает функцию.
+type Function struct {
+	Name        string      `json:"name"`                  // Имя функции
+	Description string      `json:"description,omitempty"` // Описание функции
+	Parameters  interface{} `json:"parameters"`            // JSON Schema аргументов
+}
+
+// JSONSchema содержит схему структурированного ответа.
+type JSONSchema struct {
+	Schema interface{} `json:"schema"` // JSON Schema ответа
+}
+
+// CompletionResponse представляет ответ completion или строку потока.
+type CompletionResponse struct {
+	Result Result `json:"result"` // Результат генерации
+}
+
+// Result содержит варианты ответа и использование токенов.
+type Result struct {
+	Alternatives []Alternative `json:"alternatives"`           // Варианты ответа
+	Usage        *Usage        `json:"usage,omitempty"`        // Информация об использовании токенов
+	ModelVersion string        `json:"modelVersion,omitempty"` // Версия модели
+}
+
+// Alternative представляет вариант ответа. В потоке text содержит весь ответ с начала, а не дельту.
+type Alternative struct {
+	Message Message `json:"message"` // Сообщение модели
+	Status  string  `json:"status"`  // Статус генерации (ALTERNATIVE_STATUS_*)
+}
+
+// Usage содержит информацию об использовании токенов. Числа передаются строками (int64 в JSON).
+type Usage struct {
+	InputTextTokens  int64 `json:"inputTextTokens,string"`  // Входные токены
+	CompletionTokens int64 `json:"completionTokens,string"` // Токены ответа
+	TotalTokens      int64 `json:"totalTokens,string"`      // Все токены
+}
+
+// ServiceAccountKey представляет авторизованный ключ сервисного аккаунта (authorized_key.json).
+// Справочник: https://yandex.cloud/ru/docs/iam/operations/authentication/manage-authorized-keys
+type ServiceAccountKey struct {
+	ID               string `json:"id"`                 // ID ключа (kid в заголовке JWT)
+	ServiceAccountID string `json:"service_account_id"` // ID сервисного аккаунта (iss в JWT)
+	PrivateKey       string `json:"private_key"`        // Закрытый ключ RSA в PEM
+}
+
+// IAMTokenRequest представляет запрос обмена JWT на IAM токен.
+type IAMTokenRequest struct {
+	JWT string `json:"jwt"` // JWT, подписанный ключом сервисного аккаунта (PS256)
+}
+
+// IAMTokenResponse представляет ответ с IAM токеном.
+type IAMTokenResponse struct {
+	IAMToken  string `json:"iamToken"`  // IAM токен
+	ExpiresAt string `json:"expiresAt"` // Время истечения в RFC 3339
+}
@@ -0,0 +1,338 @@
+// Package schema содержит проверку JSON значений по JSON Schema.
+// Поддерживается подмножество JSON Schema, которое используют structured output API:
+// type, properties, required, additionalProperties, items, enum, const, anyOf, oneOf, allOf,
+// minimum/maximum, minLength/maxLength, minItems/maxItems и pattern.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError описывает несоответствие значения схеме.
+type ValidationError struct {
+	Path    string // Путь до значения в формате JSON Pointer, например /items/0/name
+	Message string // Описание нарушения
+}
+
+// Error возвращает текст ошибки.
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// ValidateJSON разбирает JSON документ и проверяет его по схеме.
+func ValidateJSON(schema map[string]interface{}, data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(schema, value)
+}
+
+// Validate проверяет значение, полученное через json.Unmarshal в interface{}, по схеме.
+func Validate(schema map[string]interface{}, value interface{}) error {
+	return validate(schema, value, "")
+}
+
+// validate рекурсивно проверяет значение по схеме.
+func validate(schema map[string]interface{}, value interface{}, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if err := validateType(schema, value, path); err != nil {
+		return err
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		if !containsValue(enum, value) {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value %v is not one of %v", value, enum)}
+		}
+	}
+
+	if constValue, ok := schema["const"]; ok {
+		if !equalValues(constValue, value) {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value %v is not equal to %v", value, constValue)}
+		}
+	}
+
+	if err := validateComposition(schema, value, path); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		return validateArray(schema, v, path)
+	case string:
+		return validateString(schema, v, path)
+	case float64:
+		return validateNumber(schema, v, path)
+	}
+
+	return nil
+}
+
+// validateType проверяет ключевое слово type (строка или массив типов).
+func validateType(schema map[string]interface{}, value interface{}, path string) error {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			if typeName, ok := item.(string); ok {
+				types = append(types, typeName)
+			}
+		}
+	case []string:
+		types = t
+	default:
+		return nil
+	}
+
+	actual := typeOf(value)
+	for _, expected := range types {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return nil
+		}
+	}
+	return &ValidationError{Path: path, Message: fmt.Sprintf("expected type %s, got %s", strings.Join(types, " or "), actual)}
+}
+
+// validateComposition проверяет anyOf, oneOf и allOf.
+func validateComposition(schema map[string]interface{}, value interface{}, path string) error {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, item := range allOf {
+			if err := validate(asSchema(item), value, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, item := range anyOf {
+			if validate(asSchema(item), value, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &ValidationError{Path: path, Message: "value does not match any schema in anyOf"}
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, item := range oneOf {
+			if validate(asSchema(item), value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value matches %d schemas in oneOf, expected exactly 1", matches)}
+		}
+	}
+
+	return nil
+}
+
+// validateObject проверяет properties, required и additionalProperties.
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	for _, name := range stringList(schema["required"]) {
+		if _, exists := object[name]; !exists {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Проверяем в стабильном порядке, чтобы ошибка была воспроизводимой
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propertyPath := path + "/" + escapePointer(key)
+		if propertySchema, exists := properties[key]; exists {
+			if err := validate(asSchema(propertySchema), object[key], propertyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", key)}
+			}
+		case map[string]interface{}:
+			if err := validate(additional, object[key], propertyPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateArray проверяет items, minItems и maxItems.
+func validateArray(schema map[string]interface{}, array []interface{}, path string) error {
+	if minItems, ok := number(schema["minItems"]); ok && float64(len(array)) < minItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected at least %v items, got %d", minItems, len(array))}
+	}
+	if maxItems, ok := number(schema["maxItems"]); ok && float64(len(array)) > maxItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected at most %v items, got %d", maxItems, len(array))}
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range array {
+			if err := validate(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateString проверяет minLength, maxLength и pattern.
+func validateString(schema map[string]interface{}, value string, path string) error {
+	length := float64(utf8.RuneCountInString(value))
+	if minLength, ok := number(schema["minLength"]); ok && length < minLength {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected at least %v characters, got %v", minLength, length)}
+	}
+	if maxLength, ok := number(schema["maxLength"]); ok && length > maxLength {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected at most %v characters, got %v", maxLength, length)}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+		}
+		if !re.MatchString(value) {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value %q does not match pattern %q", value, pattern)}
+		}
+	}
+
+	return nil
+}
+
+// validateNumber проверяет minimum, maximum и их exclusive варианты.
+func validateNumber(schema map[string]interface{}, value float64, path string) error {
+	if minimum, ok := number(schema["minimum"]); ok && value < minimum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v is less than minimum %v", value, minimum)}
+	}
+	if maximum, ok := number(schema["maximum"]); ok && value > maximum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v is greater than maximum %v", value, maximum)}
+	}
+	if minimum, ok := number(schema["exclusiveMinimum"]); ok && value <= minimum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v must be greater than %v", value, minimum)}
+	}
+	if maximum, ok := number(schema["exclusiveMaximum"]); ok && value >= maximum {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v must be less than %v", value, maximum)}
+	}
+	return nil
+}
+
+// typeOf возвращает название JSON типа значения.
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// asSchema приводит вложенную схему к map.
+func asSchema(value interface{}) map[string]interface{} {
+	schema, _ := value.(map[string]interface{})
+	return schema
+}
+
+// number приводит числовое ключевое слово схемы к float64.
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// stringList приводит список строк схемы (например, required) к []string.
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// containsValue проверяет, есть ли значение в списке enum.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, item := range values {
+		if equalValues(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues сравнивает JSON значения через их сериализацию.
+func equalValues(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// escapePointer экранирует имя свойства для JSON Pointer.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
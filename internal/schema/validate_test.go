@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "minLength": float64(1)},
+			"age":  map[string]interface{}{"type": "integer", "minimum": float64(0)},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}},
+			},
+		},
+		"required":             []interface{}{"name", "age"},
+		"additionalProperties": false,
+	}
+
+	if err := ValidateJSON(schema, []byte(`{"name":"Иван","age":30,"tags":["a","b"]}`)); err != nil {
+		t.Errorf("Expected valid document, got error: %v", err)
+	}
+
+	invalid := map[string]string{
+		"missing required": `{"name":"Иван"}`,
+		"wrong type":       `{"name":"Иван","age":"30"}`,
+		"not integer":      `{"name":"Иван","age":30.5}`,
+		"below minimum":    `{"name":"Иван","age":-1}`,
+		"empty string":     `{"name":"","age":1}`,
+		"enum item":        `{"name":"Иван","age":1,"tags":["c"]}`,
+		"additional":       `{"name":"Иван","age":1,"extra":true}`,
+		"not json":         `{"name":`,
+	}
+	for name, document := range invalid {
+		if err := ValidateJSON(schema, []byte(document)); err == nil {
+			t.Errorf("Expected error for %s document %s", name, document)
+		}
+	}
+}
+
+func TestValidateErrorPath(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"items": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "number"},
+			},
+		},
+	}
+
+	err := ValidateJSON(schema, []byte(`{"items":[1,"two"]}`))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if validationErr.Path != "/items/1" {
+		t.Errorf("Expected path '/items/1', got '%s'", validationErr.Path)
+	}
+}
+
+func TestValidateAnyOfNullable(t *testing.T) {
+	schema := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "null"},
+		},
+	}
+
+	if err := ValidateJSON(schema, []byte(`null`)); err != nil {
+		t.Errorf("Expected null to match anyOf, got %v", err)
+	}
+	if err := ValidateJSON(schema, []byte(`1`)); err == nil {
+		t.Error("Expected number not to match anyOf")
+	}
+}
@@ -3,12 +3,14 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/transform"
@@ -31,10 +33,32 @@ type Valute struct {
 	Value    string `xml:"Value"`     // Курс валюты к рублю
 }
 
+// currencyRequestTimeout ограничивает время запроса курса, если клиент не передан.
+const currencyRequestTimeout = 10 * time.Second
+
+// CBRDailyRatesURL адрес ежедневных курсов валют ЦБ РФ.
+const CBRDailyRatesURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+
 // GetUSDToRUBRate получает текущий курс доллара США к рублю от ЦБ РФ.
 // Возвращает курс USD/RUB или ошибку при неудачном запросе.
 func GetUSDToRUBRate() (float64, error) {
-	req, err := http.NewRequest("GET", "https://www.cbr.ru/scripts/XML_daily.asp", nil)
+	return GetUSDToRUBRateWithClient(&http.Client{Timeout: currencyRequestTimeout})
+}
+
+// GetUSDToRUBRateWithClient получает курс доллара США к рублю от ЦБ РФ через переданный HTTP клиент.
+func GetUSDToRUBRateWithClient(client *http.Client) (float64, error) {
+	return GetUSDToRUBRateFromURL(client, CBRDailyRatesURL)
+}
+
+// GetUSDToRUBRateFromURL получает курс доллара США к рублю из XML в формате ЦБ РФ по указанному адресу.
+// Используется в тестах и для зеркал API ЦБ РФ.
+func GetUSDToRUBRateFromURL(client *http.Client, url string) (float64, error) {
+	return GetUSDToRUBRateFromURLContext(context.Background(), client, url)
+}
+
+// GetUSDToRUBRateFromURLContext получает курс доллара США к рублю из XML в формате ЦБ РФ с контекстом запроса.
+func GetUSDToRUBRateFromURLContext(ctx context.Context, client *http.Client, url string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -43,7 +67,6 @@ func GetUSDToRUBRate() (float64, error) {
 	req.Header.Set("Accept", "application/xml, text/xml, */*")
 	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch currency rates: %w", err)
@@ -1,43 +1,59 @@
 package utils
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
-)
 
-func TestGetUSDToRUBRate(t *testing.T) {
-	rate, err := GetUSDToRUBRate()
-	if err != nil {
-		t.Errorf("GetUSDToRUBRate() failed with error: %v", err)
-		return
-	}
+	"golang.org/x/text/encoding/charmap"
+)
 
-	if rate <= 0 {
-		t.Errorf("Expected positive rate, got %f", rate)
-	}
+// cbrDailyXML ответ ЦБ РФ с курсами валют в кодировке windows-1251.
+const cbrDailyXML = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="16.10.2026" name="Foreign Currency Market">
+<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>Доллар США</Name><Value>81,4373</Value></Valute>
+<Valute ID="R01239"><NumCode>978</NumCode><CharCode>EUR</CharCode><Nominal>1</Nominal><Name>Евро</Name><Value>94,8791</Value></Valute>
+</ValCurs>`
 
-	if rate < 50 || rate > 200 {
-		t.Logf("Warning: USD rate seems unusual: %f", rate)
+// newCBRServer создает сервер, отдающий body в кодировке windows-1251, как ЦБ РФ.
+func newCBRServer(t *testing.T, body string) *httptest.Server {
+	encoded, err := charmap.Windows1251.NewEncoder().String(body)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
 	}
-
-	t.Logf("Current USD to RUB rate: %f", rate)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+		w.Write([]byte(encoded))
+	}))
+	t.Cleanup(server.Close)
+	return server
 }
 
-func TestGetUSDToRUBRateRealistic(t *testing.T) {
-	rate, err := GetUSDToRUBRate()
+func TestGetUSDToRUBRate(t *testing.T) {
+	server := newCBRServer(t, cbrDailyXML)
+
+	rate, err := GetUSDToRUBRateFromURL(server.Client(), server.URL)
 	if err != nil {
 		t.Errorf("GetUSDToRUBRate() failed with error: %v", err)
 		return
 	}
 
-	if rate <= 0 {
-		t.Errorf("Rate should be positive, got %f", rate)
+	if rate != 81.4373 {
+		t.Errorf("Expected rate 81.4373, got %f", rate)
 	}
+}
+
+func TestGetUSDToRUBRateErrors(t *testing.T) {
+	server := newCBRServer(t, `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="16.10.2026" name="Foreign Currency Market">
+<Valute ID="R01239"><NumCode>978</NumCode><CharCode>EUR</CharCode><Nominal>1</Nominal><Name>Евро</Name><Value>94,8791</Value></Valute>
+</ValCurs>`)
 
-	if rate > 1000 {
-		t.Errorf("Rate seems too high, got %f", rate)
+	if _, err := GetUSDToRUBRateFromURL(server.Client(), server.URL); err == nil {
+		t.Error("Expected error when USD rate is missing")
 	}
 
-	if rate < 10 {
-		t.Errorf("Rate seems too low, got %f", rate)
+	if _, err := GetUSDToRUBRateFromURL(server.Client(), server.URL+"/\x00"); err == nil {
+		t.Error("Expected error for invalid URL")
 	}
 }
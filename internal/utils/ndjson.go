@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// ReadJSONLines читает поток JSON объектов, разделенных переводом строки (NDJSON), и вызывает handler для каждой строки.
+// Пустые строки пропускаются. Чтение завершается без ошибки на конце потока, либо с ошибкой handler.
+func ReadJSONLines(r io.Reader, handler func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handler(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONLines(t *testing.T) {
+	var lines []string
+	err := ReadJSONLines(strings.NewReader("{\"a\":1}\n\n  {\"b\":2}\r\n{\"c\":3}"), func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadJSONLines() failed with error: %v", err)
+	}
+	if strings.Join(lines, ",") != `{"a":1},{"b":2},{"c":3}` {
+		t.Errorf("Unexpected lines: %v", lines)
+	}
+
+	stop := errors.New("stop")
+	err = ReadJSONLines(strings.NewReader("{}\n{}\n"), func(line []byte) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+}
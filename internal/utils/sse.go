@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+const (
+	// sseDone маркер завершения потока в OpenAI-совместимых API.
+	sseDone = "[DONE]"
+	// sseMaxLineSize максимальный размер одной строки события (большие tool calls приходят одной строкой).
+	sseMaxLineSize = 1024 * 1024
+)
+
+// ReadSSEData читает поток Server-Sent Events и вызывает handler для данных каждого события.
+// Многострочные поля data объединяются через перевод строки, комментарии и остальные поля игнорируются.
+// Чтение завершается без ошибки на конце потока или событии [DONE], либо с ошибкой handler.
+func ReadSSEData(r io.Reader, handler func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+
+	var data []byte
+	hasData := false
+
+	dispatch := func() (bool, error) {
+		if !hasData {
+			return false, nil
+		}
+		payload := data
+		data = nil
+		hasData = false
+		if string(bytes.TrimSpace(payload)) == sseDone {
+			return true, nil
+		}
+		return false, handler(payload)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		// Пустая строка завершает событие
+		if len(line) == 0 {
+			done, err := dispatch()
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+
+		// Комментарии (например, keep-alive от OpenRouter)
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		if string(field) != "data" {
+			continue
+		}
+		value = bytes.TrimPrefix(value, []byte(" "))
+
+		if hasData {
+			data = append(data, '\n')
+		}
+		data = append(data, value...)
+		hasData = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	_, err := dispatch()
+	return err
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadSSEData(t *testing.T) {
+	input := ": OPENROUTER PROCESSING\n\n" +
+		"data: {\"a\":1}\n\n" +
+		"event: message\n" +
+		"data: {\"b\":\n" +
+		"data: 2}\n\n" +
+		"data: [DONE]\n\n" +
+		"data: {\"c\":3}\n\n"
+
+	var events []string
+	err := ReadSSEData(strings.NewReader(input), func(data []byte) error {
+		events = append(events, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSSEData() failed with error: %v", err)
+	}
+
+	expected := []string{`{"a":1}`, "{\"b\":\n2}"}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Expected event %d to be %q, got %q", i, expected[i], events[i])
+		}
+	}
+}
+
+func TestReadSSEDataWithoutTrailingNewline(t *testing.T) {
+	var events []string
+	err := ReadSSEData(strings.NewReader("data:{\"a\":1}"), func(data []byte) error {
+		events = append(events, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSSEData() failed with error: %v", err)
+	}
+
+	if len(events) != 1 || events[0] != `{"a":1}` {
+		t.Errorf("Expected single event {\"a\":1}, got %v", events)
+	}
+}
+
+func TestReadSSEDataHandlerError(t *testing.T) {
+	handlerErr := errors.New("stop")
+	err := ReadSSEData(strings.NewReader("data: 1\n\ndata: 2\n\n"), func(data []byte) error {
+		return handlerErr
+	})
+	if !errors.Is(err, handlerErr) {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+}
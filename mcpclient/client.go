@@ -0,0 +1,302 @@
+// Package mcpclient подключается к MCP серверам, собирает их инструменты для options.WithMCPTools
+// и направляет вызовы инструментов от модели обратно на сервер, которому принадлежит инструмент.
+// Промпты серверов превращаются в сообщения, а ресурсы передаются в запрос через options.WithResources.
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// Transport способ подключения к MCP серверу.
+type Transport string
+
+const (
+	// TransportStdio запускает сервер как процесс и общается с ним через stdin/stdout.
+	TransportStdio Transport = "stdio"
+	// TransportSSE подключается к серверу по HTTP с Server-Sent Events.
+	TransportSSE Transport = "sse"
+	// TransportStreamableHTTP подключается к серверу по Streamable HTTP.
+	TransportStreamableHTTP Transport = "streamable_http"
+)
+
+const (
+	// DefaultSeparator разделитель названия сервера и инструмента: server__tool.
+	// Названия инструментов у провайдеров ограничены символами [a-zA-Z0-9_-], поэтому точка и слэш не подходят.
+	DefaultSeparator = "__"
+
+	defaultClientName    = "m_ai_provider"
+	defaultClientVersion = "1.0.0"
+)
+
+// serverNamePattern допустимое название сервера: оно входит в названия инструментов.
+var serverNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+var (
+	// ErrUnknownTool возвращается при вызове инструмента, которого нет ни на одном сервере.
+	ErrUnknownTool = errors.New("unknown MCP tool")
+	// ErrUnknownPrompt возвращается при запросе промпта, которого нет ни на одном сервере.
+	ErrUnknownPrompt = errors.New("unknown MCP prompt")
+	// ErrUnknownServer возвращается при обращении к серверу, который не указан в конфигурации.
+	ErrUnknownServer = errors.New("unknown MCP server")
+)
+
+// ServerConfig описывает подключение к MCP серверу.
+type ServerConfig struct {
+	Name      string            // Название сервера, префикс названий его инструментов ([a-zA-Z0-9_-])
+	Transport Transport         // Способ подключения
+	Command   string            // Команда запуска сервера (для stdio)
+	Args      []string          // Аргументы команды (для stdio)
+	Env       []string          // Переменные окружения процесса в формате KEY=VALUE (для stdio)
+	URL       string            // Адрес сервера (для sse и streamable_http)
+	Headers   map[string]string // Заголовки HTTP запросов (для sse и streamable_http)
+}
+
+// Option настраивает Client.
+type Option func(*Client)
+
+// WithSeparator задает разделитель названия сервера и инструмента.
+func WithSeparator(separator string) Option {
+	return func(c *Client) {
+		c.separator = separator
+	}
+}
+
+// WithClientInfo задает название и версию клиента, которые передаются серверам при инициализации.
+func WithClientInfo(name string, version string) Option {
+	return func(c *Client) {
+		c.clientInfo = mcpgo.Implementation{Name: name, Version: version}
+	}
+}
+
+// connection подключение к MCP серверу.
+type connection struct {
+	config       ServerConfig             // Настройки подключения
+	client       *client.Client           // Клиент mcp-go
+	capabilities mcpgo.ServerCapabilities // Возможности сервера из инициализации
+	tools        []mcpgo.Tool             // Инструменты сервера с исходными названиями
+	prompts      []mcpgo.Prompt           // Промпты сервера с исходными названиями
+}
+
+// route указывает, какому серверу принадлежит инструмент или промпт.
+type route struct {
+	conn *connection // Подключение к серверу
+	name string      // Название инструмента или промпта на сервере
+}
+
+// Client подключается к нескольким MCP серверам и объединяет их инструменты.
+// Названия инструментов получают префикс с названием сервера (search__query), поэтому одинаковые
+// инструменты разных серверов не конфликтуют. Client реализует agent.ToolExecutor.
+type Client struct {
+	mu         sync.RWMutex
+	servers    []*connection        // Подключенные серверы в порядке конфигурации
+	routes     map[string]route     // Инструменты по названию с префиксом
+	prompts    map[string]route     // Промпты по названию с префиксом
+	separator  string               // Разделитель названия сервера и инструмента
+	clientInfo mcpgo.Implementation // Название и версия клиента
+
+	samplingHandler client.SamplingHandler // Обработчик запросов sampling от серверов (nil - sampling не поддерживается)
+
+	ctx    context.Context    // Контекст подключений, живет до Close
+	cancel context.CancelFunc // Отменяет подключения
+}
+
+// Connect подключается к серверам, выполняет инициализацию и загружает списки инструментов.
+// ctx ограничивает только подключение: процессы stdio и SSE соединения живут до Close.
+// Если не удалось подключиться хотя бы к одному серверу, остальные подключения закрываются.
+func Connect(ctx context.Context, configs []ServerConfig, opts ...Option) (*Client, error) {
+	c := &Client{
+		routes:     make(map[string]route),
+		prompts:    make(map[string]route),
+		separator:  DefaultSeparator,
+		clientInfo: mcpgo.Implementation{Name: defaultClientName, Version: defaultClientVersion},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	names := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if !serverNamePattern.MatchString(config.Name) {
+			c.Close()
+			return nil, fmt.Errorf("invalid MCP server name %q: only letters, digits, '_' and '-' are allowed", config.Name)
+		}
+		if names[config.Name] {
+			c.Close()
+			return nil, fmt.Errorf("duplicate MCP server name %q", config.Name)
+		}
+		names[config.Name] = true
+
+		srv, err := c.connect(ctx, config)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to connect to MCP server %s: %w", config.Name, err)
+		}
+		c.servers = append(c.servers, srv)
+	}
+
+	if err := c.Refresh(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect создает клиент mcp-go для сервера и выполняет инициализацию.
+func (c *Client) connect(ctx context.Context, config ServerConfig) (*connection, error) {
+	var mcpTransport transport.Interface
+	switch config.Transport {
+	case TransportStdio:
+		if config.Command == "" {
+			return nil, fmt.Errorf("command is required for stdio transport")
+		}
+		mcpTransport = transport.NewStdio(config.Command, config.Env, config.Args...)
+	case TransportSSE:
+		sseTransport, err := transport.NewSSE(config.URL, transport.WithHeaders(config.Headers))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSE transport: %w", err)
+		}
+		mcpTransport = sseTransport
+	case TransportStreamableHTTP:
+		httpOptions := []transport.StreamableHTTPCOption{transport.WithHTTPHeaders(config.Headers)}
+		// Запросы sampling сервер отправляет через отдельный GET поток, который открывается только при постоянном прослушивании
+		if c.samplingHandler != nil {
+			httpOptions = append(httpOptions, transport.WithContinuousListening())
+		}
+		httpTransport, err := transport.NewStreamableHTTP(config.URL, httpOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create streamable HTTP transport: %w", err)
+		}
+		mcpTransport = httpTransport
+	default:
+		return nil, fmt.Errorf("unknown transport %q", config.Transport)
+	}
+
+	var clientOptions []client.ClientOption
+	if c.samplingHandler != nil {
+		clientOptions = append(clientOptions, client.WithSamplingHandler(c.samplingHandler))
+	}
+	mcpClient := client.NewClient(mcpTransport, clientOptions...)
+	if err := mcpClient.Start(c.ctx); err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("failed to start transport: %w", err)
+	}
+
+	request := mcpgo.InitializeRequest{}
+	request.Params.ProtocolVersion = mcpgo.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = c.clientInfo
+	initResult, err := mcpClient.Initialize(ctx, request)
+	if err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	return &connection{config: config, client: mcpClient, capabilities: initResult.Capabilities}, nil
+}
+
+// Refresh заново загружает списки инструментов и промптов всех серверов.
+// Списки запрашиваются только у серверов, которые объявили соответствующие возможности.
+func (c *Client) Refresh(ctx context.Context) error {
+	routes := make(map[string]route)
+	promptRoutes := make(map[string]route)
+	tools := make([][]mcpgo.Tool, len(c.servers))
+	prompts := make([][]mcpgo.Prompt, len(c.servers))
+	for i, srv := range c.servers {
+		if srv.capabilities.Tools != nil {
+			result, err := srv.client.ListTools(ctx, mcpgo.ListToolsRequest{})
+			if err != nil {
+				return fmt.Errorf("failed to list tools of MCP server %s: %w", srv.config.Name, err)
+			}
+			tools[i] = result.Tools
+			for _, tool := range result.Tools {
+				routes[c.toolName(srv, tool.Name)] = route{conn: srv, name: tool.Name}
+			}
+		}
+
+		if srv.capabilities.Prompts != nil {
+			result, err := srv.client.ListPrompts(ctx, mcpgo.ListPromptsRequest{})
+			if err != nil {
+				return fmt.Errorf("failed to list prompts of MCP server %s: %w", srv.config.Name, err)
+			}
+			prompts[i] = result.Prompts
+			for _, prompt := range result.Prompts {
+				promptRoutes[c.toolName(srv, prompt.Name)] = route{conn: srv, name: prompt.Name}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, srv := range c.servers {
+		srv.tools = tools[i]
+		srv.prompts = prompts[i]
+	}
+	c.routes = routes
+	c.prompts = promptRoutes
+	return nil
+}
+
+// Tools возвращает инструменты всех серверов с названиями в формате server__tool.
+func (c *Client) Tools() []mcpgo.Tool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var tools []mcpgo.Tool
+	for _, srv := range c.servers {
+		for _, tool := range srv.tools {
+			tool.Name = c.toolName(srv, tool.Name)
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// Option возвращает опцию запроса со всеми инструментами серверов.
+func (c *Client) Option() options.SendMessageOption {
+	return options.WithMCPTools(c.Tools())
+}
+
+// CallTool вызывает инструмент на сервере, которому он принадлежит. request.Params.Name - название с префиксом
+// сервера, как его вернула модель.
+func (c *Client) CallTool(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+	c.mu.RLock()
+	target, exists := c.routes[request.Params.Name]
+	c.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, request.Params.Name)
+	}
+
+	request.Params.Name = target.name
+	result, err := target.conn.client.CallTool(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s failed to call tool %s: %w", target.conn.config.Name, target.name, err)
+	}
+	return result, nil
+}
+
+// Close закрывает подключения ко всем серверам и завершает процессы stdio серверов.
+func (c *Client) Close() error {
+	c.cancel()
+
+	var errs []error
+	for _, srv := range c.servers {
+		if err := srv.client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("MCP server %s: %w", srv.config.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// toolName возвращает название инструмента или промпта с префиксом сервера.
+func (c *Client) toolName(srv *connection, name string) string {
+	return srv.config.Name + c.separator + name
+}
@@ -0,0 +1,111 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/agent"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Client можно передать агенту как исполнитель инструментов
+var _ agent.ToolExecutor = (*Client)(nil)
+
+// stdioServerEnv переменная окружения, с которой тестовый бинарник запускается как stdio MCP сервер.
+const stdioServerEnv = "MCPCLIENT_TEST_STDIO_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(stdioServerEnv) != "" {
+		if err := server.ServeStdio(newTestServer("local")); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestServer создает MCP сервер с инструментом get_weather, ответ которого содержит название сервера.
+func newTestServer(name string) *server.MCPServer {
+	mcpServer := server.NewMCPServer(name, "1.0.0", server.WithToolCapabilities(false))
+	mcpServer.AddTool(
+		mcpgo.NewTool("get_weather", mcpgo.WithDescription("Get the current weather"), mcpgo.WithString("city", mcpgo.Required())),
+		func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			return mcpgo.NewToolResultText(name + ": +5 в городе " + request.GetString("city", "")), nil
+		},
+	)
+	return mcpServer
+}
+
+func TestClientTransportsAndRouting(t *testing.T) {
+	sseServer := server.NewTestServer(newTestServer("sse"))
+	defer sseServer.Close()
+	httpServer := server.NewTestStreamableHTTPServer(newTestServer("http"))
+	defer httpServer.Close()
+
+	ctx := context.Background()
+	c, err := Connect(ctx, []ServerConfig{
+		{Name: "local", Transport: TransportStdio, Command: os.Args[0], Env: []string{stdioServerEnv + "=1"}},
+		{Name: "sse", Transport: TransportSSE, URL: sseServer.URL + "/sse"},
+		{Name: "http", Transport: TransportStreamableHTTP, URL: httpServer.URL + "/mcp"},
+	})
+	if err != nil {
+		t.Fatalf("Connect() failed with error: %v", err)
+	}
+	defer c.Close()
+
+	tools := c.Tools()
+	expectedNames := []string{"local__get_weather", "sse__get_weather", "http__get_weather"}
+	if len(tools) != len(expectedNames) {
+		t.Fatalf("Expected %d tools, got %d", len(expectedNames), len(tools))
+	}
+	for i, name := range expectedNames {
+		if tools[i].Name != name || tools[i].Description != "Get the current weather" {
+			t.Errorf("Expected tool %d named %s with description, got %s %q", i, name, tools[i].Name, tools[i].Description)
+		}
+	}
+
+	if mcpTools, ok := options.ExtractMCPToolsOption([]options.SendMessageOption{c.Option()}); !ok || len(mcpTools) != 3 {
+		t.Errorf("Expected option with 3 MCP tools, got %d", len(mcpTools))
+	}
+
+	for _, serverName := range []string{"local", "sse", "http"} {
+		request := mcpgo.CallToolRequest{}
+		request.Params.Name = serverName + "__get_weather"
+		request.Params.Arguments = map[string]interface{}{"city": "Москва"}
+
+		result, err := c.CallTool(ctx, request)
+		if err != nil {
+			t.Fatalf("CallTool(%s) failed with error: %v", request.Params.Name, err)
+		}
+		if text := mcpgo.GetTextFromContent(result.Content[0]); text != serverName+": +5 в городе Москва" {
+			t.Errorf("Expected call to be routed to %s, got %q", serverName, text)
+		}
+	}
+
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = "get_weather"
+	if _, err := c.CallTool(ctx, request); !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("Expected ErrUnknownTool for tool without server prefix, got %v", err)
+	}
+}
+
+func TestConnectValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []ServerConfig
+	}{
+		{"invalid name", []ServerConfig{{Name: "my.server", Transport: TransportStdio, Command: "true"}}},
+		{"duplicate name", []ServerConfig{{Name: "a", Transport: TransportSSE, URL: "http://127.0.0.1:0"}, {Name: "a", Transport: TransportSSE}}},
+		{"unknown transport", []ServerConfig{{Name: "a", Transport: "websocket"}}},
+		{"missing command", []ServerConfig{{Name: "a", Transport: TransportStdio}}},
+	}
+	for _, tt := range tests {
+		if _, err := Connect(context.Background(), tt.configs); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
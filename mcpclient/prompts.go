@@ -0,0 +1,123 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/entities/mappers"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// Prompts возвращает промпты всех серверов с названиями в формате server__prompt.
+func (c *Client) Prompts() []mcpgo.Prompt {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var prompts []mcpgo.Prompt
+	for _, srv := range c.servers {
+		for _, prompt := range srv.prompts {
+			prompt.Name = c.toolName(srv, prompt.Name)
+			prompts = append(prompts, prompt)
+		}
+	}
+	return prompts
+}
+
+// GetPrompt получает промпт с сервера и возвращает его сообщения, готовые к отправке провайдеру.
+// name - название с префиксом сервера, arguments подставляются сервером в шаблон промпта.
+// Если не переданы обязательные аргументы, сервер не вызывается.
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) ([]*entities.Message, error) {
+	c.mu.RLock()
+	target, exists := c.prompts[name]
+	var prompt mcpgo.Prompt
+	if exists {
+		for _, p := range target.conn.prompts {
+			if p.Name == target.name {
+				prompt = p
+				break
+			}
+		}
+	}
+	c.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+
+	var missing []string
+	for _, argument := range prompt.Arguments {
+		if argument.Required && arguments[argument.Name] == "" {
+			missing = append(missing, argument.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("prompt %s: missing required arguments: %s", name, strings.Join(missing, ", "))
+	}
+
+	request := mcpgo.GetPromptRequest{}
+	request.Params.Name = target.name
+	request.Params.Arguments = arguments
+	result, err := target.conn.client.GetPrompt(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s failed to get prompt %s: %w", target.conn.config.Name, target.name, err)
+	}
+
+	messages, err := mappers.PromptMessagesToMessages(result.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("prompt %s: %w", name, err)
+	}
+	return messages, nil
+}
+
+// ListResources возвращает ресурсы сервера serverName.
+func (c *Client) ListResources(ctx context.Context, serverName string) ([]mcpgo.Resource, error) {
+	srv, err := c.server(serverName)
+	if err != nil {
+		return nil, err
+	}
+	result, err := srv.client.ListResources(ctx, mcpgo.ListResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources of MCP server %s: %w", serverName, err)
+	}
+	return result.Resources, nil
+}
+
+// ReadResource читает содержимое ресурса uri с сервера serverName.
+func (c *Client) ReadResource(ctx context.Context, serverName string, uri string) ([]mcpgo.ResourceContents, error) {
+	srv, err := c.server(serverName)
+	if err != nil {
+		return nil, err
+	}
+	request := mcpgo.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := srv.client.ReadResource(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s failed to read resource %s: %w", serverName, uri, err)
+	}
+	return result.Contents, nil
+}
+
+// ResourcesOption читает ресурсы сервера serverName и возвращает опцию запроса с их содержимым.
+func (c *Client) ResourcesOption(ctx context.Context, serverName string, uris ...string) (options.SendMessageOption, error) {
+	var resources []mcpgo.ResourceContents
+	for _, uri := range uris {
+		contents, err := c.ReadResource(ctx, serverName, uri)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, contents...)
+	}
+	return options.WithResources(resources...), nil
+}
+
+// server возвращает подключение к серверу по названию.
+func (c *Client) server(name string) (*connection, error) {
+	for _, srv := range c.servers {
+		if srv.config.Name == name {
+			return srv, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+}
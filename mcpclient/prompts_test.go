@@ -0,0 +1,114 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/provider"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newKnowledgeServer создает MCP сервер базы знаний с промптом и ресурсами, но без инструментов.
+func newKnowledgeServer() *server.MCPServer {
+	mcpServer := server.NewMCPServer("kb", "1.0.0", server.WithPromptCapabilities(false), server.WithResourceCapabilities(false, false))
+	policy := mcpgo.TextResourceContents{URI: "kb://policies/vacation", MIMEType: "text/markdown", Text: "Отпуск - 28 дней"}
+
+	mcpServer.AddPrompt(
+		mcpgo.NewPrompt("explain_policy", mcpgo.WithArgument("audience", mcpgo.RequiredArgument())),
+		func(ctx context.Context, request mcpgo.GetPromptRequest) (*mcpgo.GetPromptResult, error) {
+			return mcpgo.NewGetPromptResult("Объяснение политики", []mcpgo.PromptMessage{
+				mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewEmbeddedResource(policy)),
+				mcpgo.NewPromptMessage(mcpgo.RoleAssistant, mcpgo.NewTextContent("Прочитал политику")),
+				mcpgo.NewPromptMessage(mcpgo.RoleUser, mcpgo.NewTextContent("Объясни ее для: "+request.Params.Arguments["audience"])),
+			}), nil
+		},
+	)
+	mcpServer.AddResource(
+		mcpgo.NewResource(policy.URI, "Политика отпусков", mcpgo.WithMIMEType(policy.MIMEType)),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			return []mcpgo.ResourceContents{policy}, nil
+		},
+	)
+	mcpServer.AddResource(
+		mcpgo.NewResource("kb://schemas/leave.json", "Схема заявки", mcpgo.WithMIMEType("application/json")),
+		func(ctx context.Context, request mcpgo.ReadResourceRequest) ([]mcpgo.ResourceContents, error) {
+			return []mcpgo.ResourceContents{mcpgo.BlobResourceContents{URI: request.Params.URI, MIMEType: "application/json", Blob: "eyJkYXlzIjogMjh9"}}, nil
+		},
+	)
+	return mcpServer
+}
+
+func TestClientPromptsAndResources(t *testing.T) {
+	httpServer := server.NewTestStreamableHTTPServer(newKnowledgeServer())
+	defer httpServer.Close()
+
+	ctx := context.Background()
+	c, err := Connect(ctx, []ServerConfig{{Name: "kb", Transport: TransportStreamableHTTP, URL: httpServer.URL + "/mcp"}})
+	if err != nil {
+		t.Fatalf("Connect() failed with error: %v", err)
+	}
+	defer c.Close()
+
+	if len(c.Tools()) != 0 {
+		t.Errorf("Expected no tools from server without tools capability, got %d", len(c.Tools()))
+	}
+	prompts := c.Prompts()
+	if len(prompts) != 1 || prompts[0].Name != "kb__explain_policy" {
+		t.Fatalf("Expected prompt kb__explain_policy, got %+v", prompts)
+	}
+
+	if _, err := c.GetPrompt(ctx, "kb__explain_policy", nil); err == nil {
+		t.Error("Expected error for missing required argument")
+	}
+	if _, err := c.GetPrompt(ctx, "explain_policy", nil); !errors.Is(err, ErrUnknownPrompt) {
+		t.Errorf("Expected ErrUnknownPrompt for prompt without server prefix, got %v", err)
+	}
+
+	messages, err := c.GetPrompt(ctx, "kb__explain_policy", map[string]string{"audience": "новых сотрудников"})
+	if err != nil {
+		t.Fatalf("GetPrompt() failed with error: %v", err)
+	}
+	expected := []struct {
+		authorType string
+		text       string
+	}{
+		{entities.AuthorTypeUser, "<resource uri=\"kb://policies/vacation\" mime_type=\"text/markdown\">\nОтпуск - 28 дней\n</resource>"},
+		{entities.AuthorTypeRobot, "Прочитал политику"},
+		{entities.AuthorTypeUser, "Объясни ее для: новых сотрудников"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d", len(expected), len(messages))
+	}
+	for i, want := range expected {
+		if messages[i].AuthorType != want.authorType || messages[i].MessageText != want.text {
+			t.Errorf("Message %d: expected %s %q, got %s %q", i, want.authorType, want.text, messages[i].AuthorType, messages[i].MessageText)
+		}
+	}
+
+	resources, err := c.ListResources(ctx, "kb")
+	if err != nil || len(resources) != 2 {
+		t.Fatalf("Expected 2 resources, got %d (%v)", len(resources), err)
+	}
+	if _, err := c.ReadResource(ctx, "search", "kb://policies/vacation"); !errors.Is(err, ErrUnknownServer) {
+		t.Errorf("Expected ErrUnknownServer, got %v", err)
+	}
+
+	option, err := c.ResourcesOption(ctx, "kb", "kb://policies/vacation", "kb://schemas/leave.json")
+	if err != nil {
+		t.Fatalf("ResourcesOption() failed with error: %v", err)
+	}
+	mock := provider.NewMockProvider().Reply(provider.MockText("28 дней"))
+	question := []*entities.Message{{MessageText: "Сколько дней отпуска?", AuthorType: entities.AuthorTypeUser}}
+	if _, err := mock.SendMessage(ctx, question, "gpt-4o", option); err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	sent := mock.Requests()[0].Messages
+	expectedContext := "<resource uri=\"kb://policies/vacation\" mime_type=\"text/markdown\">\nОтпуск - 28 дней\n</resource>\n\n" +
+		"<resource uri=\"kb://schemas/leave.json\" mime_type=\"application/json\">\n{\"days\": 28}\n</resource>"
+	if len(sent) != 2 || sent[0].MessageText != expectedContext || sent[1] != question[0] {
+		t.Errorf("Expected resources before question, got %+v", sent)
+	}
+}
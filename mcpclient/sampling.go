@@ -0,0 +1,298 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+	"github.com/mark3labs/mcp-go/client"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+// Причины остановки генерации в ответе на sampling/createMessage.
+const (
+	stopReasonEndTurn   = "endTurn"
+	stopReasonMaxTokens = "maxTokens"
+)
+
+// ErrSamplingBudgetExceeded возвращается серверу, когда запросы sampling израсходовали бюджет.
+var ErrSamplingBudgetExceeded = errors.New("sampling budget exceeded")
+
+var _ client.SamplingHandler = (*SamplingHandler)(nil)
+
+// SamplingOption настраивает SamplingHandler.
+type SamplingOption func(*SamplingHandler)
+
+// WithSamplingMaxCost ограничивает суммарную стоимость запросов sampling в рублях.
+// Стоимость запроса известна только после ответа, поэтому перед запросом резервируется оценка - стоимость
+// самого дорогого выполненного запроса (до первого ответа - весь остаток бюджета). Запросы выполняются
+// параллельно, пока резервы помещаются в бюджет, иначе ждут завершения выполняемых.
+func WithSamplingMaxCost(maxCost decimal.Decimal) SamplingOption {
+	return func(h *SamplingHandler) {
+		h.maxCost = maxCost
+	}
+}
+
+// WithSamplingMaxTokens ограничивает max_tokens одного запроса sampling, даже если сервер просит больше.
+func WithSamplingMaxTokens(maxTokens int) SamplingOption {
+	return func(h *SamplingHandler) {
+		h.maxTokens = maxTokens
+	}
+}
+
+// WithSamplingHandler передает серверам запросы sampling/createMessage в handler.
+func WithSamplingHandler(handler client.SamplingHandler) Option {
+	return func(c *Client) {
+		c.samplingHandler = handler
+	}
+}
+
+// SamplingHandler выполняет запросы sampling/createMessage от MCP серверов через Provider.SendMessage.
+// Модель выбирается из разрешенных по предпочтениям сервера: сначала по подсказкам (подстрока названия),
+// затем по приоритетам стоимости, скорости и качества с учетом цены модели из ModelInfo.
+type SamplingHandler struct {
+	provider  provider.Provider    // Провайдер для запросов
+	models    []entities.ModelName // Модели, из которых выбирается модель для запроса
+	maxCost   decimal.Decimal      // Бюджет в рублях (0 - без ограничения)
+	maxTokens int                  // Ограничение max_tokens одного запроса (0 - без ограничения)
+
+	mu       sync.Mutex
+	spent    decimal.Decimal // Потрачено на запросы sampling
+	reserved decimal.Decimal // Зарезервировано выполняемыми запросами
+	maxSeen  decimal.Decimal // Стоимость самого дорогого выполненного запроса
+	costSeen bool            // Был ли хотя бы один ответ со стоимостью
+	released chan struct{}   // Закрывается при освобождении резерва
+}
+
+// NewSamplingHandler создает обработчик sampling для провайдера p.
+// models - модели, которые могут использовать серверы; первая используется, если сервер не указал предпочтений.
+// Если models пуст, выбор идет из всех моделей провайдера (ListModels).
+func NewSamplingHandler(p provider.Provider, models []entities.ModelName, opts ...SamplingOption) *SamplingHandler {
+	h := &SamplingHandler{provider: p, models: models, spent: decimal.Zero, released: make(chan struct{})}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Spent возвращает стоимость всех выполненных запросов sampling в рублях.
+func (h *SamplingHandler) Spent() decimal.Decimal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.spent
+}
+
+// CreateMessage выбирает модель и отправляет сообщения сервера провайдеру.
+func (h *SamplingHandler) CreateMessage(ctx context.Context, request mcpgo.CreateMessageRequest) (*mcpgo.CreateMessageResult, error) {
+	reservation, err := h.reserve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var response *entities.ProviderMessageResponseDTO
+	defer func() { h.settle(reservation, response) }()
+
+	modelName, err := h.selectModel(request.ModelPreferences)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := samplingMessages(request.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err = h.provider.SendMessage(ctx, messages, modelName, h.samplingOptions(request.CreateMessageParams)...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mcpgo.CreateMessageResult{
+		SamplingMessage: mcpgo.SamplingMessage{
+			Role:    mcpgo.RoleAssistant,
+			Content: mcpgo.NewTextContent(response.MessageText),
+		},
+		Model:      string(modelName),
+		StopReason: stopReasonEndTurn,
+	}
+	if response.FinishReason != nil && *response.FinishReason == entities.FinishReasonLength {
+		result.StopReason = stopReasonMaxTokens
+	}
+	return result, nil
+}
+
+// reserve резервирует оценку стоимости запроса в бюджете. Если бюджет израсходован, возвращается
+// ErrSamplingBudgetExceeded; если резерв не помещается рядом с резервами выполняемых запросов,
+// reserve ждет их завершения.
+func (h *SamplingHandler) reserve(ctx context.Context) (decimal.Decimal, error) {
+	if !h.maxCost.IsPositive() {
+		return decimal.Zero, nil
+	}
+	for {
+		h.mu.Lock()
+		if h.spent.GreaterThanOrEqual(h.maxCost) {
+			spent := h.spent
+			h.mu.Unlock()
+			return decimal.Zero, fmt.Errorf("%w: spent %s of %s rubles", ErrSamplingBudgetExceeded, spent, h.maxCost)
+		}
+		estimate := h.maxSeen
+		if !h.costSeen {
+			estimate = h.maxCost.Sub(h.spent)
+		}
+		if h.reserved.IsZero() || h.spent.Add(h.reserved).Add(estimate).LessThanOrEqual(h.maxCost) {
+			h.reserved = h.reserved.Add(estimate)
+			h.mu.Unlock()
+			return estimate, nil
+		}
+		released := h.released
+		h.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return decimal.Zero, ctx.Err()
+		}
+	}
+}
+
+// settle освобождает резерв запроса и учитывает стоимость ответа (nil - запрос не выполнен).
+func (h *SamplingHandler) settle(reservation decimal.Decimal, response *entities.ProviderMessageResponseDTO) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reserved = h.reserved.Sub(reservation)
+	if response != nil {
+		h.spent = h.spent.Add(response.PriceInRubles)
+		if !h.costSeen || response.PriceInRubles.GreaterThan(h.maxSeen) {
+			h.maxSeen = response.PriceInRubles
+		}
+		h.costSeen = true
+	}
+	close(h.released)
+	h.released = make(chan struct{})
+}
+
+// samplingOptions переводит параметры запроса sampling в опции запроса к провайдеру.
+func (h *SamplingHandler) samplingOptions(params mcpgo.CreateMessageParams) []options.SendMessageOption {
+	var opts []options.SendMessageOption
+	if params.SystemPrompt != "" {
+		opts = append(opts, options.WithSystemPrompt(params.SystemPrompt))
+	}
+	// temperature передается с omitempty, поэтому 0 означает, что сервер ее не указал
+	if params.Temperature > 0 {
+		opts = append(opts, options.WithTemperature(params.Temperature))
+	}
+	maxTokens := params.MaxTokens
+	if h.maxTokens > 0 && (maxTokens <= 0 || maxTokens > h.maxTokens) {
+		maxTokens = h.maxTokens
+	}
+	if maxTokens > 0 {
+		opts = append(opts, options.WithMaxTokens(maxTokens))
+	}
+	if len(params.StopSequences) > 0 {
+		opts = append(opts, options.WithStop(params.StopSequences...))
+	}
+	return opts
+}
+
+// selectModel выбирает модель по предпочтениям сервера.
+// Подсказки проверяются по порядку: первая модель, название или alias которой содержит подсказку, выбирается сразу.
+// Иначе модели упорядочиваются по цене, и приоритеты задают желаемое место в этом ряду:
+// стоимость и скорость тянут к дешевым моделям, качество - к дорогим.
+func (h *SamplingHandler) selectModel(preferences *mcpgo.ModelPreferences) (entities.ModelName, error) {
+	type candidate struct {
+		name entities.ModelName
+		info *entities.ModelInfo
+	}
+	var candidates []candidate
+	if len(h.models) == 0 {
+		models, err := h.provider.ListModels()
+		if err != nil {
+			return "", fmt.Errorf("failed to list models for sampling: %w", err)
+		}
+		for _, modelInfo := range models {
+			candidates = append(candidates, candidate{name: modelInfo.Alias, info: modelInfo})
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].name < candidates[j].name })
+	}
+	for _, modelName := range h.models {
+		if modelInfo, err := h.provider.GetModelInfo(modelName); err == nil && modelInfo != nil {
+			candidates = append(candidates, candidate{name: modelName, info: modelInfo})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no models available for sampling")
+	}
+	if preferences == nil {
+		return candidates[0].name, nil
+	}
+
+	for _, hint := range preferences.Hints {
+		normalized := normalizeModelHint(hint.Name)
+		if normalized == "" {
+			continue
+		}
+		for _, c := range candidates {
+			if strings.Contains(normalizeModelHint(string(c.name)), normalized) || strings.Contains(normalizeModelHint(c.info.Name), normalized) {
+				return c.name, nil
+			}
+		}
+	}
+
+	total := preferences.CostPriority + preferences.SpeedPriority + preferences.IntelligencePriority
+	if total <= 0 || len(candidates) == 1 {
+		return candidates[0].name, nil
+	}
+	target := preferences.IntelligencePriority / total
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].info.PriceInRubles.LessThan(candidates[j].info.PriceInRubles)
+	})
+	best, bestDistance := 0, math.Inf(1)
+	for i := range candidates {
+		position := float64(i) / float64(len(candidates)-1)
+		if distance := math.Abs(position - target); distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return candidates[best].name, nil
+}
+
+// normalizeModelHint приводит название модели к виду наших alias: нижний регистр, точки заменены дефисами
+// (claude-3.5-sonnet -> claude-3-5-sonnet).
+func normalizeModelHint(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), ".", "-")
+}
+
+// samplingMessages конвертирует сообщения sampling во внутренние сообщения.
+func samplingMessages(samplingMessages []mcpgo.SamplingMessage) ([]*entities.Message, error) {
+	messages := make([]*entities.Message, len(samplingMessages))
+	for i, samplingMessage := range samplingMessages {
+		message := &entities.Message{AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText}
+		if samplingMessage.Role == mcpgo.RoleAssistant {
+			message.AuthorType = entities.AuthorTypeRobot
+		}
+
+		if text, ok := mcpgo.AsTextContent(samplingMessage.Content); ok {
+			message.MessageText = text.Text
+		} else if image, ok := mcpgo.AsImageContent(samplingMessage.Content); ok {
+			data, err := base64.StdEncoding.DecodeString(image.Data)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: failed to decode image: %w", i, err)
+			}
+			message.MessageType = entities.MessageImage
+			message.Images = []entities.ImageContent{{Data: data, MIMEType: image.MIMEType}}
+		} else {
+			return nil, fmt.Errorf("message %d: %w: %T", i, provider.ErrUnsupportedModality, samplingMessage.Content)
+		}
+		messages[i] = message
+	}
+	return messages, nil
+}
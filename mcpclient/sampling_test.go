@@ -0,0 +1,276 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/shopspring/decimal"
+)
+
+// newSamplingMock создает тестового провайдера с тремя моделями разной цены.
+func newSamplingMock() *provider.MockProvider {
+	return provider.NewMockProvider(
+		&entities.ModelInfo{Name: "GPT-4o", Alias: "gpt-4o", PriceInRubles: decimal.NewFromInt(1000)},
+		&entities.ModelInfo{Name: "GPT-4o mini", Alias: "gpt-4o-mini", PriceInRubles: decimal.NewFromInt(60)},
+		&entities.ModelInfo{Name: "Claude 3.5 Sonnet", Alias: "claude-3-5-sonnet", PriceInRubles: decimal.NewFromInt(1500)},
+	)
+}
+
+func TestSamplingHandlerSelectModel(t *testing.T) {
+	models := []entities.ModelName{"gpt-4o", "gpt-4o-mini", "claude-3-5-sonnet"}
+	handler := NewSamplingHandler(newSamplingMock(), models)
+
+	tests := []struct {
+		name        string
+		preferences *mcpgo.ModelPreferences
+		expected    entities.ModelName
+	}{
+		{"no preferences", nil, "gpt-4o"},
+		{"hint with dots", &mcpgo.ModelPreferences{Hints: []mcpgo.ModelHint{{Name: "claude-3.5-sonnet"}}}, "claude-3-5-sonnet"},
+		{"first matching hint", &mcpgo.ModelPreferences{Hints: []mcpgo.ModelHint{{Name: "gemini"}, {Name: "Mini"}}}, "gpt-4o-mini"},
+		{"cost priority", &mcpgo.ModelPreferences{CostPriority: 1, IntelligencePriority: 0.1}, "gpt-4o-mini"},
+		{"speed priority", &mcpgo.ModelPreferences{SpeedPriority: 0.8}, "gpt-4o-mini"},
+		{"intelligence priority", &mcpgo.ModelPreferences{IntelligencePriority: 0.9, CostPriority: 0.1}, "claude-3-5-sonnet"},
+		{"balanced", &mcpgo.ModelPreferences{CostPriority: 0.5, IntelligencePriority: 0.5}, "gpt-4o"},
+		{"unknown hint falls back to priorities", &mcpgo.ModelPreferences{Hints: []mcpgo.ModelHint{{Name: "llama"}}, IntelligencePriority: 1}, "claude-3-5-sonnet"},
+	}
+	for _, tt := range tests {
+		modelName, err := handler.selectModel(tt.preferences)
+		if err != nil {
+			t.Fatalf("%s: selectModel() failed with error: %v", tt.name, err)
+		}
+		if modelName != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, modelName)
+		}
+	}
+
+	// Без списка моделей выбор идет из всех моделей провайдера
+	modelName, err := NewSamplingHandler(newSamplingMock(), nil).selectModel(&mcpgo.ModelPreferences{CostPriority: 1})
+	if err != nil || modelName != "gpt-4o-mini" {
+		t.Errorf("Expected cheapest provider model, got %s (%v)", modelName, err)
+	}
+}
+
+func TestSamplingHandlerCreateMessage(t *testing.T) {
+	mock := newSamplingMock()
+	answer := provider.MockText("Краткое содержание")
+	length := entities.FinishReasonLength
+	answer.FinishReason = &length
+	answer.PriceInRubles = decimal.NewFromInt(3)
+	mock.Reply(answer)
+	handler := NewSamplingHandler(mock, []entities.ModelName{"gpt-4o-mini"}, WithSamplingMaxTokens(100), WithSamplingMaxCost(decimal.NewFromInt(2)))
+
+	request := mcpgo.CreateMessageRequest{}
+	request.Messages = []mcpgo.SamplingMessage{
+		{Role: mcpgo.RoleUser, Content: mcpgo.NewImageContent(base64.StdEncoding.EncodeToString([]byte("png")), "image/png")},
+		{Role: mcpgo.RoleAssistant, Content: mcpgo.NewTextContent("Что сделать с картинкой?")},
+		{Role: mcpgo.RoleUser, Content: mcpgo.NewTextContent("Опиши ее")},
+	}
+	request.SystemPrompt = "Отвечай кратко"
+	request.MaxTokens = 1000
+	request.Temperature = 0.3
+
+	result, err := handler.CreateMessage(context.Background(), request)
+	if err != nil {
+		t.Fatalf("CreateMessage() failed with error: %v", err)
+	}
+	if text, ok := mcpgo.AsTextContent(result.Content); !ok || text.Text != "Краткое содержание" || result.Role != mcpgo.RoleAssistant {
+		t.Errorf("Expected assistant text answer, got %+v", result.SamplingMessage)
+	}
+	if result.Model != "gpt-4o-mini" || result.StopReason != "maxTokens" {
+		t.Errorf("Expected model gpt-4o-mini with stop reason maxTokens, got %s %s", result.Model, result.StopReason)
+	}
+
+	sent := mock.Requests()[0]
+	params := options.ExtractGenerationParams(sent.Options)
+	if params.MaxTokens == nil || *params.MaxTokens != 100 || params.Temperature == nil || *params.Temperature != 0.3 {
+		t.Errorf("Expected max_tokens capped to 100 and temperature 0.3, got %+v", params)
+	}
+	if prompt, ok := options.ExtractSystemPromptOption(sent.Options); !ok || prompt != "Отвечай кратко" {
+		t.Errorf("Expected system prompt option, got %q", prompt)
+	}
+	image := sent.Messages[1]
+	if image.MessageType != entities.MessageImage || len(image.Images) != 1 || string(image.Images[0].Data) != "png" || image.Images[0].MIMEType != "image/png" {
+		t.Errorf("Expected decoded image message, got %+v", image)
+	}
+	if sent.Messages[2].AuthorType != entities.AuthorTypeRobot || sent.Messages[3].AuthorType != entities.AuthorTypeUser {
+		t.Errorf("Expected assistant role mapped to robot, got %s %s", sent.Messages[2].AuthorType, sent.Messages[3].AuthorType)
+	}
+
+	// Первый запрос израсходовал бюджет, второй отклоняется без обращения к провайдеру
+	if _, err := handler.CreateMessage(context.Background(), request); !errors.Is(err, ErrSamplingBudgetExceeded) {
+		t.Errorf("Expected ErrSamplingBudgetExceeded, got %v", err)
+	}
+	if len(mock.Requests()) != 1 || !handler.Spent().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected single request for 3 rubles, got %d for %s", len(mock.Requests()), handler.Spent())
+	}
+
+	request.Messages = []mcpgo.SamplingMessage{{Role: mcpgo.RoleUser, Content: mcpgo.NewAudioContent("", "audio/wav")}}
+	if _, err := NewSamplingHandler(mock, nil).CreateMessage(context.Background(), request); !errors.Is(err, provider.ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for audio, got %v", err)
+	}
+}
+
+// slowProvider отвечает с задержкой, чтобы параллельные запросы пересекались во времени.
+type slowProvider struct {
+	provider.Provider
+}
+
+func (p slowProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	time.Sleep(10 * time.Millisecond)
+	return p.Provider.SendMessage(ctx, messages, modelName, opts...)
+}
+
+func TestSamplingHandlerParallelBudget(t *testing.T) {
+	mock := newSamplingMock()
+	answer := provider.MockText("ok")
+	answer.PriceInRubles = decimal.NewFromInt(3)
+	mock.Script(&provider.MockResponse{Response: answer, Repeat: true})
+	handler := NewSamplingHandler(slowProvider{mock}, []entities.ModelName{"gpt-4o-mini"}, WithSamplingMaxCost(decimal.NewFromInt(5)))
+
+	request := mcpgo.CreateMessageRequest{}
+	request.Messages = []mcpgo.SamplingMessage{{Role: mcpgo.RoleUser, Content: mcpgo.NewTextContent("Привет")}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := handler.CreateMessage(context.Background(), request)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrSamplingBudgetExceeded):
+			t.Errorf("Expected ErrSamplingBudgetExceeded, got %v", err)
+		}
+	}
+	// Второй запрос начинается при потраченных 3 из 5 рублей, третий - уже за пределами бюджета
+	if succeeded != 2 || len(mock.Requests()) != 2 || !handler.Spent().Equal(decimal.NewFromInt(6)) {
+		t.Errorf("Expected 2 requests for 6 rubles, got %d succeeded, %d sent, %s spent", succeeded, len(mock.Requests()), handler.Spent())
+	}
+}
+
+// barrierProvider отвечает, только когда все parties запросов пришли одновременно.
+type barrierProvider struct {
+	provider.Provider
+	arrived chan struct{}
+	parties int
+}
+
+func (p *barrierProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	p.arrived <- struct{}{}
+	deadline := time.After(time.Second)
+	for len(p.arrived) < p.parties {
+		select {
+		case <-deadline:
+			return nil, errors.New("requests were not executed in parallel")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return p.Provider.SendMessage(ctx, messages, modelName, opts...)
+}
+
+func TestSamplingHandlerParallelUnderBudget(t *testing.T) {
+	mock := newSamplingMock()
+	answer := provider.MockText("ok")
+	answer.PriceInRubles = decimal.NewFromInt(1)
+	mock.Script(&provider.MockResponse{Response: answer, Repeat: true})
+	handler := NewSamplingHandler(mock, []entities.ModelName{"gpt-4o-mini"}, WithSamplingMaxCost(decimal.NewFromInt(10)))
+
+	request := mcpgo.CreateMessageRequest{}
+	request.Messages = []mcpgo.SamplingMessage{{Role: mcpgo.RoleUser, Content: mcpgo.NewTextContent("Привет")}}
+
+	// Первый запрос определяет оценку стоимости, следующие два помещаются в бюджет и выполняются параллельно
+	if _, err := handler.CreateMessage(context.Background(), request); err != nil {
+		t.Fatalf("CreateMessage() failed with error: %v", err)
+	}
+	handler.provider = &barrierProvider{Provider: mock, arrived: make(chan struct{}, 2), parties: 2}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := handler.CreateMessage(context.Background(), request)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected parallel requests under budget, got %v", err)
+		}
+	}
+	if !handler.Spent().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Expected 3 rubles spent, got %s", handler.Spent())
+	}
+}
+
+func TestSamplingOverMCP(t *testing.T) {
+	mcpServer := server.NewMCPServer("summarizer", "1.0.0", server.WithToolCapabilities(false))
+	mcpServer.EnableSampling()
+	mcpServer.AddTool(
+		mcpgo.NewTool("summarize", mcpgo.WithString("text", mcpgo.Required())),
+		func(ctx context.Context, request mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			samplingRequest := mcpgo.CreateMessageRequest{}
+			samplingRequest.Messages = []mcpgo.SamplingMessage{
+				{Role: mcpgo.RoleUser, Content: mcpgo.NewTextContent("Сократи: " + request.GetString("text", ""))},
+			}
+			samplingRequest.ModelPreferences = &mcpgo.ModelPreferences{CostPriority: 1}
+			samplingRequest.MaxTokens = 50
+			result, err := mcpServer.RequestSampling(ctx, samplingRequest)
+			if err != nil {
+				return nil, err
+			}
+			text, _ := mcpgo.AsTextContent(result.Content)
+			return mcpgo.NewToolResultText(result.Model + ": " + text.Text), nil
+		},
+	)
+	httpServer := server.NewTestStreamableHTTPServer(mcpServer)
+	defer httpServer.Close()
+
+	mock := newSamplingMock()
+	mock.Reply(provider.MockText("Погода хорошая"))
+
+	ctx := context.Background()
+	c, err := Connect(ctx, []ServerConfig{{Name: "text", Transport: TransportStreamableHTTP, URL: httpServer.URL + "/mcp"}},
+		WithSamplingHandler(NewSamplingHandler(mock, nil)))
+	if err != nil {
+		t.Fatalf("Connect() failed with error: %v", err)
+	}
+	defer c.Close()
+
+	request := mcpgo.CallToolRequest{}
+	request.Params.Name = "text__summarize"
+	request.Params.Arguments = map[string]interface{}{"text": "Сегодня в Москве солнечно и +20"}
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		t.Fatalf("CallTool() failed with error: %v", err)
+	}
+	if text := mcpgo.GetTextFromContent(result.Content[0]); text != "gpt-4o-mini: Погода хорошая" {
+		t.Errorf("Expected sampled answer from cheapest model, got %q", text)
+	}
+	if requests := mock.Requests(); len(requests) != 1 || requests[0].LastMessage().MessageText != "Сократи: Сегодня в Москве солнечно и +20" {
+		t.Errorf("Expected server messages to reach provider, got %+v", requests)
+	}
+}
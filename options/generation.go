@@ -0,0 +1,162 @@
+package options
+
+// TemperatureOption задает "креативность" ответа (обычно от 0.0 до 2.0).
+type TemperatureOption struct {
+	Temperature float64
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o TemperatureOption) OptionType() string {
+	return OptionTypeTemperature
+}
+
+// WithTemperature создает опцию температуры генерации.
+func WithTemperature(temperature float64) SendMessageOption {
+	return TemperatureOption{Temperature: temperature}
+}
+
+// MaxTokensOption ограничивает количество токенов в ответе.
+type MaxTokensOption struct {
+	MaxTokens int
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o MaxTokensOption) OptionType() string {
+	return OptionTypeMaxTokens
+}
+
+// WithMaxTokens создает опцию максимального количества токенов в ответе.
+func WithMaxTokens(maxTokens int) SendMessageOption {
+	return MaxTokensOption{MaxTokens: maxTokens}
+}
+
+// TopPOption задает порог ядерной выборки (от 0.0 до 1.0).
+type TopPOption struct {
+	TopP float64
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o TopPOption) OptionType() string {
+	return OptionTypeTopP
+}
+
+// WithTopP создает опцию ядерной выборки.
+func WithTopP(topP float64) SendMessageOption {
+	return TopPOption{TopP: topP}
+}
+
+// StopOption задает последовательности, на которых модель останавливает генерацию.
+type StopOption struct {
+	Sequences []string
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o StopOption) OptionType() string {
+	return OptionTypeStop
+}
+
+// WithStop создает опцию стоп-последовательностей.
+func WithStop(sequences ...string) SendMessageOption {
+	return StopOption{Sequences: sequences}
+}
+
+// SeedOption задает seed для детерминированной генерации.
+type SeedOption struct {
+	Seed int
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o SeedOption) OptionType() string {
+	return OptionTypeSeed
+}
+
+// WithSeed создает опцию seed.
+func WithSeed(seed int) SendMessageOption {
+	return SeedOption{Seed: seed}
+}
+
+// PresencePenaltyOption задает штраф за наличие токенов (от -2.0 до 2.0).
+type PresencePenaltyOption struct {
+	Penalty float64
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o PresencePenaltyOption) OptionType() string {
+	return OptionTypePresencePenalty
+}
+
+// WithPresencePenalty создает опцию штрафа за наличие токенов.
+func WithPresencePenalty(penalty float64) SendMessageOption {
+	return PresencePenaltyOption{Penalty: penalty}
+}
+
+// FrequencyPenaltyOption задает штраф за частоту токенов (от -2.0 до 2.0).
+type FrequencyPenaltyOption struct {
+	Penalty float64
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o FrequencyPenaltyOption) OptionType() string {
+	return OptionTypeFrequencyPenalty
+}
+
+// WithFrequencyPenalty создает опцию штрафа за частоту токенов.
+func WithFrequencyPenalty(penalty float64) SendMessageOption {
+	return FrequencyPenaltyOption{Penalty: penalty}
+}
+
+// LogitBiasOption модифицирует вероятность конкретных токенов.
+// Ключ - ID токена в токенизаторе модели, значение - смещение от -100 до 100.
+type LogitBiasOption struct {
+	LogitBias map[string]int
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o LogitBiasOption) OptionType() string {
+	return OptionTypeLogitBias
+}
+
+// WithLogitBias создает опцию модификации вероятности токенов.
+func WithLogitBias(logitBias map[string]int) SendMessageOption {
+	return LogitBiasOption{LogitBias: logitBias}
+}
+
+// GenerationParams содержит параметры генерации, собранные из опций.
+// nil (или пустое значение для срезов и map) означает, что используется значение провайдера по умолчанию.
+type GenerationParams struct {
+	Temperature      *float64
+	MaxTokens        *int
+	TopP             *float64
+	Stop             []string
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	LogitBias        map[string]int
+}
+
+// ExtractGenerationParams извлекает параметры генерации из списка опций.
+// Если опция указана несколько раз, используется последнее значение.
+func ExtractGenerationParams(options []SendMessageOption) GenerationParams {
+	var params GenerationParams
+	for _, option := range options {
+		switch o := option.(type) {
+		case TemperatureOption:
+			params.Temperature = &o.Temperature
+		case MaxTokensOption:
+			params.MaxTokens = &o.MaxTokens
+		case TopPOption:
+			params.TopP = &o.TopP
+		case StopOption:
+			params.Stop = o.Sequences
+		case SeedOption:
+			params.Seed = &o.Seed
+		case PresencePenaltyOption:
+			params.PresencePenalty = &o.Penalty
+		case FrequencyPenaltyOption:
+			params.FrequencyPenalty = &o.Penalty
+		case LogitBiasOption:
+			params.LogitBias = o.LogitBias
+		}
+	}
+	return params
+}
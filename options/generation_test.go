@@ -0,0 +1,35 @@
+package options
+
+import "testing"
+
+func TestExtractGenerationParams(t *testing.T) {
+	params := ExtractGenerationParams([]SendMessageOption{
+		WithTemperature(0.7),
+		WithMaxTokens(256),
+		WithStop("\n\n", "END"),
+		WithSeed(42),
+		WithMCPTools(nil),
+		WithTemperature(0),
+	})
+
+	// Последнее значение опции перекрывает предыдущие
+	if params.Temperature == nil || *params.Temperature != 0 {
+		t.Errorf("Expected temperature 0, got %v", params.Temperature)
+	}
+
+	if params.MaxTokens == nil || *params.MaxTokens != 256 {
+		t.Errorf("Expected max tokens 256, got %v", params.MaxTokens)
+	}
+
+	if len(params.Stop) != 2 || params.Stop[1] != "END" {
+		t.Errorf("Expected stop sequences [\\n\\n END], got %v", params.Stop)
+	}
+
+	if params.Seed == nil || *params.Seed != 42 {
+		t.Errorf("Expected seed 42, got %v", params.Seed)
+	}
+
+	if params.TopP != nil || params.PresencePenalty != nil || params.FrequencyPenalty != nil || params.LogitBias != nil {
+		t.Errorf("Expected unset parameters to be nil, got %+v", params)
+	}
+}
@@ -11,4 +11,26 @@ type SendMessageOption interface {
 const (
 	// OptionTypeMCPTools тип опции для MCP инструментов
 	OptionTypeMCPTools = "mcp_tools"
-)
\ No newline at end of file
+	// OptionTypeSystemPrompt тип опции для системного промпта
+	OptionTypeSystemPrompt = "system_prompt"
+	// OptionTypeResponseFormat тип опции для формата ответа
+	OptionTypeResponseFormat = "response_format"
+	// OptionTypeTemperature тип опции для температуры генерации
+	OptionTypeTemperature = "temperature"
+	// OptionTypeMaxTokens тип опции для максимального количества токенов в ответе
+	OptionTypeMaxTokens = "max_tokens"
+	// OptionTypeTopP тип опции для ядерной выборки
+	OptionTypeTopP = "top_p"
+	// OptionTypeStop тип опции для стоп-последовательностей
+	OptionTypeStop = "stop"
+	// OptionTypeSeed тип опции для seed детерминированной генерации
+	OptionTypeSeed = "seed"
+	// OptionTypePresencePenalty тип опции для штрафа за наличие токенов
+	OptionTypePresencePenalty = "presence_penalty"
+	// OptionTypeFrequencyPenalty тип опции для штрафа за частоту токенов
+	OptionTypeFrequencyPenalty = "frequency_penalty"
+	// OptionTypeLogitBias тип опции для модификации вероятности токенов
+	OptionTypeLogitBias = "logit_bias"
+	// OptionTypeResources тип опции для MCP ресурсов в контексте запроса
+	OptionTypeResources = "resources"
+)
@@ -0,0 +1,28 @@
+package options
+
+// SystemPromptOption представляет опцию для добавления системного промпта к запросу.
+// Промпт добавляется провайдером перед историей сообщений, сама история не изменяется.
+type SystemPromptOption struct {
+	Prompt string
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o SystemPromptOption) OptionType() string {
+	return OptionTypeSystemPrompt
+}
+
+// WithSystemPrompt создает опцию для добавления системного промпта.
+func WithSystemPrompt(prompt string) SendMessageOption {
+	return SystemPromptOption{Prompt: prompt}
+}
+
+// ExtractSystemPromptOption извлекает системный промпт из списка опций.
+// Возвращает промпт и флаг найдена ли опция.
+func ExtractSystemPromptOption(options []SendMessageOption) (string, bool) {
+	for _, option := range options {
+		if promptOption, ok := option.(SystemPromptOption); ok {
+			return promptOption.Prompt, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,34 @@
+package options
+
+import mcpgo "github.com/mark3labs/mcp-go/mcp"
+
+// ResourcesOption добавляет содержимое MCP ресурсов в контекст запроса.
+// Провайдер передает ресурсы модели отдельным сообщением пользователя перед историей:
+// текстовые ресурсы как текст с адресом ресурса, изображения как изображения.
+type ResourcesOption struct {
+	Resources []mcpgo.ResourceContents
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o ResourcesOption) OptionType() string {
+	return OptionTypeResources
+}
+
+// WithResources создает опцию с содержимым MCP ресурсов (например, из ReadResource).
+func WithResources(resources ...mcpgo.ResourceContents) SendMessageOption {
+	return ResourcesOption{Resources: resources}
+}
+
+// ExtractResourcesOption собирает ресурсы из всех опций ResourcesOption.
+// Возвращает ресурсы и флаг найдена ли опция.
+func ExtractResourcesOption(options []SendMessageOption) ([]mcpgo.ResourceContents, bool) {
+	var resources []mcpgo.ResourceContents
+	found := false
+	for _, option := range options {
+		if resourcesOption, ok := option.(ResourcesOption); ok {
+			resources = append(resources, resourcesOption.Resources...)
+			found = true
+		}
+	}
+	return resources, found
+}
@@ -0,0 +1,53 @@
+package options
+
+const (
+	// ResponseFormatJSONObject ответ в виде произвольного JSON объекта.
+	ResponseFormatJSONObject = "json_object"
+	// ResponseFormatJSONSchema ответ в виде JSON, соответствующего заданной схеме.
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormatOption представляет опцию для задания формата ответа модели.
+type ResponseFormatOption struct {
+	Type       string                 // ResponseFormatJSONObject или ResponseFormatJSONSchema
+	SchemaName string                 // Имя схемы (для ResponseFormatJSONSchema)
+	Schema     map[string]interface{} // JSON Schema ответа (для ResponseFormatJSONSchema)
+	Strict     bool                   // Строгое соблюдение схемы (для ResponseFormatJSONSchema)
+}
+
+// OptionType возвращает тип опции для идентификации провайдером.
+func (o ResponseFormatOption) OptionType() string {
+	return OptionTypeResponseFormat
+}
+
+// WithJSONObject создает опцию, требующую от модели ответ в виде JSON объекта.
+func WithJSONObject() SendMessageOption {
+	return ResponseFormatOption{Type: ResponseFormatJSONObject}
+}
+
+// WithJSONSchema создает опцию, требующую от модели ответ по JSON Schema.
+// name - имя схемы (a-z, A-Z, 0-9, _ и -)
+// schema - JSON Schema ответа
+// strict - строгое соблюдение схемы (поддерживается не всеми моделями)
+func WithJSONSchema(name string, schema map[string]interface{}, strict bool) SendMessageOption {
+	return ResponseFormatOption{
+		Type:       ResponseFormatJSONSchema,
+		SchemaName: name,
+		Schema:     schema,
+		Strict:     strict,
+	}
+}
+
+// ExtractResponseFormatOption извлекает опцию формата ответа из списка опций.
+// Если опция указана несколько раз, используется последнее значение.
+func ExtractResponseFormatOption(options []SendMessageOption) (ResponseFormatOption, bool) {
+	var result ResponseFormatOption
+	found := false
+	for _, option := range options {
+		if formatOption, ok := option.(ResponseFormatOption); ok {
+			result = formatOption
+			found = true
+		}
+	}
+	return result, found
+}
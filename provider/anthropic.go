@@ -0,0 +1,716 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/config"
+	"github.com/Murolando/m_ai_provider/internal/entities/anthropic"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+// Константы для провайдера Anthropic
+const (
+	anthropicProviderName     = "Anthropic"
+	anthropicDefaultBaseURL   = "https://api.anthropic.com/v1"
+	anthropicDefaultMaxTokens = 4096
+	// anthropicResponseTool инструмент, через который запрашивается структурированный ответ
+	anthropicResponseTool = "json_response"
+)
+
+var (
+	_ Provider       = (*AnthropicProvider)(nil)
+	_ Namer          = (*AnthropicProvider)(nil)
+	_ ModelRefresher = (*AnthropicProvider)(nil)
+)
+
+// anthropicPrices цены моделей по префиксу ID; более точные префиксы идут раньше.
+// Anthropic не отдает цены через API, поэтому они взяты из https://www.anthropic.com/pricing.
+var anthropicPrices = []modelPrice{
+	{"claude-opus-4-5", tokenPrice{input: 5, output: 25}},
+	{"claude-opus-4", tokenPrice{input: 15, output: 75}},
+	{"claude-sonnet-4", tokenPrice{input: 3, output: 15}},
+	{"claude-haiku-4-5", tokenPrice{input: 1, output: 5}},
+	{"claude-3-7-sonnet", tokenPrice{input: 3, output: 15}},
+	{"claude-3-5-sonnet", tokenPrice{input: 3, output: 15}},
+	{"claude-3-5-haiku", tokenPrice{input: 0.8, output: 4}},
+	{"claude-3-opus", tokenPrice{input: 15, output: 75}},
+	{"claude-3-haiku", tokenPrice{input: 0.25, output: 1.25}},
+}
+
+// anthropicErrorStatuses HTTP статусы типов ошибок Anthropic для ошибок посреди потока.
+var anthropicErrorStatuses = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"request_too_large":     http.StatusRequestEntityTooLarge,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      529,
+}
+
+// AnthropicProvider представляет провайдера для работы с Anthropic Messages API.
+type AnthropicProvider struct {
+	baseURL     string               // Базовый URL для API запросов
+	apiKey      string               // API ключ для аутентификации
+	models      *modelCache          // Кэш информации о моделях
+	toolsMapper *mappers.ToolsMapper // Маппер для конвертации инструментов
+	httpClient  *http.Client         // HTTP клиент для запросов к API
+	rate        *exchangeRate        // Курс доллара к рублю для расчета цен
+}
+
+// NewAnthropicProvider создает новый экземпляр Anthropic провайдера.
+// apiKey - API ключ Anthropic
+// opts - настройки HTTP клиента (прокси, таймауты, заголовки, базовый URL)
+// Возвращает настроенный провайдер или ошибку при неудачной инициализации.
+func NewAnthropicProvider(apiKey string, opts ...ClientOption) (*AnthropicProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY is not set")
+	}
+
+	httpConfig, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	rate, err := newExchangeRate(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := anthropicDefaultBaseURL
+	if httpConfig.baseURL != "" {
+		baseURL = strings.TrimSuffix(httpConfig.baseURL, "/")
+	}
+
+	provider := &AnthropicProvider{
+		baseURL:     baseURL,
+		apiKey:      apiKey,
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		httpClient:  httpClient,
+		rate:        rate,
+	}
+
+	if err := provider.getModels(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", err)
+	}
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *AnthropicProvider) Name() string {
+	return anthropicProviderName
+}
+
+// SendMessage отправляет сообщения в модель через Anthropic Messages API.
+func (p *AnthropicProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(anthropicProviderName, err))
+	}
+
+	var messagesResponse anthropic.MessagesResponse
+	if err := json.Unmarshal(responseBody, &messagesResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(anthropicProviderName, responseBody, err))
+	}
+
+	structured := isStructuredRequest(request)
+	result := &entities.ProviderMessageResponseDTO{
+		FinishReason: mapAnthropicStopReason(messagesResponse.StopReason, structured),
+		TotalTokens:  anthropicTotalTokens(messagesResponse.Usage),
+	}
+	result.PriceInRubles = p.usagePriceInRubles(request.Model, messagesResponse.Usage)
+
+	var text strings.Builder
+	for _, block := range messagesResponse.Content {
+		switch block.Type {
+		case anthropic.ContentTypeText:
+			text.WriteString(block.Text)
+		case anthropic.ContentTypeToolUse:
+			// Структурированный ответ приходит аргументами служебного инструмента
+			if structured && block.Name == anthropicResponseTool {
+				text.WriteString(string(block.Input))
+				continue
+			}
+			mcpToolCall, err := p.toolsMapper.OpenAIToolCallToMCP(openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool call %d to MCP: %w", len(result.ToolCalls), err)
+			}
+			result.ToolCalls = append(result.ToolCalls, mcpToolCall)
+			result.ToolCallIDs = append(result.ToolCallIDs, block.ID)
+		}
+	}
+	result.MessageText = text.String()
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в модель и возвращает ответ потоком (SSE).
+func (p *AnthropicProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	response, err := p.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	structured := isStructuredRequest(request)
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage anthropic.Usage
+		toolIndexes := make(map[int]int)       // Порядковый номер вызова инструмента по индексу блока
+		structuredBlocks := make(map[int]bool) // Блоки служебного инструмента структурированного ответа
+
+		sendText := func(text string) error {
+			accumulator.addText(text)
+			if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: text}) {
+				return ctx.Err()
+			}
+			return nil
+		}
+		sendToolCall := func(delta entities.ToolCallDelta) error {
+			accumulator.addToolCallDelta(delta)
+			if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+				return ctx.Err()
+			}
+			return nil
+		}
+
+		err := utils.ReadSSEData(response.Body, func(data []byte) error {
+			var event anthropic.StreamEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return newDecodeError(anthropicProviderName, data, err)
+			}
+
+			switch event.Type {
+			case anthropic.EventError:
+				if providerErr := anthropicStreamError(data); providerErr != nil {
+					return providerErr
+				}
+				return newDecodeError(anthropicProviderName, data, nil)
+			case anthropic.EventMessageStart:
+				if event.Message != nil {
+					usage = event.Message.Usage
+				}
+			case anthropic.EventContentBlockStart:
+				if event.ContentBlock == nil || event.ContentBlock.Type != anthropic.ContentTypeToolUse {
+					return nil
+				}
+				if structured && event.ContentBlock.Name == anthropicResponseTool {
+					structuredBlocks[event.Index] = true
+					return nil
+				}
+				toolIndexes[event.Index] = len(toolIndexes)
+				return sendToolCall(entities.ToolCallDelta{
+					Index: toolIndexes[event.Index],
+					ID:    event.ContentBlock.ID,
+					Name:  event.ContentBlock.Name,
+				})
+			case anthropic.EventContentBlockDelta:
+				if event.Delta == nil {
+					return nil
+				}
+				switch event.Delta.Type {
+				case anthropic.DeltaTypeText:
+					if event.Delta.Text != "" {
+						return sendText(event.Delta.Text)
+					}
+				case anthropic.DeltaTypeInputJSON:
+					if event.Delta.PartialJSON == "" {
+						return nil
+					}
+					if structuredBlocks[event.Index] {
+						return sendText(event.Delta.PartialJSON)
+					}
+					if toolIndex, exists := toolIndexes[event.Index]; exists {
+						return sendToolCall(entities.ToolCallDelta{Index: toolIndex, ArgumentsDelta: event.Delta.PartialJSON})
+					}
+				}
+			case anthropic.EventMessageDelta:
+				if event.Delta != nil && event.Delta.StopReason != "" {
+					accumulator.setFinishReason(mapAnthropicStopReason(event.Delta.StopReason, structured))
+				}
+				// usage в message_delta накопительный, нулевые поля не перезаписывают значения из message_start
+				if event.Usage != nil {
+					mergeAnthropicUsage(&usage, *event.Usage)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(anthropicProviderName, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		result.TotalTokens = anthropicTotalTokens(usage)
+		result.PriceInRubles = p.usagePriceInRubles(request.Model, usage)
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo получает информацию о конкретной модели из кэша.
+func (p *AnthropicProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(anthropicProviderName, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *AnthropicProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список моделей и цены.
+func (p *AnthropicProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
+}
+
+// buildRequest конвертирует сообщения и опции в запрос Messages API.
+func (p *AnthropicProvider) buildRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (*anthropic.MessagesRequest, error) {
+	modelID, exists := config.AnthropicNamesMap[modelName]
+	if !exists {
+		return nil, newModelNotSupportedError(anthropicProviderName, string(modelName))
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Модели без информации в кэше проверяет сам API
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		if err := checkImageInput(anthropicProviderName, prepared, modelName, modelInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	system, chatMessages, err := p.convertToMessages(prepared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &anthropic.MessagesRequest{
+		Model:     modelID,
+		Messages:  chatMessages,
+		MaxTokens: anthropicDefaultMaxTokens,
+		System:    system,
+	}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		for _, mcpTool := range mcpTools {
+			openaiTool, err := p.toolsMapper.MCPToolToOpenAI(mcpTool)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert MCP tools to Anthropic: %w", err)
+			}
+			request.Tools = append(request.Tools, anthropic.Tool{
+				Name:        mcpTool.Name,
+				Description: mcpTool.Description,
+				InputSchema: openaiTool.Function.Parameters,
+			})
+		}
+		request.ToolChoice = &anthropic.ToolChoice{Type: anthropic.ToolChoiceAuto}
+	}
+
+	if err := p.applyGenerationParams(request, options.ExtractGenerationParams(opts)); err != nil {
+		return nil, err
+	}
+
+	// Messages API не поддерживает response_format, поэтому модель обязана вызвать служебный инструмент со схемой ответа
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		if len(request.Tools) > 0 {
+			return nil, &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeResponseFormat, Reason: "cannot be combined with MCP tools"}
+		}
+		var inputSchema interface{} = map[string]interface{}{"type": "object"}
+		if responseFormat.Type == options.ResponseFormatJSONSchema && responseFormat.Schema != nil {
+			inputSchema = responseFormat.Schema
+		}
+		request.Tools = []anthropic.Tool{{
+			Name:        anthropicResponseTool,
+			Description: "Return the final answer as JSON matching the input schema.",
+			InputSchema: inputSchema,
+		}}
+		request.ToolChoice = &anthropic.ToolChoice{Type: anthropic.ToolChoiceTool, Name: anthropicResponseTool}
+	}
+
+	return request, nil
+}
+
+// applyGenerationParams переносит параметры генерации в запрос Anthropic.
+// Messages API не поддерживает seed, штрафы и logit_bias, для них возвращается UnsupportedParameterError.
+func (p *AnthropicProvider) applyGenerationParams(request *anthropic.MessagesRequest, params options.GenerationParams) error {
+	if params.Temperature != nil {
+		if *params.Temperature < 0 || *params.Temperature > 1 {
+			return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeTemperature, Reason: "value must be between 0 and 1"}
+		}
+		request.Temperature = params.Temperature
+	}
+	if params.MaxTokens != nil {
+		if *params.MaxTokens <= 0 {
+			return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeMaxTokens, Reason: "value must be positive"}
+		}
+		request.MaxTokens = *params.MaxTokens
+	}
+	request.TopP = params.TopP
+	request.StopSequences = params.Stop
+
+	if params.Seed != nil {
+		return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeSeed, Reason: "not supported by Messages API"}
+	}
+	if params.PresencePenalty != nil {
+		return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypePresencePenalty, Reason: "not supported by Messages API"}
+	}
+	if params.FrequencyPenalty != nil {
+		return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeFrequencyPenalty, Reason: "not supported by Messages API"}
+	}
+	if len(params.LogitBias) > 0 {
+		return &UnsupportedParameterError{Provider: anthropicProviderName, Parameter: options.OptionTypeLogitBias, Reason: "not supported by Messages API"}
+	}
+	return nil
+}
+
+// convertToMessages конвертирует внутренние сообщения в формат Anthropic.
+// Системные сообщения и инструкции разработчика собираются в system, результаты инструментов
+// передаются блоками tool_result в сообщении user, соседние сообщения одной роли объединяются.
+func (p *AnthropicProvider) convertToMessages(messages []*entities.Message) (string, []anthropic.Message, error) {
+	var system []string
+	chatMessages := make([]anthropic.Message, 0, len(messages))
+
+	appendBlocks := func(role string, blocks []anthropic.ContentBlock) {
+		if last := len(chatMessages) - 1; last >= 0 && chatMessages[last].Role == role {
+			chatMessages[last].Content = append(chatMessages[last].Content, blocks...)
+			return
+		}
+		chatMessages = append(chatMessages, anthropic.Message{Role: role, Content: blocks})
+	}
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			if msg.MessageText != "" {
+				system = append(system, msg.MessageText)
+			}
+		case entities.AuthorTypeUser, "":
+			blocks := make([]anthropic.ContentBlock, 0, len(msg.Images)+1)
+			if msg.MessageText != "" {
+				blocks = append(blocks, anthropic.ContentBlock{Type: anthropic.ContentTypeText, Text: msg.MessageText})
+			}
+			for j, image := range msg.Images {
+				source, err := anthropicImageSource(image)
+				if err != nil {
+					return "", nil, fmt.Errorf("message at index %d: invalid image %d: %w", i, j, err)
+				}
+				blocks = append(blocks, anthropic.ContentBlock{Type: anthropic.ContentTypeImage, Source: source})
+			}
+			if len(blocks) == 0 {
+				return "", nil, fmt.Errorf("user message at index %d is empty", i)
+			}
+			appendBlocks(anthropic.RoleUser, blocks)
+		case entities.AuthorTypeRobot:
+			var blocks []anthropic.ContentBlock
+			// Anthropic не принимает пустые текстовые блоки
+			if msg.MessageText != "" {
+				blocks = append(blocks, anthropic.ContentBlock{Type: anthropic.ContentTypeText, Text: msg.MessageText})
+			}
+			if len(msg.ToolCalls) > 0 && len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+				return "", nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+			}
+			for j, mcpCall := range msg.ToolCalls {
+				openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+				if err != nil {
+					return "", nil, fmt.Errorf("failed to convert MCP tool call %d to Anthropic: %w", j, err)
+				}
+				blocks = append(blocks, anthropic.ContentBlock{
+					Type:  anthropic.ContentTypeToolUse,
+					ID:    msg.ToolCallIDs[j],
+					Name:  openaiToolCall.Function.Name,
+					Input: json.RawMessage(openaiToolCall.Function.Arguments),
+				})
+			}
+			if len(blocks) > 0 {
+				appendBlocks(anthropic.RoleAssistant, blocks)
+			}
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return "", nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			appendBlocks(anthropic.RoleUser, []anthropic.ContentBlock{{
+				Type:      anthropic.ContentTypeToolResult,
+				ToolUseID: msg.ToolCallIDs[0],
+				Content:   msg.MessageText,
+			}})
+		default:
+			return "", nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	return strings.Join(system, "\n\n"), chatMessages, nil
+}
+
+// post отправляет запрос в Messages API и возвращает ответ со статусом 200.
+func (p *AnthropicProvider) post(ctx context.Context, request *anthropic.MessagesRequest) (*http.Response, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	p.setHeaders(req)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(anthropicProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		responseBody, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("API request failed: %w", newHTTPError(anthropicProviderName, response.StatusCode, response.Header, responseBody))
+	}
+
+	return response, nil
+}
+
+// setHeaders добавляет ключ API и версию API в заголовки запроса.
+func (p *AnthropicProvider) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropic.APIVersion)
+}
+
+// getModels получает модели из Anthropic API постранично и заполняет кэш моделей.
+func (p *AnthropicProvider) getModels(ctx context.Context) error {
+	usdToRubRate := p.rate.refresh(ctx)
+
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo)
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+		page, err := p.getModelsPage(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, model := range page.Data {
+			for ourModelName, anthropicModelID := range config.AnthropicNamesMap {
+				if model.ID == anthropicModelID {
+					modelsInfo[ourModelName] = &entities.ModelInfo{
+						Name:            model.DisplayName,
+						Alias:           ourModelName,
+						PriceInRubles:   calculateAnthropicPrice(model.ID, usdToRubRate),
+						InputModalities: []string{entities.ModalityText, entities.ModalityImage},
+					}
+				}
+			}
+		}
+
+		if !page.HasMore || page.LastID == "" {
+			break
+		}
+		afterID = page.LastID
+	}
+	p.models.replace(modelsInfo)
+
+	return nil
+}
+
+// getModelsPage загружает одну страницу списка моделей.
+func (p *AnthropicProvider) getModelsPage(ctx context.Context, query url.Values) (*anthropic.ModelsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(req)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(anthropicProviderName, err))
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(anthropicProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed: %w", newHTTPError(anthropicProviderName, response.StatusCode, response.Header, body))
+	}
+
+	var page anthropic.ModelsResponse
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", newDecodeError(anthropicProviderName, body, err))
+	}
+	return &page, nil
+}
+
+// usagePriceInRubles рассчитывает стоимость запроса в рублях по usage и ценам модели.
+// Запись в кэш стоит 1.25 цены входных токенов, чтение из кэша - 0.1.
+func (p *AnthropicProvider) usagePriceInRubles(modelID string, usage anthropic.Usage) decimal.Decimal {
+	price, exists := findTokenPrice(anthropicPrices, modelID)
+	if !exists {
+		return decimal.Zero
+	}
+
+	costUSD := (float64(usage.InputTokens)*price.input +
+		float64(usage.CacheCreationInputTokens)*price.input*1.25 +
+		float64(usage.CacheReadInputTokens)*price.input*0.1 +
+		float64(usage.OutputTokens)*price.output) / 1_000_000
+	return decimal.NewFromFloat(costUSD * p.rate.value()).Round(3)
+}
+
+// calculateAnthropicPrice рассчитывает цену модели в рублях по таблице цен.
+func calculateAnthropicPrice(modelID string, usdToRubRate float64) decimal.Decimal {
+	price, exists := findTokenPrice(anthropicPrices, modelID)
+	if !exists {
+		return decimal.Zero
+	}
+	return price.priceInRubles(usdToRubRate)
+}
+
+// anthropicTotalTokens считает все токены запроса, включая кэшированные.
+func anthropicTotalTokens(usage anthropic.Usage) int64 {
+	return usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens + usage.OutputTokens
+}
+
+// mergeAnthropicUsage переносит ненулевые поля usage из message_delta.
+func mergeAnthropicUsage(usage *anthropic.Usage, delta anthropic.Usage) {
+	if delta.InputTokens > 0 {
+		usage.InputTokens = delta.InputTokens
+	}
+	if delta.OutputTokens > 0 {
+		usage.OutputTokens = delta.OutputTokens
+	}
+	if delta.CacheCreationInputTokens > 0 {
+		usage.CacheCreationInputTokens = delta.CacheCreationInputTokens
+	}
+	if delta.CacheReadInputTokens > 0 {
+		usage.CacheReadInputTokens = delta.CacheReadInputTokens
+	}
+}
+
+// anthropicImageSource конвертирует изображение в источник Anthropic: data URI и байты передаются в base64.
+func anthropicImageSource(image entities.ImageContent) (*anthropic.ImageSource, error) {
+	imageURL, err := image.DataURL()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(imageURL, "data:") {
+		return &anthropic.ImageSource{Type: anthropic.ImageSourceURL, URL: imageURL}, nil
+	}
+
+	mediaType, data, err := splitDataURI(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return &anthropic.ImageSource{Type: anthropic.ImageSourceBase64, MediaType: mediaType, Data: data}, nil
+}
+
+// anthropicStreamError проверяет событие error в потоке и определяет статус по типу ошибки Anthropic.
+func anthropicStreamError(data []byte) *ProviderError {
+	providerErr := streamPayloadError(anthropicProviderName, data)
+	if providerErr == nil {
+		return nil
+	}
+	if status, exists := anthropicErrorStatuses[providerErr.Code]; exists {
+		providerErr.StatusCode = status
+		providerErr.Kind = classifyError(status, providerErr.Code, providerErr.Message)
+	}
+	return providerErr
+}
+
+// isStructuredRequest проверяет, запрошен ли структурированный ответ через служебный инструмент.
+func isStructuredRequest(request *anthropic.MessagesRequest) bool {
+	return request.ToolChoice != nil && request.ToolChoice.Type == anthropic.ToolChoiceTool && request.ToolChoice.Name == anthropicResponseTool
+}
+
+// mapAnthropicStopReason маппит stop_reason Anthropic в общие константы entities.
+// Для структурированного ответа вызов служебного инструмента считается естественным завершением.
+func mapAnthropicStopReason(stopReason string, structured bool) *string {
+	if stopReason == "" {
+		return nil
+	}
+
+	var mappedReason string
+	switch stopReason {
+	case anthropic.StopReasonEndTurn, anthropic.StopReasonStopSequence:
+		mappedReason = entities.FinishReasonStop
+	case anthropic.StopReasonMaxTokens:
+		mappedReason = entities.FinishReasonLength
+	case anthropic.StopReasonToolUse:
+		mappedReason = entities.FinishReasonToolCalls
+		if structured {
+			mappedReason = entities.FinishReasonStop
+		}
+	case anthropic.StopReasonRefusal:
+		mappedReason = entities.FinishReasonContentFilter
+	default:
+		// Если неизвестная причина, возвращаем как есть
+		mappedReason = stopReason
+	}
+
+	return &mappedReason
+}
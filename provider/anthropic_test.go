@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/anthropic"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// newFakeAnthropicServer создает сервер Anthropic API, который отвечает на /v1/messages через handler.
+func newFakeAnthropicServer(t *testing.T, handler func(w http.ResponseWriter, request anthropic.MessagesRequest)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != anthropic.APIVersion {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data": [{"type": "model", "id": "claude-sonnet-4-5-20250929", "display_name": "Claude Sonnet 4.5"}], "has_more": false}`))
+		case "/v1/messages":
+			var request anthropic.MessagesRequest
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			handler(w, request)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestAnthropicProvider(t *testing.T, server *httptest.Server) *AnthropicProvider {
+	// Курс валют не запрашиваем у ЦБ в тестах
+	p, err := NewAnthropicProvider("test-key", WithBaseURL(server.URL+"/v1"), WithUSDToRUBRate(FixedUSDToRUBRate(80)))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return p
+}
+
+func TestAnthropicSendMessageWithToolHistory(t *testing.T) {
+	var received anthropic.MessagesRequest
+	server := newFakeAnthropicServer(t, func(w http.ResponseWriter, request anthropic.MessagesRequest) {
+		received = request
+		w.Write([]byte(`{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5-20250929",
+			"content": [
+				{"type": "text", "text": "Проверю погоду."},
+				{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {"city": "Казань"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 1000, "output_tokens": 100}
+		}`))
+	})
+	defer server.Close()
+
+	p := newTestAnthropicProvider(t, server)
+
+	modelInfo, err := p.GetModelInfo("claude-sonnet-4-5")
+	if err != nil || !modelInfo.SupportsInputModality(entities.ModalityImage) {
+		t.Fatalf("Expected cached model with image input, got %+v, %v", modelInfo, err)
+	}
+
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Погода в Москве?", AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{Data: []byte("png"), MIMEType: "image/png"}}},
+		{
+			AuthorType:  entities.AuthorTypeRobot,
+			ToolCalls:   []mcpgo.CallToolRequest{{Params: mcpgo.CallToolParams{Name: "get_weather", Arguments: map[string]interface{}{"city": "Москва"}}}},
+			ToolCallIDs: []string{"toolu_1"},
+		},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"toolu_1"}},
+		{MessageText: "А в Казани?", AuthorType: entities.AuthorTypeUser},
+	}
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithDescription("Погода"), mcpgo.WithString("city", mcpgo.Required()))
+
+	response, err := p.SendMessage(context.Background(), messages, "claude-sonnet-4-5", options.WithMCPTools([]mcpgo.Tool{tool}), options.WithTemperature(0.2))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if received.Model != "claude-sonnet-4-5-20250929" || received.System != "Ты синоптик" || received.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("Unexpected request: model %q, system %q, max_tokens %d", received.Model, received.System, received.MaxTokens)
+	}
+	if len(received.Tools) != 1 || received.Tools[0].Name != "get_weather" || received.Tools[0].InputSchema == nil {
+		t.Errorf("Expected tool with input schema, got %+v", received.Tools)
+	}
+	// user (текст + картинка), assistant (tool_use), user (tool_result + текст)
+	if len(received.Messages) != 3 {
+		t.Fatalf("Expected 3 messages after merging, got %+v", received.Messages)
+	}
+	if image := received.Messages[0].Content[1]; image.Type != anthropic.ContentTypeImage || image.Source.Type != anthropic.ImageSourceBase64 || image.Source.MediaType != "image/png" {
+		t.Errorf("Expected base64 image block, got %+v", image)
+	}
+	if toolUse := received.Messages[1].Content[0]; toolUse.Type != anthropic.ContentTypeToolUse || toolUse.ID != "toolu_1" || string(toolUse.Input) != `{"city":"Москва"}` {
+		t.Errorf("Expected tool_use block, got %+v", toolUse)
+	}
+	if toolResult := received.Messages[2].Content; len(toolResult) != 2 || toolResult[0].Type != anthropic.ContentTypeToolResult || toolResult[0].ToolUseID != "toolu_1" || toolResult[1].Text != "А в Казани?" {
+		t.Errorf("Expected tool_result followed by user text, got %+v", toolResult)
+	}
+
+	if response.MessageText != "Проверю погоду." || len(response.ToolCalls) != 1 || response.ToolCallIDs[0] != "toolu_2" {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	if args, _ := response.ToolCalls[0].Params.Arguments.(map[string]interface{}); args["city"] != "Казань" {
+		t.Errorf("Expected tool call arguments, got %v", response.ToolCalls[0].Params.Arguments)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+	// (1000 * 3 + 100 * 15) / 1e6 USD * 80 RUB
+	if response.TotalTokens != 1100 || response.PriceInRubles.String() != "0.36" {
+		t.Errorf("Unexpected usage: tokens %d, price %s", response.TotalTokens, response.PriceInRubles)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "claude-sonnet-4-5", options.WithSeed(1)); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected ErrUnsupportedParameter for seed, got %v", err)
+	}
+}
+
+func TestAnthropicExchangeRateFetchedOnRefresh(t *testing.T) {
+	server := newFakeAnthropicServer(t, func(w http.ResponseWriter, request anthropic.MessagesRequest) {
+		w.Write([]byte(`{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5-20250929",
+			"content": [{"type": "text", "text": "ok"}],
+			"stop_reason": "end_turn", "usage": {"input_tokens": 1000, "output_tokens": 100}
+		}`))
+	})
+	defer server.Close()
+
+	calls := 0
+	rate := func(ctx context.Context) (float64, error) {
+		calls++
+		return 100, nil
+	}
+	p, err := NewAnthropicProvider("test-key", WithBaseURL(server.URL+"/v1"), WithUSDToRUBRate(rate))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText}}
+	for i := 0; i < 2; i++ {
+		response, err := p.SendMessage(context.Background(), messages, "claude-sonnet-4-5")
+		// (1000 * 3 + 100 * 15) / 1e6 USD * 100 RUB
+		if err != nil || response.PriceInRubles.String() != "0.45" {
+			t.Fatalf("Expected price by cached rate, got %+v (%v)", response, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected rate to be fetched once on construction, got %d calls", calls)
+	}
+	if err := p.RefreshModels(context.Background()); err != nil || calls != 2 {
+		t.Errorf("Expected RefreshModels to fetch rate again, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestAnthropicSendMessageStream(t *testing.T) {
+	server := newFakeAnthropicServer(t, func(w http.ResponseWriter, request anthropic.MessagesRequest) {
+		if !request.Stream {
+			t.Error("Expected stream request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type": "message_start", "message": {"id": "msg_1", "role": "assistant", "content": [], "usage": {"input_tokens": 20, "output_tokens": 1}}}`,
+			`{"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}`,
+			`{"type": "ping"}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "При"}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "вет"}}`,
+			`{"type": "content_block_stop", "index": 0}`,
+			`{"type": "content_block_start", "index": 1, "content_block": {"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {}}}`,
+			`{"type": "content_block_delta", "index": 1, "delta": {"type": "input_json_delta", "partial_json": "{\"city\": "}}`,
+			`{"type": "content_block_delta", "index": 1, "delta": {"type": "input_json_delta", "partial_json": "\"Москва\"}"}}`,
+			`{"type": "content_block_stop", "index": 1}`,
+			`{"type": "message_delta", "delta": {"stop_reason": "tool_use"}, "usage": {"output_tokens": 15}}`,
+			`{"type": "message_stop"}`,
+		}
+		for _, event := range events {
+			w.Write([]byte("event: x\ndata: " + event + "\n\n"))
+		}
+	})
+	defer server.Close()
+
+	p := newTestAnthropicProvider(t, server)
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "claude-sonnet-4-5")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var text string
+	var toolDeltas int
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkToolCall:
+			toolDeltas++
+			if chunk.ToolCallDelta.Index != 0 {
+				t.Errorf("Expected tool call index 0, got %d", chunk.ToolCallDelta.Index)
+			}
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if text != "Привет" || toolDeltas != 3 || final == nil {
+		t.Fatalf("Unexpected stream: text %q, tool deltas %d, final %+v", text, toolDeltas, final)
+	}
+	if final.TotalTokens != 35 || len(final.ToolCallIDs) != 1 || final.ToolCallIDs[0] != "toolu_1" {
+		t.Errorf("Unexpected final response: %+v", final)
+	}
+	if final.FinishReason == nil || *final.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", final.FinishReason)
+	}
+}
+
+func TestAnthropicStructuredAndStreamError(t *testing.T) {
+	server := newFakeAnthropicServer(t, func(w http.ResponseWriter, request anthropic.MessagesRequest) {
+		if request.Stream {
+			w.Write([]byte("event: error\ndata: {\"type\": \"error\", \"error\": {\"type\": \"overloaded_error\", \"message\": \"Overloaded\"}}\n\n"))
+			return
+		}
+		if request.ToolChoice == nil || request.ToolChoice.Name != anthropicResponseTool {
+			t.Errorf("Expected forced response tool, got %+v", request.ToolChoice)
+		}
+		w.Write([]byte(`{
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "json_response", "input": {"answer": 42}}],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	})
+	defer server.Close()
+
+	p := newTestAnthropicProvider(t, server)
+	messages := []*entities.Message{{MessageText: "Ответ?", AuthorType: entities.AuthorTypeUser}}
+
+	type answer struct {
+		Answer int `json:"answer"`
+	}
+	result, response, err := SendStructured[answer](context.Background(), p, messages, "claude-sonnet-4-5")
+	if err != nil {
+		t.Fatalf("SendStructured() failed with error: %v", err)
+	}
+	if result.Answer != 42 || len(response.ToolCalls) != 0 || *response.FinishReason != entities.FinishReasonStop {
+		t.Errorf("Unexpected structured result %+v, response %+v", result, response)
+	}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "claude-sonnet-4-5")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Type == entities.StreamChunkError {
+			streamErr = chunk.Err
+		}
+	}
+	if !errors.Is(streamErr, ErrServer) {
+		t.Errorf("Expected ErrServer from overloaded_error event, got %v", streamErr)
+	}
+}
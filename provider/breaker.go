@@ -0,0 +1,508 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+var (
+	_ Provider       = (*CircuitBreakerProvider)(nil)
+	_ Wrapper        = (*CircuitBreakerProvider)(nil)
+	_ HealthReporter = (*CircuitBreakerProvider)(nil)
+)
+
+// ErrCircuitOpen запрос не отправлен: провайдер или модель недавно отказывали, цепь разомкнута.
+// RetryAfter в ProviderError - время до пробного запроса.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// windowBuckets количество интервалов скользящего окна.
+const windowBuckets = 10
+
+// CircuitState состояние цепи.
+type CircuitState string
+
+const (
+	// CircuitClosed запросы проходят, статистика собирается в скользящем окне.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen запросы сразу завершаются ошибкой ErrCircuitOpen.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen пропускается ограниченное число пробных запросов: успех замыкает цепь, отказ снова размыкает.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerPolicy описывает правила размыкания цепи.
+type CircuitBreakerPolicy struct {
+	Window           time.Duration    // Длина скользящего окна статистики
+	MinRequests      int              // Минимальное число запросов в окне для оценки доли отказов
+	FailureRate      float64          // Доля отказов (0..1), при которой цепь размыкается
+	SlowCallDuration time.Duration    // Запрос дольше считается медленным (для потока - до первого chunk)
+	SlowCallRate     float64          // Доля медленных запросов, при которой цепь размыкается (0 - не размыкать)
+	OpenTimeout      time.Duration    // Сколько цепь остается разомкнутой до пробных запросов
+	HalfOpenRequests int              // Число успешных пробных запросов для замыкания цепи
+	Failure          func(error) bool // Какие ошибки считать отказом (nil - IsBreakerFailure)
+}
+
+// DefaultCircuitBreakerPolicy возвращает политику по умолчанию: окно в минуту, размыкание при 50% отказов
+// от 10 запросов, пробный запрос через 30 секунд. Задержка не учитывается.
+func DefaultCircuitBreakerPolicy() CircuitBreakerPolicy {
+	return CircuitBreakerPolicy{
+		Window:           time.Minute,
+		MinRequests:      10,
+		FailureRate:      0.5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// IsBreakerFailure сообщает, говорит ли ошибка о неисправности провайдера: временная ошибка (IsRetryable)
+// или истекший таймаут. Ошибки запроса (ErrBadRequest, ErrContentFilter и т.п.) отказом не считаются.
+func IsBreakerFailure(err error) bool {
+	return IsRetryable(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// HealthStatus снимок состояния цепи провайдера или модели.
+type HealthStatus struct {
+	Provider       string             `json:"provider"`             // Название провайдера
+	Model          entities.ModelName `json:"model,omitempty"`      // Модель (пусто - провайдер целиком)
+	State          CircuitState       `json:"state"`                // Состояние цепи
+	Requests       int                `json:"requests"`             // Запросов в окне
+	Failures       int                `json:"failures"`             // Отказов в окне
+	SlowCalls      int                `json:"slow_calls"`           // Медленных запросов в окне
+	ErrorRate      float64            `json:"error_rate"`           // Доля отказов в окне
+	AverageLatency time.Duration      `json:"average_latency"`      // Средняя задержка в окне
+	OpenedAt       time.Time          `json:"opened_at,omitzero"`   // Когда цепь разомкнулась (для open и half_open)
+	LastError      string             `json:"last_error,omitempty"` // Последний отказ
+}
+
+// CircuitBreakerProvider оборачивает провайдера и перестает отправлять запросы, пока провайдер
+// или отдельная модель отказывают. Цепи провайдера и каждой модели независимы: запрос проходит,
+// только если замкнуты обе. Пока цепь разомкнута, запрос сразу завершается ErrCircuitOpen
+// вместо ожидания таймаута; Router в этом случае переходит к следующему провайдеру.
+type CircuitBreakerProvider struct {
+	Provider
+	policy CircuitBreakerPolicy
+	name   string           // Название провайдера для ошибок и снимков состояния
+	now    func() time.Time // Текущее время (подменяется в тестах)
+
+	mu              sync.Mutex
+	providerBreaker *circuitBreaker                        // Цепь провайдера целиком
+	models          map[entities.ModelName]*circuitBreaker // Цепи моделей
+}
+
+// NewCircuitBreakerProvider создает обертку над провайдером с указанной политикой.
+// Незаданные поля политики берутся из DefaultCircuitBreakerPolicy.
+func NewCircuitBreakerProvider(provider Provider, policy CircuitBreakerPolicy) *CircuitBreakerProvider {
+	defaults := DefaultCircuitBreakerPolicy()
+	if policy.Window <= 0 {
+		policy.Window = defaults.Window
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = defaults.MinRequests
+	}
+	if policy.FailureRate <= 0 || policy.FailureRate > 1 {
+		policy.FailureRate = defaults.FailureRate
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = defaults.OpenTimeout
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = defaults.HalfOpenRequests
+	}
+	if policy.Failure == nil {
+		policy.Failure = IsBreakerFailure
+	}
+
+	name := "provider"
+	if namer, ok := Capability[Namer](provider); ok {
+		name = namer.Name()
+	}
+	return &CircuitBreakerProvider{
+		Provider:        provider,
+		policy:          policy,
+		name:            name,
+		now:             time.Now,
+		providerBreaker: newCircuitBreaker(policy.Window),
+		models:          make(map[entities.ModelName]*circuitBreaker),
+	}
+}
+
+// Unwrap возвращает обернутый провайдер.
+func (p *CircuitBreakerProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// SendMessage отправляет сообщения, если цепи провайдера и модели замкнуты, и учитывает результат.
+func (p *CircuitBreakerProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	ticket, err := p.allow(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	start := p.now()
+	response, err := p.Provider.SendMessage(ctx, messages, modelName, opts...)
+	p.record(ticket, err, p.now().Sub(start))
+	return response, err
+}
+
+// SendMessageStream открывает поток, если цепи провайдера и модели замкнуты.
+// Задержкой потока считается время до первого chunk, результатом - итоговый chunk или chunk с ошибкой.
+func (p *CircuitBreakerProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	ticket, err := p.allow(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	start := p.now()
+	chunks, err := p.Provider.SendMessageStream(ctx, messages, modelName, opts...)
+	if err != nil {
+		p.record(ticket, err, p.now().Sub(start))
+		return nil, err
+	}
+
+	observed := make(chan entities.StreamChunk)
+	go func() {
+		defer close(observed)
+
+		var latency time.Duration
+		var streamErr error
+		received, completed := false, false
+		for chunk := range chunks {
+			if !received {
+				latency = p.now().Sub(start)
+				received = true
+			}
+			switch chunk.Type {
+			case entities.StreamChunkError:
+				streamErr = chunk.Err
+			case entities.StreamChunkFinal:
+				completed = true
+			}
+			if !sendStreamChunk(ctx, observed, chunk) {
+				break
+			}
+		}
+		if !received {
+			latency = p.now().Sub(start)
+		}
+		if streamErr == nil && !completed && ctx.Err() != nil {
+			streamErr = ctx.Err()
+		}
+		p.record(ticket, streamErr, latency)
+	}()
+	return observed, nil
+}
+
+// Health возвращает состояние цепи провайдера и цепей моделей, к которым были запросы.
+func (p *CircuitBreakerProvider) Health() []HealthStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.providerBreaker.refresh(now, p.policy)
+	health := []HealthStatus{p.providerBreaker.status(now, p.name, "")}
+
+	modelNames := make([]entities.ModelName, 0, len(p.models))
+	for modelName := range p.models {
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Slice(modelNames, func(i, j int) bool { return modelNames[i] < modelNames[j] })
+	for _, modelName := range modelNames {
+		breaker := p.models[modelName]
+		breaker.refresh(now, p.policy)
+		health = append(health, breaker.status(now, p.name, modelName))
+	}
+	return health
+}
+
+// breakerTicket разрешение на запрос: цепь модели и какие цепи пропустили его как пробный.
+type breakerTicket struct {
+	modelBreaker  *circuitBreaker // Цепь модели (nil - модели нет у провайдера)
+	providerProbe bool            // Пробный запрос цепи провайдера
+	modelProbe    bool            // Пробный запрос цепи модели
+}
+
+// allow проверяет цепи провайдера и модели. Если одна из цепей разомкнута,
+// возвращает ErrCircuitOpen со временем до пробного запроса в RetryAfter.
+// Цепь заводится только для модели, которую знает провайдер (GetModelInfo без ошибки),
+// чтобы запросы к несуществующим моделям не раздували состояние обертки.
+func (p *CircuitBreakerProvider) allow(modelName entities.ModelName) (breakerTicket, error) {
+	_, modelErr := p.Provider.GetModelInfo(modelName)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var ticket breakerTicket
+	if modelErr == nil {
+		ticket.modelBreaker = p.modelBreaker(modelName)
+	}
+
+	var allowed bool
+	var retryAfter time.Duration
+	if ticket.providerProbe, allowed, retryAfter = p.providerBreaker.allow(now, p.policy); !allowed {
+		return ticket, p.openError(modelName, "", retryAfter)
+	}
+	if ticket.modelBreaker == nil {
+		return ticket, nil
+	}
+	if ticket.modelProbe, allowed, retryAfter = ticket.modelBreaker.allow(now, p.policy); !allowed {
+		p.providerBreaker.release(ticket.providerProbe)
+		return ticket, p.openError(modelName, modelName, retryAfter)
+	}
+	return ticket, nil
+}
+
+// record учитывает результат запроса в цепях провайдера и модели.
+// Отмена запроса вызывающим кодом не учитывается.
+func (p *CircuitBreakerProvider) record(ticket breakerTicket, err error, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		p.providerBreaker.release(ticket.providerProbe)
+		if ticket.modelBreaker != nil {
+			ticket.modelBreaker.release(ticket.modelProbe)
+		}
+		return
+	}
+
+	now := p.now()
+	failed := err != nil && p.policy.Failure(err)
+	p.providerBreaker.record(now, p.policy, ticket.providerProbe, failed, latency, err)
+	if ticket.modelBreaker != nil {
+		ticket.modelBreaker.record(now, p.policy, ticket.modelProbe, failed, latency, err)
+	}
+}
+
+// modelBreaker возвращает цепь модели, создавая ее при первом запросе к модели провайдера. Вызывается под p.mu.
+func (p *CircuitBreakerProvider) modelBreaker(modelName entities.ModelName) *circuitBreaker {
+	breaker, exists := p.models[modelName]
+	if !exists {
+		breaker = newCircuitBreaker(p.policy.Window)
+		p.models[modelName] = breaker
+	}
+	return breaker
+}
+
+// openError создает ошибку разомкнутой цепи провайдера (scope пустой) или модели scope.
+func (p *CircuitBreakerProvider) openError(modelName entities.ModelName, scope entities.ModelName, retryAfter time.Duration) error {
+	message := fmt.Sprintf("provider is unavailable, request to model %s rejected", modelName)
+	if scope != "" {
+		message = fmt.Sprintf("model %s is unavailable", scope)
+	}
+	return &ProviderError{Kind: ErrCircuitOpen, Provider: p.name, Message: message, RetryAfter: retryAfter}
+}
+
+// circuitBreaker состояние одной цепи. Методы вызываются под мьютексом CircuitBreakerProvider.
+type circuitBreaker struct {
+	state     CircuitState
+	window    *slidingWindow
+	openedAt  time.Time // Когда цепь разомкнулась
+	probes    int       // Пробных запросов в работе (half_open)
+	successes int       // Успешных пробных запросов (half_open)
+	lastError string    // Последний отказ
+}
+
+// newCircuitBreaker создает замкнутую цепь со скользящим окном длиной window.
+func newCircuitBreaker(window time.Duration) *circuitBreaker {
+	return &circuitBreaker{state: CircuitClosed, window: newSlidingWindow(window)}
+}
+
+// refresh переводит разомкнутую цепь в half_open, когда истек OpenTimeout.
+func (b *circuitBreaker) refresh(now time.Time, policy CircuitBreakerPolicy) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= policy.OpenTimeout {
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.successes = 0
+	}
+}
+
+// allow решает, можно ли отправить запрос. probe - запрос пропущен как пробный,
+// retryAfter - время до пробного запроса, если запрос не пропущен.
+func (b *circuitBreaker) allow(now time.Time, policy CircuitBreakerPolicy) (probe bool, allowed bool, retryAfter time.Duration) {
+	b.refresh(now, policy)
+	switch b.state {
+	case CircuitOpen:
+		return false, false, b.openedAt.Add(policy.OpenTimeout).Sub(now)
+	case CircuitHalfOpen:
+		if b.probes+b.successes >= policy.HalfOpenRequests {
+			return false, false, 0
+		}
+		b.probes++
+		return true, true, 0
+	default:
+		return false, true, 0
+	}
+}
+
+// release возвращает место пробного запроса, результат которого не учитывается.
+func (b *circuitBreaker) release(probe bool) {
+	if probe && b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record учитывает результат запроса и меняет состояние цепи.
+// Результаты обычных запросов, завершившихся после размыкания, попадают только в статистику.
+func (b *circuitBreaker) record(now time.Time, policy CircuitBreakerPolicy, probe bool, failed bool, latency time.Duration, err error) {
+	slow := policy.SlowCallDuration > 0 && latency >= policy.SlowCallDuration
+	b.window.add(now, failed, slow, latency)
+	if failed {
+		b.lastError = err.Error()
+	}
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if !probe {
+			return
+		}
+		b.release(probe)
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= policy.HalfOpenRequests {
+			b.state = CircuitClosed
+			b.window.reset()
+		}
+	case CircuitClosed:
+		stats := b.window.stats(now)
+		if stats.requests < policy.MinRequests {
+			return
+		}
+		if stats.failureRate() >= policy.FailureRate || (policy.SlowCallRate > 0 && stats.slowRate() >= policy.SlowCallRate) {
+			b.open(now)
+		}
+	}
+}
+
+// open размыкает цепь.
+func (b *circuitBreaker) open(now time.Time) {
+	b.state = CircuitOpen
+	b.openedAt = now
+	b.probes = 0
+	b.successes = 0
+}
+
+// status возвращает снимок состояния цепи.
+func (b *circuitBreaker) status(now time.Time, providerName string, modelName entities.ModelName) HealthStatus {
+	stats := b.window.stats(now)
+	status := HealthStatus{
+		Provider:       providerName,
+		Model:          modelName,
+		State:          b.state,
+		Requests:       stats.requests,
+		Failures:       stats.failures,
+		SlowCalls:      stats.slow,
+		ErrorRate:      stats.failureRate(),
+		AverageLatency: stats.averageLatency(),
+		LastError:      b.lastError,
+	}
+	if b.state != CircuitClosed {
+		status.OpenedAt = b.openedAt
+	}
+	return status
+}
+
+// windowBucket статистика одного интервала скользящего окна.
+type windowBucket struct {
+	epoch    int64         // Номер интервала с начала эпохи Unix
+	requests int           // Запросов
+	failures int           // Отказов
+	slow     int           // Медленных запросов
+	latency  time.Duration // Суммарная задержка
+}
+
+// windowStats статистика за окно.
+type windowStats struct {
+	requests int
+	failures int
+	slow     int
+	latency  time.Duration
+}
+
+// failureRate возвращает долю отказов.
+func (s windowStats) failureRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.failures) / float64(s.requests)
+}
+
+// slowRate возвращает долю медленных запросов.
+func (s windowStats) slowRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.slow) / float64(s.requests)
+}
+
+// averageLatency возвращает среднюю задержку.
+func (s windowStats) averageLatency() time.Duration {
+	if s.requests == 0 {
+		return 0
+	}
+	return s.latency / time.Duration(s.requests)
+}
+
+// slidingWindow скользящее окно статистики из windowBuckets интервалов.
+// Окно сдвигается по интервалам, поэтому старые запросы выпадают из статистики порциями.
+type slidingWindow struct {
+	buckets  [windowBuckets]windowBucket
+	interval time.Duration // Длина одного интервала
+}
+
+// newSlidingWindow создает окно длиной window.
+func newSlidingWindow(window time.Duration) *slidingWindow {
+	interval := window / windowBuckets
+	if interval <= 0 {
+		interval = 1
+	}
+	return &slidingWindow{interval: interval}
+}
+
+// add учитывает запрос в текущем интервале.
+func (w *slidingWindow) add(now time.Time, failed bool, slow bool, latency time.Duration) {
+	epoch := now.UnixNano() / int64(w.interval)
+	bucket := &w.buckets[epoch%windowBuckets]
+	if bucket.epoch != epoch {
+		*bucket = windowBucket{epoch: epoch}
+	}
+	bucket.requests++
+	bucket.latency += latency
+	if failed {
+		bucket.failures++
+	}
+	if slow {
+		bucket.slow++
+	}
+}
+
+// stats суммирует интервалы, попадающие в окно.
+func (w *slidingWindow) stats(now time.Time) windowStats {
+	epoch := now.UnixNano() / int64(w.interval)
+	var stats windowStats
+	for _, bucket := range w.buckets {
+		if bucket.requests == 0 || epoch-bucket.epoch >= windowBuckets {
+			continue
+		}
+		stats.requests += bucket.requests
+		stats.failures += bucket.failures
+		stats.slow += bucket.slow
+		stats.latency += bucket.latency
+	}
+	return stats
+}
+
+// reset очищает статистику.
+func (w *slidingWindow) reset() {
+	w.buckets = [windowBuckets]windowBucket{}
+}
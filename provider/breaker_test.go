@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+)
+
+// newTestBreaker создает CircuitBreakerProvider с управляемыми часами.
+func newTestBreaker(inner Provider, policy CircuitBreakerPolicy) (*CircuitBreakerProvider, *time.Time) {
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCircuitBreakerProvider(inner, policy)
+	p.now = func() time.Time { return clock }
+	return p, &clock
+}
+
+// healthOf возвращает снимок состояния модели (пусто - провайдера целиком).
+func healthOf(t *testing.T, p HealthReporter, modelName entities.ModelName) HealthStatus {
+	t.Helper()
+	for _, status := range p.Health() {
+		if status.Model == modelName {
+			return status
+		}
+	}
+	t.Fatalf("No health status for model %q", modelName)
+	return HealthStatus{}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	mock := newRouterMock("HydraAI", 10, "gpt-4o")
+	p, clock := newTestBreaker(mock, CircuitBreakerPolicy{MinRequests: 4, FailureRate: 0.5, OpenTimeout: 30 * time.Second})
+
+	mock.Reply(MockText("ok"), MockText("ok"))
+	mock.ReplyError(&ProviderError{Kind: ErrServer, StatusCode: 503})
+	mock.ReplyError(&ProviderError{Kind: ErrTransport})
+	for i := 0; i < 4; i++ {
+		p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	}
+
+	status := healthOf(t, p, "")
+	if status.State != CircuitOpen || status.Requests != 4 || status.Failures != 2 || status.ErrorRate != 0.5 || status.Provider != "HydraAI" {
+		t.Fatalf("Expected open circuit after 50%% failures, got %+v", status)
+	}
+
+	_, err := p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	var providerErr *ProviderError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &providerErr) || providerErr.RetryAfter != 30*time.Second {
+		t.Errorf("Expected ErrCircuitOpen with Retry-After, got %v", err)
+	}
+	if len(mock.Requests()) != 4 {
+		t.Errorf("Expected no request while circuit is open, got %d requests", len(mock.Requests()))
+	}
+
+	// После OpenTimeout пробный запрос с ошибкой снова размыкает цепь, успешный - замыкает
+	*clock = clock.Add(30 * time.Second)
+	if state := healthOf(t, p, "").State; state != CircuitHalfOpen {
+		t.Errorf("Expected half-open circuit after timeout, got %s", state)
+	}
+	mock.ReplyError(&ProviderError{Kind: ErrServer, StatusCode: 502})
+	p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	if state := healthOf(t, p, "").State; state != CircuitOpen {
+		t.Errorf("Expected failed probe to open circuit, got %s", state)
+	}
+
+	*clock = clock.Add(30 * time.Second)
+	mock.Reply(MockText("ok"))
+	if _, err := p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o"); err != nil {
+		t.Fatalf("Expected probe request to pass, got %v", err)
+	}
+	if status := healthOf(t, p, ""); status.State != CircuitClosed || status.Requests != 0 {
+		t.Errorf("Expected closed circuit with reset window, got %+v", status)
+	}
+}
+
+func TestCircuitBreakerPerModel(t *testing.T) {
+	mock := newRouterMock("HydraAI", 10, "gpt-4o", "claude")
+	p, _ := newTestBreaker(mock, CircuitBreakerPolicy{MinRequests: 2})
+
+	broken := func(request *MockRequest) bool { return request.ModelName == "claude" }
+	mock.Script(&MockResponse{Match: broken, Err: &ProviderError{Kind: ErrServer}, Repeat: true})
+	mock.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+	for i := 0; i < 4; i++ {
+		p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	}
+	for i := 0; i < 2; i++ {
+		p.SendMessage(context.Background(), userMessage("Привет"), "claude")
+	}
+
+	if state := healthOf(t, p, "claude").State; state != CircuitOpen {
+		t.Errorf("Expected open circuit for failing model, got %s", state)
+	}
+	if state := healthOf(t, p, "gpt-4o").State; state != CircuitClosed {
+		t.Errorf("Expected closed circuit for healthy model, got %s", state)
+	}
+	if status := healthOf(t, p, ""); status.State != CircuitClosed || status.Failures != 2 {
+		t.Errorf("Expected closed provider circuit with 2 failures, got %+v", status)
+	}
+	if _, err := p.SendMessage(context.Background(), userMessage("Привет"), "claude"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen for failing model, got %v", err)
+	}
+}
+
+func TestCircuitBreakerUnknownModel(t *testing.T) {
+	mock := newRouterMock("HydraAI", 10, "gpt-4o")
+	mock.Script(&MockResponse{Err: &ProviderError{Kind: ErrModelNotFound, StatusCode: 404}, Repeat: true})
+	p, _ := newTestBreaker(mock, CircuitBreakerPolicy{MinRequests: 2})
+
+	for i := 0; i < 3; i++ {
+		model := entities.ModelName(fmt.Sprintf("made-up-%d", i))
+		if _, err := p.SendMessage(context.Background(), userMessage("Привет"), model); !errors.Is(err, ErrModelNotFound) {
+			t.Errorf("Expected ErrModelNotFound for %s, got %v", model, err)
+		}
+	}
+	if len(p.models) != 0 || len(p.Health()) != 1 {
+		t.Errorf("Expected no model circuits for unknown models, got %d circuits", len(p.models))
+	}
+	if status := healthOf(t, p, ""); status.Requests != 3 || status.Failures != 0 {
+		t.Errorf("Expected unknown models not to count as failures, got %+v", status)
+	}
+}
+
+func TestCircuitBreakerWindowAndLatency(t *testing.T) {
+	mock := newRouterMock("HydraAI", 10, "gpt-4o")
+	mock.Script(&MockResponse{Err: &ProviderError{Kind: ErrBadRequest}})
+	mock.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+	p, clock := newTestBreaker(mock, CircuitBreakerPolicy{
+		Window:           time.Minute,
+		MinRequests:      2,
+		SlowCallDuration: time.Second,
+		SlowCallRate:     0.5,
+	})
+
+	// Ошибка запроса не считается отказом провайдера
+	p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	if status := healthOf(t, p, ""); status.Requests != 1 || status.Failures != 0 {
+		t.Errorf("Expected bad request not to count as failure, got %+v", status)
+	}
+
+	// Запросы выпадают из окна после его окончания
+	*clock = clock.Add(2 * time.Minute)
+	if status := healthOf(t, p, ""); status.Requests != 0 {
+		t.Errorf("Expected empty window, got %+v", status)
+	}
+
+	slow := func() time.Time { return *clock }
+	p.now = func() time.Time {
+		now := slow()
+		*clock = clock.Add(2 * time.Second)
+		return now
+	}
+	p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	p.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	p.now = slow
+	if status := healthOf(t, p, ""); status.State != CircuitOpen || status.SlowCalls != 2 || status.AverageLatency != 2*time.Second {
+		t.Errorf("Expected open circuit after slow calls, got %+v", status)
+	}
+}
+
+func TestCircuitBreakerStreamAndRouter(t *testing.T) {
+	primary := newRouterMock("primary", 10, "gpt-4o")
+	primary.Script(&MockResponse{Err: &ProviderError{Kind: ErrServer}, Repeat: true})
+	secondary := newRouterMock("secondary", 10, "gpt-4o")
+	secondary.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+	breaker, _ := newTestBreaker(primary, CircuitBreakerPolicy{MinRequests: 1})
+	router := NewRouter(RouterPolicy{}, breaker, secondary)
+
+	for i := 0; i < 2; i++ {
+		chunks, err := router.SendMessageStream(context.Background(), userMessage("Привет"), "gpt-4o")
+		if err != nil {
+			t.Fatalf("Expected fallback stream, got %v", err)
+		}
+		for range chunks {
+		}
+	}
+	if len(primary.Requests()) != 1 || len(secondary.Requests()) != 2 {
+		t.Errorf("Expected open circuit to skip primary, got %d and %d requests", len(primary.Requests()), len(secondary.Requests()))
+	}
+
+	health := router.Health()
+	if len(health) != 2 || health[0].Provider != "primary" || health[0].State != CircuitOpen {
+		t.Errorf("Expected router health with open primary, got %+v", health)
+	}
+}
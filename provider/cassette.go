@@ -0,0 +1,392 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+var (
+	_ Provider = (*CassetteProvider)(nil)
+	_ Namer    = (*CassetteProvider)(nil)
+	_ Wrapper  = (*CassetteProvider)(nil)
+)
+
+// Cassette содержит записанные запросы к провайдеру и его ответы.
+type Cassette struct {
+	Provider     string                                     `json:"provider"`         // Название записанного провайдера
+	Models       map[entities.ModelName]*entities.ModelInfo `json:"models,omitempty"` // Информация о моделях, запрошенная при записи
+	Interactions []*CassetteInteraction                     `json:"interactions"`     // Запросы и ответы в порядке записи
+}
+
+// CassetteInteraction содержит один запрос и ответ на него.
+type CassetteInteraction struct {
+	Request  CassetteRequest                      `json:"request"`            // Запрос
+	Response *entities.ProviderMessageResponseDTO `json:"response,omitempty"` // Ответ (nil, если провайдер вернул ошибку)
+	Error    *CassetteError                       `json:"error,omitempty"`    // Ошибка провайдера
+}
+
+// CassetteRequest содержит запрос в сериализуемом виде.
+type CassetteRequest struct {
+	ModelName entities.ModelName  `json:"model"`             // Модель
+	Messages  []*entities.Message `json:"messages"`          // Сообщения
+	Options   []CassetteOption    `json:"options,omitempty"` // Опции запроса
+}
+
+// CassetteOption содержит опцию запроса: тип и значение в JSON.
+type CassetteOption struct {
+	Type  string          `json:"type"`  // Тип опции (options.OptionType*)
+	Value json.RawMessage `json:"value"` // Значение опции
+}
+
+// CassetteError содержит ошибку провайдера. При воспроизведении *ProviderError восстанавливается
+// с категорией, статусом, кодом и Retry-After, поэтому errors.Is(err, ErrRateLimit), пауза RetryProvider
+// и переход Router к следующему провайдеру работают так же, как при записи.
+type CassetteError struct {
+	Kind       string `json:"kind,omitempty"`        // Категория ошибки (текст ErrRateLimit, ErrAuth, ...)
+	Provider   string `json:"provider,omitempty"`    // Провайдер из *ProviderError
+	StatusCode int    `json:"status_code,omitempty"` // HTTP статус
+	Code       string `json:"code,omitempty"`        // Код ошибки провайдера
+	Message    string `json:"message,omitempty"`     // Сообщение *ProviderError
+	Cause      string `json:"cause,omitempty"`       // Текст исходной ошибки *ProviderError
+	RetryAfter string `json:"retry_after,omitempty"` // Пауза из Retry-After (например "30s")
+	Text       string `json:"text,omitempty"`        // Текст ошибки, которая не является *ProviderError
+}
+
+// cassetteTextError воспроизведенная ошибка, которая не была *ProviderError: текст как при записи
+// и категория для errors.Is.
+type cassetteTextError struct {
+	text string
+	kind error
+}
+
+func (e *cassetteTextError) Error() string {
+	return e.text
+}
+
+func (e *cassetteTextError) Unwrap() error {
+	return e.kind
+}
+
+// providerErrorKinds категории ошибок, которые восстанавливаются при воспроизведении кассеты.
+var providerErrorKinds = []error{
+	ErrAuth, ErrRateLimit, ErrQuotaExceeded, ErrContextLength, ErrContentFilter,
+	ErrModelNotFound, ErrBadRequest, ErrServer, ErrTransport, ErrDecode, ErrCircuitOpen,
+}
+
+// CassetteProvider записывает запросы к провайдеру в файл кассеты или воспроизводит их из файла без сети.
+// При воспроизведении запрос должен совпасть с записанным: модель, сообщения и опции.
+// Одинаковые запросы получают записанные ответы по очереди.
+type CassetteProvider struct {
+	mu       sync.Mutex
+	path     string    // Путь к файлу кассеты
+	inner    Provider  // Записываемый провайдер (nil при воспроизведении)
+	cassette *Cassette // Содержимое кассеты
+	used     []bool    // Воспроизведенные записи
+}
+
+// NewCassetteRecorder создает провайдера, который передает запросы в inner и записывает их с ответами в файл path.
+// Файл перезаписывается после каждого запроса, поэтому запись не теряется при падении теста.
+func NewCassetteRecorder(inner Provider, path string) *CassetteProvider {
+	name := ""
+	if namer, ok := Capability[Namer](inner); ok {
+		name = namer.Name()
+	}
+	return &CassetteProvider{
+		path:     path,
+		inner:    inner,
+		cassette: &Cassette{Provider: name, Models: make(map[entities.ModelName]*entities.ModelInfo)},
+	}
+}
+
+// NewCassettePlayer создает провайдера, который отвечает записями из файла кассеты path.
+func NewCassettePlayer(path string) (*CassetteProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	return &CassetteProvider{
+		path:     path,
+		cassette: &cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// Name возвращает название записанного провайдера.
+func (p *CassetteProvider) Name() string {
+	return p.cassette.Provider
+}
+
+// Unwrap возвращает записываемый провайдер (nil при воспроизведении).
+func (p *CassetteProvider) Unwrap() Provider {
+	return p.inner
+}
+
+// SendMessage передает запрос записываемому провайдеру или возвращает записанный ответ.
+func (p *CassetteProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	request, err := newCassetteRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.inner == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return p.replay(request)
+	}
+
+	response, err := p.inner.SendMessage(ctx, messages, modelName, opts...)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// Отмену не записываем: она зависит от теста, а не от провайдера
+		return nil, err
+	}
+	if recordErr := p.record(request, response, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return response, err
+}
+
+// SendMessageStream передает запрос записываемому провайдеру или воспроизводит записанный ответ потоком.
+// При записи сохраняется итоговый ответ потока, поэтому при воспроизведении текст приходит по словам.
+func (p *CassetteProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	request, err := newCassetteRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.inner == nil {
+		response, err := p.replay(request)
+		if err != nil {
+			return nil, err
+		}
+		return streamResponse(ctx, response), nil
+	}
+
+	innerChunks, err := p.inner.SendMessageStream(ctx, messages, modelName, opts...)
+	if err != nil {
+		if recordErr := p.record(request, nil, err); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+
+		for chunk := range innerChunks {
+			switch chunk.Type {
+			case entities.StreamChunkFinal:
+				if err := p.record(request, chunk.Response, nil); err != nil {
+					sendStreamError(ctx, chunks, err)
+					continue
+				}
+			case entities.StreamChunkError:
+				if ctx.Err() == nil {
+					if err := p.record(request, nil, chunk.Err); err != nil {
+						sendStreamError(ctx, chunks, err)
+						continue
+					}
+				}
+			}
+			if !sendStreamChunk(ctx, chunks, chunk) {
+				// Дочитываем поток, чтобы горутина провайдера завершилась
+				for range innerChunks {
+				}
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo возвращает информацию о модели от записываемого провайдера (и записывает ее) или из кассеты.
+func (p *CassetteProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if p.inner == nil {
+		if modelInfo, exists := p.cassette.Models[modelName]; exists {
+			return modelInfo, nil
+		}
+		return nil, newModelNotFoundError(p.cassette.Provider, string(modelName))
+	}
+
+	modelInfo, err := p.inner.GetModelInfo(modelName)
+	if err != nil || modelInfo == nil {
+		return modelInfo, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cassette.Models[modelName] = modelInfo
+	return modelInfo, p.save()
+}
+
+// ListModels возвращает модели записываемого провайдера или модели, записанные в кассету.
+func (p *CassetteProvider) ListModels() ([]*entities.ModelInfo, error) {
+	if p.inner != nil {
+		return p.inner.ListModels()
+	}
+	models := make([]*entities.ModelInfo, 0, len(p.cassette.Models))
+	for _, modelInfo := range p.cassette.Models {
+		models = append(models, modelInfo)
+	}
+	return models, nil
+}
+
+// replay находит первую невоспроизведенную запись с таким же запросом.
+func (p *CassetteProvider) replay(request *CassetteRequest) (*entities.ProviderMessageResponseDTO, error) {
+	key, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, interaction := range p.cassette.Interactions {
+		if p.used[i] {
+			continue
+		}
+		recordedKey, err := json.Marshal(interaction.Request)
+		if err != nil || !bytes.Equal(key, recordedKey) {
+			continue
+		}
+		p.used[i] = true
+		if interaction.Error != nil {
+			return nil, interaction.Error.err()
+		}
+		response := *interaction.Response
+		return &response, nil
+	}
+
+	return nil, fmt.Errorf("%w: cassette %s has no recorded request for model %s with %d messages", ErrNoMockResponse, p.path, request.ModelName, len(request.Messages))
+}
+
+// record добавляет запрос с ответом или ошибкой в кассету и сохраняет файл.
+func (p *CassetteProvider) record(request *CassetteRequest, response *entities.ProviderMessageResponseDTO, err error) error {
+	interaction := &CassetteInteraction{Request: *request, Response: response}
+	if err != nil {
+		interaction.Error = newCassetteError(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction)
+	return p.save()
+}
+
+// save записывает кассету в файл. Вызывается под мьютексом.
+func (p *CassetteProvider) save() error {
+	data, err := json.MarshalIndent(p.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.WriteFile(p.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// newCassetteRequest переводит запрос в сериализуемый вид для записи и сравнения.
+func newCassetteRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (*CassetteRequest, error) {
+	request := &CassetteRequest{ModelName: modelName, Messages: messages}
+	for _, option := range opts {
+		value, err := json.Marshal(option)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal option %s: %w", option.OptionType(), err)
+		}
+		request.Options = append(request.Options, CassetteOption{Type: option.OptionType(), Value: value})
+	}
+
+	// Нормализуем через JSON, чтобы запрос совпадал с прочитанным из файла (например, аргументы инструментов)
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var normalized CassetteRequest
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to normalize request: %w", err)
+	}
+	return &normalized, nil
+}
+
+// newCassetteError сохраняет поля ошибки провайдера, чтобы воспроизвести ее с тем же текстом.
+func newCassetteError(err error) *CassetteError {
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		cassetteErr := &CassetteError{Text: err.Error()}
+		for _, kind := range providerErrorKinds {
+			if errors.Is(err, kind) {
+				cassetteErr.Kind = kind.Error()
+				break
+			}
+		}
+		return cassetteErr
+	}
+
+	cassetteErr := &CassetteError{
+		Provider:   providerErr.Provider,
+		StatusCode: providerErr.StatusCode,
+		Code:       providerErr.Code,
+		Message:    providerErr.Message,
+	}
+	if providerErr.Kind != nil {
+		cassetteErr.Kind = providerErr.Kind.Error()
+	}
+	if providerErr.Err != nil {
+		cassetteErr.Cause = providerErr.Err.Error()
+	}
+	if providerErr.RetryAfter > 0 {
+		cassetteErr.RetryAfter = providerErr.RetryAfter.String()
+	}
+	return cassetteErr
+}
+
+// err восстанавливает ошибку: записанную из *ProviderError - как *ProviderError,
+// прочую - с тем же текстом и категорией, если она известна.
+func (e *CassetteError) err() error {
+	var kind error
+	for _, known := range providerErrorKinds {
+		if known.Error() == e.Kind {
+			kind = known
+			break
+		}
+	}
+	if e.Text != "" {
+		if kind == nil {
+			return errors.New(e.Text)
+		}
+		return &cassetteTextError{text: e.Text, kind: kind}
+	}
+	if kind == nil {
+		kind = errors.New(e.Kind)
+	}
+
+	providerErr := &ProviderError{Kind: kind, Provider: e.Provider, StatusCode: e.StatusCode, Code: e.Code, Message: e.Message}
+	if e.Cause != "" {
+		providerErr.Err = errors.New(e.Cause)
+	}
+	if e.RetryAfter != "" {
+		retryAfter, err := time.ParseDuration(e.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("invalid retry_after %q in cassette: %w", e.RetryAfter, err)
+		}
+		providerErr.RetryAfter = retryAfter
+	}
+	return providerErr
+}
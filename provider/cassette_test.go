@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather.json")
+
+	recordedErr := &ProviderError{Kind: ErrRateLimit, Provider: "HydraAI", StatusCode: 429, Code: "rate_limit", Message: "slow down", RetryAfter: 30 * time.Second}
+	inner := NewMockProvider(&entities.ModelInfo{Name: "GPT-4o", Alias: "gpt-4o", PriceInRubles: decimal.NewFromInt(1000)})
+	toolCall := MockToolCalls(MockToolCall("get_weather", map[string]interface{}{"city": "Москва", "days": 1}))
+	toolCall.TotalTokens = 100
+	inner.Reply(toolCall, MockText("В Москве +5")).ReplyError(recordedErr)
+
+	recorder := NewCassetteRecorder(inner, path)
+	messages := []*entities.Message{{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser}}
+	opts := []options.SendMessageOption{options.WithTemperature(0), options.WithSystemPrompt("Ты синоптик")}
+
+	first, err := recorder.SendMessage(context.Background(), messages, "gpt-4o", opts...)
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	if _, err := recorder.GetModelInfo("gpt-4o"); err != nil {
+		t.Fatalf("GetModelInfo() failed with error: %v", err)
+	}
+	chunks, err := recorder.SendMessageStream(context.Background(), messages, "gpt-4o")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	for range chunks {
+	}
+	if _, err := recorder.SendMessage(context.Background(), messages, "gpt-4o", opts...); !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("Expected recorded ErrRateLimit, got %v", err)
+	}
+
+	player, err := NewCassettePlayer(path)
+	if err != nil {
+		t.Fatalf("NewCassettePlayer() failed with error: %v", err)
+	}
+	if player.Name() != mockProviderName {
+		t.Errorf("Expected recorded provider name, got %s", player.Name())
+	}
+
+	replayed, err := player.SendMessage(context.Background(), messages, "gpt-4o", opts...)
+	if err != nil {
+		t.Fatalf("SendMessage() replay failed with error: %v", err)
+	}
+	if replayed.ToolCallIDs[0] != first.ToolCallIDs[0] || replayed.ToolCalls[0].Params.Name != "get_weather" || !replayed.PriceInRubles.Equal(first.PriceInRubles) {
+		t.Errorf("Replayed response differs from recorded: %+v vs %+v", replayed, first)
+	}
+
+	// Тот же запрос получает следующую запись, ошибка восстанавливается со своей категорией
+	var providerErr *ProviderError
+	if _, err := player.SendMessage(context.Background(), messages, "gpt-4o", opts...); !errors.As(err, &providerErr) || providerErr.Kind != ErrRateLimit || providerErr.StatusCode != 429 {
+		t.Errorf("Expected replayed ErrRateLimit with status, got %v", err)
+	} else if providerErr.Error() != recordedErr.Error() || providerErr.RetryAfter != recordedErr.RetryAfter || providerErr.Code != recordedErr.Code {
+		t.Errorf("Expected replayed error %q with Retry-After %s, got %q with %s", recordedErr, recordedErr.RetryAfter, providerErr, providerErr.RetryAfter)
+	}
+	if _, err := player.SendMessage(context.Background(), messages, "gpt-4o", options.WithTemperature(1)); !errors.Is(err, ErrNoMockResponse) {
+		t.Errorf("Expected ErrNoMockResponse for unrecorded request, got %v", err)
+	}
+
+	chunks, err = player.SendMessageStream(context.Background(), messages, "gpt-4o")
+	if err != nil {
+		t.Fatalf("SendMessageStream() replay failed with error: %v", err)
+	}
+	var text string
+	for chunk := range chunks {
+		text += chunk.TextDelta
+	}
+	if text != "В Москве +5" {
+		t.Errorf("Expected replayed stream text, got %q", text)
+	}
+
+	if modelInfo, err := player.GetModelInfo("gpt-4o"); err != nil || !modelInfo.PriceInRubles.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("Expected recorded model info, got %+v, %v", modelInfo, err)
+	}
+}
+
+func TestCassetteErrorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"provider error", &ProviderError{Kind: ErrServer, Provider: "Anthropic", StatusCode: 529, Message: "overloaded", RetryAfter: 1500 * time.Millisecond}, ErrServer},
+		{"provider error with cause", &ProviderError{Kind: ErrTransport, Provider: "Gemini", Err: errors.New("connection reset")}, ErrTransport},
+		{"wrapped kind", fmt.Errorf("stream interrupted: %w", ErrTransport), ErrTransport},
+		{"plain error", errors.New("unexpected end of cassette"), nil},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(newCassetteError(tt.err))
+		if err != nil {
+			t.Fatalf("%s: failed to marshal: %v", tt.name, err)
+		}
+		var cassetteErr CassetteError
+		if err := json.Unmarshal(data, &cassetteErr); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", tt.name, err)
+		}
+
+		replayed := cassetteErr.err()
+		if replayed.Error() != tt.err.Error() {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.err, replayed)
+		}
+		if tt.kind != nil && !errors.Is(replayed, tt.kind) {
+			t.Errorf("%s: expected replayed error to match %v", tt.name, tt.kind)
+		}
+		var recorded, restored *ProviderError
+		if errors.As(tt.err, &recorded) && (!errors.As(replayed, &restored) || restored.RetryAfter != recorded.RetryAfter) {
+			t.Errorf("%s: expected Retry-After %s, got %+v", tt.name, recorded.RetryAfter, restored)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientOptionsHeadersAndBaseURL(t *testing.T) {
+	var userAgent, team string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		team = r.Header.Get("X-Team")
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHydraAIProvider("token", "https://hydra.invalid",
+		WithBaseURL(server.URL),
+		WithUserAgent("m-ai-provider/test"),
+		WithHeader("X-Team", "billing"),
+		WithTimeout(5*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if userAgent != "m-ai-provider/test" || team != "billing" {
+		t.Errorf("Expected custom headers, got User-Agent %q and X-Team %q", userAgent, team)
+	}
+
+	if _, err := NewOpenRouterProvider("token", WithBaseURL(server.URL), WithUserAgent("m-ai-provider/test"), WithUSDToRUBRate(FixedUSDToRUBRate(80))); err != nil {
+		t.Fatalf("Failed to create OpenRouter provider with custom base URL: %v", err)
+	}
+	if userAgent != "m-ai-provider/test" {
+		t.Errorf("Expected OpenRouter request to use custom User-Agent, got %q", userAgent)
+	}
+}
+
+func TestClientOptionsProxy(t *testing.T) {
+	var proxiedURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedURL = r.URL.String()
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer proxy.Close()
+
+	if _, err := NewHydraAIProvider("token", "http://hydra.invalid/v1", WithProxy(proxy.URL)); err != nil {
+		t.Fatalf("Failed to create provider through proxy: %v", err)
+	}
+	if proxiedURL != "http://hydra.invalid/v1/models" {
+		t.Errorf("Expected request to go through proxy, got %q", proxiedURL)
+	}
+
+	if _, err := NewHydraAIProvider("token", "http://hydra.invalid", WithProxy("ftp://proxy:21")); err == nil {
+		t.Error("Expected error for unsupported proxy scheme")
+	}
+
+	if _, err := NewHydraAIProvider("token", "http://hydra.invalid", WithTransport(roundTripFunc(nil)), WithProxy("socks5://proxy:1080")); err == nil {
+		t.Error("Expected error for proxy with custom transport")
+	}
+}
+
+// roundTripFunc позволяет использовать функцию как http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,102 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/provider"
+	"github.com/Murolando/m_ai_provider/providertest"
+	"github.com/shopspring/decimal"
+)
+
+func TestOpenAICompatibleConformance(t *testing.T) {
+	providertest.Run(t, providertest.Suite{
+		Model: "deepseek-chat",
+		New: func(t *testing.T) (provider.Provider, providertest.Backend) {
+			server := providertest.NewServer()
+			t.Cleanup(server.Close)
+
+			p, err := provider.NewOpenAICompatibleProvider(provider.OpenAICompatibleConfig{
+				Name:    "DeepSeek",
+				BaseURL: server.URL + "/v1",
+				APIKey:  "secret",
+				Quirks: provider.OpenAICompatibleQuirks{
+					Cost: func(usage json.RawMessage) (decimal.Decimal, error) {
+						var cost struct {
+							Cost float64 `json:"cost"`
+						}
+						err := json.Unmarshal(usage, &cost)
+						return decimal.NewFromFloat(cost.Cost), err
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+			return p, server
+		},
+		Usage: map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20, "cost": 0.5},
+		Price: decimal.NewFromFloat(0.5),
+	})
+}
+
+func TestHydraAIConformance(t *testing.T) {
+	providertest.Run(t, providertest.Suite{
+		Model: "gpt-4-1",
+		New: func(t *testing.T) (provider.Provider, providertest.Backend) {
+			server := providertest.NewServer()
+			t.Cleanup(server.Close)
+			server.SetModels(`{"data": [
+				{"id": "gpt-4.1", "name": "GPT-4.1", "active": true, "input_modalities": ["text", "image"], "pricing": {"type": "tokens", "in_cost_per_million": 250, "out_cost_per_million": 1000}},
+				{"id": "gpt-4.1-mini", "name": "GPT-4.1 mini", "active": false, "pricing": {"type": "tokens", "cost_per_million": 60}}
+			]}`)
+
+			p, err := provider.NewHydraAIProvider("secret", server.URL)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			modelInfo, err := p.GetModelInfo("gpt-4-1")
+			if err != nil || !modelInfo.PriceInRubles.Equal(decimal.NewFromInt(1250)) {
+				t.Fatalf("Expected gpt-4-1 priced at 1250 rubles, got %+v, %v", modelInfo, err)
+			}
+			if _, err := p.GetModelInfo("gpt-4-1-mini"); err == nil {
+				t.Fatal("Expected inactive model to be skipped")
+			}
+			return p, server
+		},
+		Usage: map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20, "cost_request": 1.25},
+		Price: decimal.NewFromFloat(1.25),
+	})
+}
+
+func TestOpenRouterConformance(t *testing.T) {
+	providertest.Run(t, providertest.Suite{
+		Model: "glm-4-5-air",
+		New: func(t *testing.T) (provider.Provider, providertest.Backend) {
+			server := providertest.NewServer()
+			t.Cleanup(server.Close)
+			server.SetModels(`{"data": [
+				{"id": "z-ai/glm-4.5-air:free", "name": "GLM 4.5 Air", "architecture": {"input_modalities": ["text"]}, "pricing": {"prompt": "0.5", "completion": "1"}}
+			]}`)
+			server.SetUSDToRUBRate(90)
+
+			// Курс доллара запрашивается у ЦБ РФ, поэтому все запросы направляются на фейковый сервер
+			p, err := provider.NewOpenRouterProvider("secret",
+				provider.WithBaseURL(server.URL+"/api/v1"),
+				provider.WithTransport(server.Transport()),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create provider: %v", err)
+			}
+
+			modelInfo, err := p.GetModelInfo("glm-4-5-air")
+			if err != nil || !modelInfo.PriceInRubles.Equal(decimal.NewFromInt(135)) {
+				t.Fatalf("Expected glm-4-5-air priced at 135 rubles, got %+v, %v", modelInfo, err)
+			}
+			return p, server
+		},
+		Usage: map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20, "cost": 0.01},
+		Price: decimal.NewFromFloat(0.9),
+	})
+}
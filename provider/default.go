@@ -2,12 +2,11 @@ package provider
 
 import (
 	"context"
+	"strings"
 
 	"github.com/Murolando/m_ai_provider/entities"
-	internalEnt "github.com/Murolando/m_ai_provider/internal/entities"
 	"github.com/Murolando/m_ai_provider/internal/utils"
 	"github.com/Murolando/m_ai_provider/options"
-	"github.com/shopspring/decimal"
 )
 
 var _ Provider = (*DefaultProvider)(nil)
@@ -22,23 +21,53 @@ func NewDefaultProvider() *DefaultProvider {
 
 // SendMessage отправляет сообщения через DefaultProvider (возвращает тестовый ответ).
 func (p *DefaultProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, options ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
-	message := utils.MakeRequestMessageString(messages)
+	prepared, err := prepareMessages(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	message := utils.MakeRequestMessageString(prepared)
 	return &entities.ProviderMessageResponseDTO{
 		MessageText: "DEFAULT ANSWER FOR " + message,
 	}, nil
 }
 
+// SendMessageStream отправляет сообщения через DefaultProvider (возвращает тестовый ответ по словам).
+func (p *DefaultProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, options ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	response, err := p.SendMessage(ctx, messages, modelName, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+
+		for _, word := range strings.SplitAfter(response.MessageText, " ") {
+			if word == "" {
+				continue
+			}
+			if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: word}) {
+				return
+			}
+		}
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: response})
+	}()
+
+	return chunks, nil
+}
+
 // GetModelInfo получает информацию о модели (всегда возвращает nil для DefaultProvider).
 func (p *DefaultProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
 	return nil, nil
 }
 
-// calculatePrice рассчитывает цену (DefaultProvider всегда возвращает нулевую цену).
-func (p *DefaultProvider) calculatePrice(params internalEnt.PricingParams) (decimal.Decimal, error) {
-	return decimal.Zero, nil
+// ListModels возвращает список моделей (DefaultProvider не знает о моделях).
+func (p *DefaultProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return nil, nil
 }
 
-// getModels загружает модели (DefaultProvider не загружает модели).
-func (p *DefaultProvider) getModels() error {
-	return nil
+// Name возвращает название провайдера.
+func (p *DefaultProvider) Name() string {
+	return "Default"
 }
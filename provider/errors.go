@@ -0,0 +1,302 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Категории ошибок провайдеров. Проверяются через errors.Is, детали доступны через errors.As с *ProviderError.
+var (
+	// ErrAuth неверный или отозванный API ключ, нет доступа к ресурсу.
+	ErrAuth = errors.New("authentication failed")
+	// ErrRateLimit превышен лимит запросов, запрос можно повторить позже.
+	ErrRateLimit = errors.New("rate limit exceeded")
+	// ErrQuotaExceeded закончился баланс или квота аккаунта.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrContextLength запрос не помещается в контекстное окно модели.
+	ErrContextLength = errors.New("context length exceeded")
+	// ErrContentFilter запрос или ответ заблокирован фильтром контента.
+	ErrContentFilter = errors.New("content filtered")
+	// ErrModelNotFound модель не поддерживается провайдером или не найдена.
+	ErrModelNotFound = errors.New("model not found")
+	// ErrBadRequest провайдер отклонил запрос как некорректный.
+	ErrBadRequest = errors.New("bad request")
+	// ErrServer внутренняя ошибка или недоступность провайдера (5xx).
+	ErrServer = errors.New("provider server error")
+	// ErrTransport сетевая ошибка: не удалось отправить запрос или прочитать ответ.
+	ErrTransport = errors.New("transport error")
+	// ErrDecode ответ провайдера не удалось разобрать.
+	ErrDecode = errors.New("decode error")
+)
+
+// ProviderError описывает ошибку, полученную от провайдера.
+type ProviderError struct {
+	Kind       error         // Категория ошибки (ErrAuth, ErrRateLimit, ...)
+	Provider   string        // Название провайдера
+	StatusCode int           // HTTP статус ответа (0, если ответа не было)
+	Code       string        // Код ошибки провайдера, если есть
+	Message    string        // Сообщение об ошибке
+	RetryAfter time.Duration // Значение заголовка Retry-After (0, если не задан)
+	Body       []byte        // Тело ответа с ошибкой
+	Err        error         // Исходная ошибка, если есть
+}
+
+// Error возвращает текст ошибки.
+func (e *ProviderError) Error() string {
+	var text strings.Builder
+	if e.Provider != "" {
+		text.WriteString(e.Provider)
+		text.WriteString(": ")
+	}
+	text.WriteString(e.Kind.Error())
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&text, " (status %d", e.StatusCode)
+		if e.Code != "" {
+			fmt.Fprintf(&text, ", code %s", e.Code)
+		}
+		text.WriteString(")")
+	}
+	if e.Message != "" {
+		text.WriteString(": ")
+		text.WriteString(e.Message)
+	}
+	if e.Err != nil {
+		text.WriteString(": ")
+		text.WriteString(e.Err.Error())
+	}
+	return text.String()
+}
+
+// Unwrap позволяет проверять категорию и исходную ошибку через errors.Is и errors.As.
+func (e *ProviderError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// ErrUnsupportedParameter возвращается, когда провайдер не может применить параметр запроса.
+// Используйте errors.Is для проверки и errors.As с *UnsupportedParameterError для деталей.
+var ErrUnsupportedParameter = errors.New("unsupported parameter")
+
+// UnsupportedParameterError описывает параметр, который провайдер не может применить.
+type UnsupportedParameterError struct {
+	Provider  string // Название провайдера
+	Parameter string // Тип опции (options.OptionType*)
+	Reason    string // Причина, по которой параметр не может быть применен
+}
+
+// Error возвращает текст ошибки.
+func (e *UnsupportedParameterError) Error() string {
+	return fmt.Sprintf("parameter %s is not supported by %s provider: %s", e.Parameter, e.Provider, e.Reason)
+}
+
+// Is позволяет сравнивать ошибку с ErrUnsupportedParameter через errors.Is.
+func (e *UnsupportedParameterError) Is(target error) bool {
+	return target == ErrUnsupportedParameter
+}
+
+// ErrUnsupportedModality возвращается, когда модель не принимает входные данные указанного типа (например, изображения).
+var ErrUnsupportedModality = errors.New("unsupported input modality")
+
+// newModelNotSupportedError создает ошибку для модели, которой нет в маппинге провайдера.
+func newModelNotSupportedError(providerName string, modelName string) *ProviderError {
+	return &ProviderError{
+		Kind:     ErrModelNotFound,
+		Provider: providerName,
+		Message:  fmt.Sprintf("model %s not supported by %s provider", modelName, providerName),
+	}
+}
+
+// newModelNotFoundError создает ошибку для модели, которой нет в кэше моделей провайдера.
+func newModelNotFoundError(providerName string, modelName string) *ProviderError {
+	return &ProviderError{
+		Kind:     ErrModelNotFound,
+		Provider: providerName,
+		Message:  fmt.Sprintf("model %s not found in %s provider", modelName, providerName),
+	}
+}
+
+// newTransportError создает ошибку отправки запроса или чтения ответа.
+// Отмена контекста возвращается как есть, чтобы ее не путали с сетевой ошибкой.
+func newTransportError(providerName string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &ProviderError{Kind: ErrTransport, Provider: providerName, Err: err}
+}
+
+// newDecodeError создает ошибку разбора ответа провайдера.
+func newDecodeError(providerName string, body []byte, err error) *ProviderError {
+	return &ProviderError{Kind: ErrDecode, Provider: providerName, Body: body, Err: err}
+}
+
+// newHTTPError создает ошибку по неуспешному HTTP ответу провайдера.
+// Категория определяется по статусу, коду и тексту ошибки в теле ответа.
+func newHTTPError(providerName string, statusCode int, header http.Header, body []byte) *ProviderError {
+	code, message := parseErrorBody(body)
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	return &ProviderError{
+		Kind:       classifyError(statusCode, code, message),
+		Provider:   providerName,
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		RetryAfter: parseRetryAfter(header),
+		Body:       body,
+	}
+}
+
+// streamPayloadError проверяет, не содержит ли событие потока ошибку вида {"error": ...}.
+// Статус берется из кода ошибки, если это HTTP статус, иначе ошибка считается ошибкой сервера.
+func streamPayloadError(providerName string, data []byte) *ProviderError {
+	var payload struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || len(payload.Error) == 0 || string(payload.Error) == "null" {
+		return nil
+	}
+
+	code, message := parseErrorBody(data)
+	statusCode := http.StatusInternalServerError
+	if status, err := strconv.Atoi(code); err == nil && status >= 400 && status < 600 {
+		statusCode = status
+	}
+	return &ProviderError{
+		Kind:       classifyError(statusCode, code, message),
+		Provider:   providerName,
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		Body:       data,
+	}
+}
+
+// classifyError определяет категорию ошибки по HTTP статусу, коду и тексту ошибки.
+func classifyError(statusCode int, code string, message string) error {
+	text := strings.ToLower(code + " " + message)
+
+	// Сначала смотрим на код и текст: провайдеры часто отдают их с общим статусом 400
+	switch {
+	case containsAny(text, "context_length", "context length", "maximum context", "too many tokens", "prompt is too long"):
+		return ErrContextLength
+	case containsAny(text, "content_filter", "content filter", "content_policy", "content policy", "moderation"):
+		return ErrContentFilter
+	case containsAny(text, "insufficient_quota", "insufficient quota", "insufficient credits", "insufficient balance", "quota"):
+		return ErrQuotaExceeded
+	case containsAny(text, "model_not_found", "model not found", "no such model", "unknown model", "is not a valid model"):
+		return ErrModelNotFound
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusPaymentRequired:
+		return ErrQuotaExceeded
+	case statusCode == http.StatusNotFound:
+		return ErrModelNotFound
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ErrContextLength
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case statusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return ErrBadRequest
+	}
+}
+
+// parseErrorBody извлекает код и сообщение из тела ответа с ошибкой.
+// Поддерживает форматы {"error": {"code", "type", "message"}}, {"error": "..."} и {"code", "message"}.
+func parseErrorBody(body []byte) (string, string) {
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Code    interface{}     `json:"code"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", ""
+	}
+
+	if len(payload.Error) > 0 {
+		var nested struct {
+			Code    interface{} `json:"code"`
+			Type    string      `json:"type"`
+			Message string      `json:"message"`
+		}
+		if err := json.Unmarshal(payload.Error, &nested); err == nil {
+			code := codeString(nested.Code)
+			if code == "" {
+				code = nested.Type
+			}
+			return code, nested.Message
+		}
+
+		var message string
+		if err := json.Unmarshal(payload.Error, &message); err == nil {
+			return codeString(payload.Code), message
+		}
+	}
+
+	return codeString(payload.Code), payload.Message
+}
+
+// parseRetryAfter разбирает заголовок Retry-After (секунды или HTTP дата) и retry-after-ms.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if value := header.Get("Retry-After-Ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// codeString приводит код ошибки (строка или число) к строке.
+func codeString(code interface{}) string {
+	switch c := code.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64)
+	default:
+		return fmt.Sprint(c)
+	}
+}
+
+// containsAny проверяет, содержит ли текст одну из подстрок.
+func containsAny(text string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(text, substring) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/revrost/go-openrouter"
+)
+
+func TestNewHTTPErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   error
+	}{
+		{"auth", http.StatusUnauthorized, `{"error": {"message": "Invalid API key", "code": "invalid_api_key"}}`, ErrAuth},
+		{"rate limit", http.StatusTooManyRequests, `{"error": {"message": "Rate limit reached"}}`, ErrRateLimit},
+		{"quota", http.StatusTooManyRequests, `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota"}}`, ErrQuotaExceeded},
+		{"payment required", http.StatusPaymentRequired, `{"error": {"message": "Insufficient credits", "code": 402}}`, ErrQuotaExceeded},
+		{"context length", http.StatusBadRequest, `{"error": {"message": "This model's maximum context length is 8192 tokens", "code": "context_length_exceeded"}}`, ErrContextLength},
+		{"content filter", http.StatusBadRequest, `{"error": {"message": "Request blocked by moderation"}}`, ErrContentFilter},
+		{"model not found", http.StatusNotFound, `{"error": "no such model"}`, ErrModelNotFound},
+		{"bad request", http.StatusBadRequest, `{"message": "invalid messages"}`, ErrBadRequest},
+		{"server", http.StatusBadGateway, `upstream unavailable`, ErrServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newHTTPError("test", tt.statusCode, nil, []byte(tt.body))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err.Kind)
+			}
+			if err.StatusCode != tt.statusCode || string(err.Body) != tt.body {
+				t.Errorf("Expected status and body to be preserved, got %d %q", err.StatusCode, err.Body)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	if delay := parseRetryAfter(header); delay != 3*time.Second {
+		t.Errorf("Expected 3s, got %v", delay)
+	}
+
+	header.Set("Retry-After-Ms", "1500")
+	if delay := parseRetryAfter(header); delay != 1500*time.Millisecond {
+		t.Errorf("Expected retry-after-ms to take precedence, got %v", delay)
+	}
+
+	header = http.Header{}
+	header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if delay := parseRetryAfter(header); delay <= 0 || delay > time.Minute {
+		t.Errorf("Expected delay up to 1m from HTTP date, got %v", delay)
+	}
+
+	if delay := parseRetryAfter(nil); delay != 0 {
+		t.Errorf("Expected 0 without header, got %v", delay)
+	}
+}
+
+func TestStreamPayloadError(t *testing.T) {
+	err := streamPayloadError("test", []byte(`{"error": {"message": "Provider returned error", "code": 502}}`))
+	if !errors.Is(err, ErrServer) || err.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected server error with status 502, got %v", err)
+	}
+
+	if err := streamPayloadError("test", []byte(`{"choices": []}`)); err != nil {
+		t.Errorf("Expected no error for regular chunk, got %v", err)
+	}
+}
+
+func TestHydraSendMessageRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"message": "Too many requests", "code": "rate_limit"}}`))
+	}))
+	defer server.Close()
+
+	p, err := NewHydraAIProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	_, err = p.SendMessage(context.Background(), messages, "claude-sonnet-4")
+	if !errors.Is(err, ErrRateLimit) {
+		t.Fatalf("Expected ErrRateLimit, got %v", err)
+	}
+
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected *ProviderError, got %T", err)
+	}
+	if providerErr.StatusCode != http.StatusTooManyRequests || providerErr.Code != "rate_limit" || providerErr.RetryAfter != 2*time.Second {
+		t.Errorf("Unexpected error details: %+v", providerErr)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "unknown-model"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for unknown model, got %v", err)
+	}
+}
+
+func TestConvertOpenRouterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"message": "Rate limit exceeded", "code": 429}}`))
+	}))
+	defer server.Close()
+
+	clientConfig := openrouter.DefaultConfig("token")
+	clientConfig.BaseURL = server.URL
+	clientConfig.HTTPClient = &capturingDoer{next: clientConfig.HTTPClient}
+	client := openrouter.NewClientWithConfig(*clientConfig)
+
+	ctx, capture := withResponseCapture(context.Background())
+	_, err := client.ListModels(ctx)
+	err = convertOpenRouterError(err, capture)
+
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected *ProviderError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, ErrRateLimit) || providerErr.Code != "429" || providerErr.RetryAfter != 5*time.Second || len(providerErr.Body) == 0 {
+		t.Errorf("Unexpected error details: %+v", providerErr)
+	}
+
+	var apiErr *openrouter.APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("Expected original *openrouter.APIError to stay available via errors.As")
+	}
+
+	if err := convertOpenRouterError(context.Canceled, nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled to be returned as is, got %v", err)
+	}
+}
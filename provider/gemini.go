@@ -0,0 +1,702 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/config"
+	"github.com/Murolando/m_ai_provider/internal/entities/gemini"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+// Константы для провайдера Gemini
+const (
+	geminiProviderName   = "Gemini"
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	// geminiFilesURIPrefix префикс URI файлов, загруженных через Gemini Files API.
+	geminiFilesURIPrefix = "https://generativelanguage.googleapis.com/"
+)
+
+var (
+	_ Provider       = (*GeminiProvider)(nil)
+	_ Namer          = (*GeminiProvider)(nil)
+	_ ModelRefresher = (*GeminiProvider)(nil)
+)
+
+// geminiPrices цены моделей по префиксу ID; более точные префиксы идут раньше.
+// Gemini не отдает цены через API, поэтому они взяты из https://ai.google.dev/pricing (промпты до 200k токенов).
+var geminiPrices = []modelPrice{
+	{"gemini-3-pro", tokenPrice{input: 2, output: 12}},
+	{"gemini-3-flash", tokenPrice{input: 0.5, output: 3}},
+	{"gemini-2.5-pro", tokenPrice{input: 1.25, output: 10}},
+	{"gemini-2.5-flash-lite", tokenPrice{input: 0.1, output: 0.4}},
+	{"gemini-2.5-flash", tokenPrice{input: 0.3, output: 2.5}},
+	{"gemini-2.0-flash-lite", tokenPrice{input: 0.075, output: 0.3}},
+	{"gemini-2.0-flash", tokenPrice{input: 0.1, output: 0.4}},
+}
+
+// GeminiProvider представляет провайдера для работы с Google Gemini API (generateContent).
+type GeminiProvider struct {
+	baseURL     string               // Базовый URL для API запросов
+	apiKey      string               // API ключ для аутентификации
+	models      *modelCache          // Кэш информации о моделях
+	toolsMapper *mappers.ToolsMapper // Маппер для конвертации инструментов
+	httpClient  *http.Client         // HTTP клиент для запросов к API
+	rate        *exchangeRate        // Курс доллара к рублю для расчета цен
+}
+
+// NewGeminiProvider создает новый экземпляр Gemini провайдера.
+// apiKey - API ключ Google AI Studio
+// opts - настройки HTTP клиента (прокси, таймауты, заголовки, базовый URL)
+// Возвращает настроенный провайдер или ошибку при неудачной инициализации.
+func NewGeminiProvider(apiKey string, opts ...ClientOption) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+
+	httpConfig, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	rate, err := newExchangeRate(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := geminiDefaultBaseURL
+	if httpConfig.baseURL != "" {
+		baseURL = strings.TrimSuffix(httpConfig.baseURL, "/")
+	}
+
+	provider := &GeminiProvider{
+		baseURL:     baseURL,
+		apiKey:      apiKey,
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		httpClient:  httpClient,
+		rate:        rate,
+	}
+
+	if err := provider.getModels(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", err)
+	}
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *GeminiProvider) Name() string {
+	return geminiProviderName
+}
+
+// SendMessage отправляет сообщения в модель через метод generateContent.
+func (p *GeminiProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, modelID, "generateContent", nil, request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(geminiProviderName, err))
+	}
+
+	var contentResponse gemini.GenerateContentResponse
+	if err := json.Unmarshal(responseBody, &contentResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(geminiProviderName, responseBody, err))
+	}
+
+	if err := geminiBlockedError(&contentResponse, responseBody); err != nil {
+		return nil, err
+	}
+	if len(contentResponse.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates in response: %w", newDecodeError(geminiProviderName, responseBody, nil))
+	}
+
+	// Собираем ответ тем же аккумулятором, что и поток: вызовы функций приходят целиком
+	accumulator := newStreamAccumulator()
+	candidate := contentResponse.Candidates[0]
+	toolCalls := 0
+	for _, part := range candidate.Content.Parts {
+		text, delta, err := geminiPartDelta(part, &toolCalls)
+		if err != nil {
+			return nil, err
+		}
+		accumulator.addText(text)
+		if delta != nil {
+			accumulator.addToolCallDelta(*delta)
+		}
+	}
+	accumulator.setFinishReason(mapGeminiFinishReason(candidate.FinishReason, toolCalls > 0))
+
+	result, err := accumulator.response(p.toolsMapper)
+	if err != nil {
+		return nil, err
+	}
+	p.applyUsage(result, modelID, contentResponse.UsageMetadata)
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в модель через streamGenerateContent и возвращает ответ потоком (SSE).
+func (p *GeminiProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, modelID, "streamGenerateContent", url.Values{"alt": {"sse"}}, request)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage *gemini.UsageMetadata
+		var finishReason string
+		toolCalls := 0
+
+		err := utils.ReadSSEData(response.Body, func(data []byte) error {
+			// Ошибка может прийти посреди потока отдельным событием {"error": ...}
+			if err := streamPayloadError(geminiProviderName, data); err != nil {
+				return err
+			}
+
+			var chunk gemini.GenerateContentResponse
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return newDecodeError(geminiProviderName, data, err)
+			}
+			if err := geminiBlockedError(&chunk, data); err != nil {
+				return err
+			}
+			// usageMetadata в каждом chunk накопительный
+			if chunk.UsageMetadata != nil {
+				usage = chunk.UsageMetadata
+			}
+			if len(chunk.Candidates) == 0 {
+				return nil
+			}
+
+			candidate := chunk.Candidates[0]
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+			for _, part := range candidate.Content.Parts {
+				text, delta, err := geminiPartDelta(part, &toolCalls)
+				if err != nil {
+					return err
+				}
+				if text != "" {
+					accumulator.addText(text)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: text}) {
+						return ctx.Err()
+					}
+				}
+				if delta != nil {
+					accumulator.addToolCallDelta(*delta)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: delta}) {
+						return ctx.Err()
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(geminiProviderName, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		accumulator.setFinishReason(mapGeminiFinishReason(finishReason, toolCalls > 0))
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		p.applyUsage(result, modelID, usage)
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo получает информацию о конкретной модели из кэша.
+func (p *GeminiProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(geminiProviderName, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *GeminiProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список моделей и цены.
+func (p *GeminiProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
+}
+
+// buildRequest конвертирует сообщения и опции в запрос generateContent.
+// Возвращает ID модели в Gemini и тело запроса.
+func (p *GeminiProvider) buildRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (string, *gemini.GenerateContentRequest, error) {
+	modelID, exists := config.GeminiNamesMap[modelName]
+	if !exists {
+		return "", nil, newModelNotSupportedError(geminiProviderName, string(modelName))
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Модели без информации в кэше проверяет сам API
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		if err := checkImageInput(geminiProviderName, prepared, modelName, modelInfo); err != nil {
+			return "", nil, err
+		}
+	}
+
+	systemInstruction, contents, err := p.convertToContents(prepared)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &gemini.GenerateContentRequest{
+		Contents:          contents,
+		SystemInstruction: systemInstruction,
+	}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		declarations := make([]gemini.FunctionDeclaration, len(mcpTools))
+		for i, mcpTool := range mcpTools {
+			openaiTool, err := p.toolsMapper.MCPToolToOpenAI(mcpTool)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to convert MCP tools to Gemini: %w", err)
+			}
+			parameters, err := geminiSchema(openaiTool.Function.Parameters)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to convert schema of tool %s: %w", mcpTool.Name, err)
+			}
+			declarations[i] = gemini.FunctionDeclaration{
+				Name:        mcpTool.Name,
+				Description: mcpTool.Description,
+				Parameters:  parameters,
+			}
+		}
+		request.Tools = []gemini.Tool{{FunctionDeclarations: declarations}}
+		request.ToolConfig = &gemini.ToolConfig{FunctionCallingConfig: &gemini.FunctionCallingConfig{Mode: gemini.FunctionCallingModeAuto}}
+	}
+
+	generationConfig, err := p.generationConfig(opts, len(request.Tools) > 0)
+	if err != nil {
+		return "", nil, err
+	}
+	request.GenerationConfig = generationConfig
+
+	return modelID, request, nil
+}
+
+// generationConfig переносит параметры генерации и формат ответа в generationConfig.
+// Gemini не поддерживает logit_bias и не совмещает JSON ответ с вызовом функций.
+func (p *GeminiProvider) generationConfig(opts []options.SendMessageOption, hasTools bool) (*gemini.GenerationConfig, error) {
+	params := options.ExtractGenerationParams(opts)
+	if len(params.LogitBias) > 0 {
+		return nil, &UnsupportedParameterError{Provider: geminiProviderName, Parameter: options.OptionTypeLogitBias, Reason: "not supported by Gemini API"}
+	}
+
+	generationConfig := &gemini.GenerationConfig{
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		MaxOutputTokens:  params.MaxTokens,
+		StopSequences:    params.Stop,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+	}
+
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		if hasTools {
+			return nil, &UnsupportedParameterError{Provider: geminiProviderName, Parameter: options.OptionTypeResponseFormat, Reason: "cannot be combined with MCP tools"}
+		}
+		generationConfig.ResponseMimeType = gemini.MIMETypeJSON
+		if responseFormat.Type == options.ResponseFormatJSONSchema && responseFormat.Schema != nil {
+			responseSchema, err := geminiSchema(responseFormat.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert response schema: %w", err)
+			}
+			generationConfig.ResponseSchema = responseSchema
+		}
+	}
+
+	return generationConfig, nil
+}
+
+// convertToContents конвертирует внутренние сообщения в формат Gemini.
+// Системные сообщения и инструкции разработчика собираются в systemInstruction, результаты инструментов
+// передаются частями functionResponse от роли user, соседние сообщения одной роли объединяются.
+func (p *GeminiProvider) convertToContents(messages []*entities.Message) (*gemini.Content, []gemini.Content, error) {
+	var systemParts []gemini.Part
+	contents := make([]gemini.Content, 0, len(messages))
+	// Gemini сопоставляет результат с вызовом по имени функции, поэтому запоминаем имена по ID вызова
+	toolNames := make(map[string]string)
+
+	appendParts := func(role string, parts []gemini.Part) {
+		if last := len(contents) - 1; last >= 0 && contents[last].Role == role {
+			contents[last].Parts = append(contents[last].Parts, parts...)
+			return
+		}
+		contents = append(contents, gemini.Content{Role: role, Parts: parts})
+	}
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			if msg.MessageText != "" {
+				systemParts = append(systemParts, gemini.Part{Text: msg.MessageText})
+			}
+		case entities.AuthorTypeUser, "":
+			parts := make([]gemini.Part, 0, len(msg.Images)+1)
+			if msg.MessageText != "" {
+				parts = append(parts, gemini.Part{Text: msg.MessageText})
+			}
+			for j, image := range msg.Images {
+				part, err := geminiImagePart(image)
+				if err != nil {
+					return nil, nil, fmt.Errorf("message at index %d: invalid image %d: %w", i, j, err)
+				}
+				parts = append(parts, part)
+			}
+			if len(parts) == 0 {
+				return nil, nil, fmt.Errorf("user message at index %d is empty", i)
+			}
+			appendParts(gemini.RoleUser, parts)
+		case entities.AuthorTypeRobot:
+			var parts []gemini.Part
+			if msg.MessageText != "" {
+				parts = append(parts, gemini.Part{Text: msg.MessageText})
+			}
+			if len(msg.ToolCalls) > 0 && len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+				return nil, nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+			}
+			for j, mcpCall := range msg.ToolCalls {
+				openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to convert MCP tool call %d to Gemini: %w", j, err)
+				}
+				var args map[string]interface{}
+				if err := json.Unmarshal([]byte(openaiToolCall.Function.Arguments), &args); err != nil {
+					return nil, nil, fmt.Errorf("failed to convert MCP tool call %d to Gemini: %w", j, err)
+				}
+				toolNames[msg.ToolCallIDs[j]] = openaiToolCall.Function.Name
+				parts = append(parts, gemini.Part{FunctionCall: &gemini.FunctionCall{Name: openaiToolCall.Function.Name, Args: args}})
+			}
+			if len(parts) > 0 {
+				appendParts(gemini.RoleModel, parts)
+			}
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			name, exists := toolNames[msg.ToolCallIDs[0]]
+			if !exists {
+				return nil, nil, fmt.Errorf("tool message at index %d references unknown tool call %q", i, msg.ToolCallIDs[0])
+			}
+			appendParts(gemini.RoleUser, []gemini.Part{{FunctionResponse: &gemini.FunctionResponse{
+				Name:     name,
+				Response: geminiFunctionResponse(msg.MessageText),
+			}}})
+		default:
+			return nil, nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	var systemInstruction *gemini.Content
+	if len(systemParts) > 0 {
+		systemInstruction = &gemini.Content{Parts: systemParts}
+	}
+	return systemInstruction, contents, nil
+}
+
+// post отправляет запрос к методу модели и возвращает ответ со статусом 200.
+func (p *GeminiProvider) post(ctx context.Context, modelID string, method string, query url.Values, request *gemini.GenerateContentRequest) (*http.Response, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	requestURL := p.baseURL + "/models/" + url.PathEscape(modelID) + ":" + method
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(geminiProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		responseBody, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("API request failed: %w", newGeminiHTTPError(response, responseBody))
+	}
+
+	return response, nil
+}
+
+// getModels получает модели из Gemini API постранично и заполняет кэш моделей.
+func (p *GeminiProvider) getModels(ctx context.Context) error {
+	usdToRubRate := p.rate.refresh(ctx)
+
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo)
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		page, err := p.getModelsPage(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, model := range page.Models {
+			geminiModelID := strings.TrimPrefix(model.Name, "models/")
+			for ourModelName, mappedModelID := range config.GeminiNamesMap {
+				if geminiModelID != mappedModelID {
+					continue
+				}
+				price, _ := findTokenPrice(geminiPrices, geminiModelID)
+				modelsInfo[ourModelName] = &entities.ModelInfo{
+					Name:            model.DisplayName,
+					Alias:           ourModelName,
+					PriceInRubles:   price.priceInRubles(usdToRubRate),
+					InputModalities: []string{entities.ModalityText, entities.ModalityImage},
+				}
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	p.models.replace(modelsInfo)
+
+	return nil
+}
+
+// getModelsPage загружает одну страницу списка моделей.
+func (p *GeminiProvider) getModelsPage(ctx context.Context, query url.Values) (*gemini.ModelsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", p.apiKey)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(geminiProviderName, err))
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(geminiProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed: %w", newGeminiHTTPError(response, body))
+	}
+
+	var page gemini.ModelsResponse
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", newDecodeError(geminiProviderName, body, err))
+	}
+	return &page, nil
+}
+
+// applyUsage заполняет токены и стоимость из usageMetadata.
+// Токены рассуждений оплачиваются как токены ответа, кэшированные входные токены - по четверти цены.
+func (p *GeminiProvider) applyUsage(result *entities.ProviderMessageResponseDTO, modelID string, usage *gemini.UsageMetadata) {
+	if usage == nil {
+		return
+	}
+	result.TotalTokens = usage.TotalTokenCount
+	if result.TotalTokens == 0 {
+		result.TotalTokens = usage.PromptTokenCount + usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	}
+
+	price, exists := findTokenPrice(geminiPrices, modelID)
+	if !exists {
+		return
+	}
+	costUSD := (float64(usage.PromptTokenCount-usage.CachedContentTokenCount)*price.input +
+		float64(usage.CachedContentTokenCount)*price.input*0.25 +
+		float64(usage.CandidatesTokenCount+usage.ThoughtsTokenCount)*price.output) / 1_000_000
+	result.PriceInRubles = decimal.NewFromFloat(costUSD * p.rate.value()).Round(3)
+}
+
+// newGeminiHTTPError создает ошибку по неуспешному ответу Gemini.
+// Gemini отвечает 429 RESOURCE_EXHAUSTED с текстом про квоту и при превышении лимита запросов в минуту,
+// поэтому такие ответы считаются ErrRateLimit, чтобы их можно было повторить.
+func newGeminiHTTPError(response *http.Response, body []byte) *ProviderError {
+	providerErr := newHTTPError(geminiProviderName, response.StatusCode, response.Header, body)
+	if providerErr.StatusCode == http.StatusTooManyRequests && providerErr.Kind == ErrQuotaExceeded {
+		providerErr.Kind = ErrRateLimit
+	}
+	return providerErr
+}
+
+// geminiBlockedError возвращает ErrContentFilter, если запрос заблокирован до генерации.
+func geminiBlockedError(response *gemini.GenerateContentResponse, body []byte) error {
+	if response.PromptFeedback == nil || response.PromptFeedback.BlockReason == "" {
+		return nil
+	}
+	return &ProviderError{
+		Kind:     ErrContentFilter,
+		Provider: geminiProviderName,
+		Code:     response.PromptFeedback.BlockReason,
+		Message:  "prompt blocked",
+		Body:     body,
+	}
+}
+
+// geminiPartDelta разбирает часть ответа: текст или вызов функции целиком в виде дельты.
+// Рассуждения модели (thought) в ответ не попадают. toolCalls - счетчик вызовов для индекса и ID.
+func geminiPartDelta(part gemini.Part, toolCalls *int) (string, *entities.ToolCallDelta, error) {
+	if part.Thought {
+		return "", nil, nil
+	}
+	if part.FunctionCall == nil {
+		return part.Text, nil, nil
+	}
+
+	args := part.FunctionCall.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal function call arguments: %w", err)
+	}
+
+	// Не все модели Gemini возвращают ID вызова, а он нужен для сопоставления с результатом
+	id := part.FunctionCall.ID
+	if id == "" {
+		id = newToolCallID()
+	}
+	delta := &entities.ToolCallDelta{
+		Index:          *toolCalls,
+		ID:             id,
+		Name:           part.FunctionCall.Name,
+		ArgumentsDelta: string(argsJSON),
+	}
+	*toolCalls++
+	return part.Text, delta, nil
+}
+
+// geminiImagePart конвертирует изображение в часть Gemini: байты и data URI передаются inlineData.
+// По ссылке Gemini читает только файлы, загруженные через Files API, и только с указанным MIME типом,
+// поэтому остальные http(s) ссылки отклоняются с ErrUnsupportedModality. MIME тип байтов без MIMEType
+// определяется по содержимому.
+func geminiImagePart(image entities.ImageContent) (gemini.Part, error) {
+	if len(image.Data) > 0 && image.MIMEType == "" {
+		image.MIMEType = http.DetectContentType(image.Data)
+	}
+	imageURL, err := image.DataURL()
+	if err != nil {
+		return gemini.Part{}, err
+	}
+	if len(image.Data) > 0 {
+		return gemini.Part{InlineData: &gemini.Blob{MimeType: image.MIMEType, Data: base64.StdEncoding.EncodeToString(image.Data)}}, nil
+	}
+	if strings.HasPrefix(imageURL, "data:") {
+		mediaType, data, err := splitDataURI(imageURL)
+		if err != nil {
+			return gemini.Part{}, err
+		}
+		return gemini.Part{InlineData: &gemini.Blob{MimeType: mediaType, Data: data}}, nil
+	}
+	if !strings.HasPrefix(imageURL, geminiFilesURIPrefix) {
+		return gemini.Part{}, fmt.Errorf("%w: Gemini does not fetch image urls, pass image data or a data URI instead of %s", ErrUnsupportedModality, imageURL)
+	}
+	if !strings.HasPrefix(image.MIMEType, "image/") {
+		return gemini.Part{}, fmt.Errorf("image mime type is required for Gemini file %s", imageURL)
+	}
+	return gemini.Part{FileData: &gemini.FileData{MimeType: image.MIMEType, FileURI: imageURL}}, nil
+}
+
+// geminiFunctionResponse превращает результат инструмента в JSON объект: Gemini принимает только объекты.
+func geminiFunctionResponse(content string) map[string]interface{} {
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &response); err == nil && response != nil {
+		return response
+	}
+	return map[string]interface{}{"result": content}
+}
+
+// mapGeminiFinishReason маппит finishReason Gemini в общие константы entities.
+// Gemini завершает ответ с вызовом функций причиной STOP, поэтому наличие вызовов проверяется отдельно.
+func mapGeminiFinishReason(finishReason string, hasToolCalls bool) *string {
+	if finishReason == "" {
+		return nil
+	}
+
+	var mappedReason string
+	switch finishReason {
+	case gemini.FinishReasonStop:
+		mappedReason = entities.FinishReasonStop
+		if hasToolCalls {
+			mappedReason = entities.FinishReasonToolCalls
+		}
+	case gemini.FinishReasonMaxTokens:
+		mappedReason = entities.FinishReasonLength
+	case gemini.FinishReasonSafety, gemini.FinishReasonRecitation, gemini.FinishReasonBlocklist,
+		gemini.FinishReasonProhibitedContent, gemini.FinishReasonSPII:
+		mappedReason = entities.FinishReasonContentFilter
+	default:
+		// Если неизвестная причина, возвращаем как есть
+		mappedReason = strings.ToLower(finishReason)
+	}
+
+	return &mappedReason
+}
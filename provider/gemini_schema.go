@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// geminiMaxSchemaDepth ограничивает раскрытие $ref, чтобы рекурсивные схемы не зацикливались.
+const geminiMaxSchemaDepth = 16
+
+// geminiSchemaFields поля JSON Schema, которые входят в подмножество OpenAPI 3.0, принимаемое Gemini.
+// Остальные поля (additionalProperties, $schema, examples и т.д.) Gemini отклоняет, поэтому они удаляются.
+var geminiSchemaFields = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"items":            true,
+	"minItems":         true,
+	"maxItems":         true,
+	"properties":       true,
+	"required":         true,
+	"minProperties":    true,
+	"maxProperties":    true,
+	"minLength":        true,
+	"maxLength":        true,
+	"pattern":          true,
+	"minimum":          true,
+	"maximum":          true,
+	"anyOf":            true,
+	"propertyOrdering": true,
+	"default":          true,
+}
+
+// geminiSchemaFormats форматы, которые Gemini поддерживает для каждого типа.
+var geminiSchemaFormats = map[string]map[string]bool{
+	"string":  {"enum": true, "date-time": true},
+	"integer": {"int32": true, "int64": true},
+	"number":  {"float": true, "double": true},
+}
+
+// geminiSchema приводит JSON Schema (MCP inputSchema или схему ответа) к подмножеству OpenAPI 3.0 для Gemini:
+// раскрывает $ref, заменяет const и oneOf, переводит тип null в nullable и удаляет неподдерживаемые поля.
+// Схема предварительно проходит через JSON, чтобы типизированные значения ([]string и т.д.) стали обычными.
+func geminiSchema(schema interface{}) (interface{}, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return nil, err
+	}
+
+	root, _ := schema.(map[string]interface{})
+	definitions := make(map[string]interface{})
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := root[key].(map[string]interface{}); ok {
+			for name, definition := range defs {
+				definitions["#/"+key+"/"+name] = definition
+			}
+		}
+	}
+	return downgradeGeminiSchema(schema, definitions, 0), nil
+}
+
+// downgradeGeminiSchema рекурсивно конвертирует одну схему.
+func downgradeGeminiSchema(schema interface{}, definitions map[string]interface{}, depth int) map[string]interface{} {
+	source, ok := schema.(map[string]interface{})
+	if !ok {
+		// Схема true или неизвестный формат: любое значение
+		return map[string]interface{}{}
+	}
+	if depth > geminiMaxSchemaDepth {
+		// Рекурсивная схема: дальше описываем узел как произвольный объект
+		return map[string]interface{}{"type": "object"}
+	}
+
+	if ref, ok := source["$ref"].(string); ok {
+		resolved := downgradeGeminiSchema(definitions[ref], definitions, depth+1)
+		if description, ok := source["description"].(string); ok {
+			resolved["description"] = description
+		}
+		return resolved
+	}
+
+	result := make(map[string]interface{})
+
+	// allOf объединяем в одну схему
+	if allOf, ok := source["allOf"].([]interface{}); ok {
+		for _, item := range allOf {
+			mergeGeminiSchema(result, downgradeGeminiSchema(item, definitions, depth+1))
+		}
+	}
+
+	for key, value := range source {
+		switch key {
+		case "properties":
+			if properties, ok := value.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(properties))
+				for name, property := range properties {
+					converted[name] = downgradeGeminiSchema(property, definitions, depth+1)
+				}
+				mergeGeminiSchema(result, map[string]interface{}{"properties": converted})
+			}
+		case "required":
+			if required, ok := value.([]interface{}); ok {
+				mergeGeminiSchema(result, map[string]interface{}{"required": required})
+			}
+		case "items":
+			// Кортежи (items как массив) Gemini не поддерживает, берем схему первого элемента
+			if tuple, ok := value.([]interface{}); ok {
+				if len(tuple) > 0 {
+					result["items"] = downgradeGeminiSchema(tuple[0], definitions, depth+1)
+				}
+				continue
+			}
+			result["items"] = downgradeGeminiSchema(value, definitions, depth+1)
+		case "anyOf", "oneOf":
+			if variants, ok := value.([]interface{}); ok {
+				setGeminiVariants(result, variants, definitions, depth)
+			}
+		case "type":
+			setGeminiType(result, value)
+		case "const":
+			if constant, ok := value.(string); ok {
+				result["enum"] = []interface{}{constant}
+			}
+		case "enum":
+			// Gemini принимает только строковые enum
+			if values, ok := value.([]interface{}); ok && allStrings(values) {
+				result["enum"] = values
+			}
+		default:
+			if geminiSchemaFields[key] {
+				result[key] = value
+			}
+		}
+	}
+
+	if _, hasEnum := result["enum"]; hasEnum {
+		if _, hasType := result["type"]; !hasType {
+			result["type"] = "string"
+		}
+	}
+	if format, ok := result["format"].(string); ok {
+		schemaType, _ := result["type"].(string)
+		if !geminiSchemaFormats[schemaType][format] {
+			delete(result, "format")
+		}
+	}
+	if required, ok := result["required"].([]interface{}); ok {
+		if required = existingProperties(required, result["properties"]); len(required) > 0 {
+			result["required"] = required
+		} else {
+			delete(result, "required")
+		}
+	}
+
+	return result
+}
+
+// setGeminiType переносит type; массив типов с null превращается в nullable.
+func setGeminiType(result map[string]interface{}, value interface{}) {
+	switch schemaType := value.(type) {
+	case string:
+		if schemaType == "null" {
+			result["nullable"] = true
+			return
+		}
+		result["type"] = schemaType
+	case []interface{}:
+		var types []interface{}
+		for _, item := range schemaType {
+			if item == "null" {
+				result["nullable"] = true
+				continue
+			}
+			types = append(types, item)
+		}
+		switch len(types) {
+		case 0:
+		case 1:
+			result["type"] = types[0]
+		default:
+			variants := make([]interface{}, len(types))
+			for i, item := range types {
+				variants[i] = map[string]interface{}{"type": item}
+			}
+			result["anyOf"] = variants
+		}
+	}
+}
+
+// setGeminiVariants переносит anyOf и oneOf в anyOf; вариант null превращается в nullable.
+func setGeminiVariants(result map[string]interface{}, variants []interface{}, definitions map[string]interface{}, depth int) {
+	converted := make([]interface{}, 0, len(variants))
+	for _, variant := range variants {
+		if variantMap, ok := variant.(map[string]interface{}); ok && variantMap["type"] == "null" {
+			result["nullable"] = true
+			continue
+		}
+		converted = append(converted, downgradeGeminiSchema(variant, definitions, depth+1))
+	}
+
+	if len(converted) == 1 {
+		mergeGeminiSchema(result, converted[0].(map[string]interface{}))
+		return
+	}
+	if len(converted) > 1 {
+		result["anyOf"] = converted
+	}
+}
+
+// mergeGeminiSchema добавляет поля схемы source в target, объединяя properties и required.
+func mergeGeminiSchema(target map[string]interface{}, source map[string]interface{}) {
+	for key, value := range source {
+		switch key {
+		case "properties":
+			properties, _ := target["properties"].(map[string]interface{})
+			if properties == nil {
+				properties = make(map[string]interface{})
+			}
+			for name, property := range value.(map[string]interface{}) {
+				properties[name] = property
+			}
+			target["properties"] = properties
+		case "required":
+			existing, _ := target["required"].([]interface{})
+			target["required"] = append(existing, value.([]interface{})...)
+		default:
+			target[key] = value
+		}
+	}
+}
+
+// existingProperties оставляет в required только объявленные свойства, иначе Gemini отклоняет схему.
+func existingProperties(required []interface{}, properties interface{}) []interface{} {
+	propertiesMap, _ := properties.(map[string]interface{})
+	result := make([]interface{}, 0, len(required))
+	seen := make(map[string]bool)
+	for _, item := range required {
+		name, ok := item.(string)
+		if !ok || seen[name] {
+			continue
+		}
+		if _, exists := propertiesMap[name]; exists {
+			result = append(result, name)
+			seen[name] = true
+		}
+	}
+	return result
+}
+
+// allStrings проверяет, что все значения являются непустыми строками.
+func allStrings(values []interface{}) bool {
+	for _, value := range values {
+		if text, ok := value.(string); !ok || strings.TrimSpace(text) == "" {
+			return false
+		}
+	}
+	return true
+}
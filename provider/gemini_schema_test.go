@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGeminiSchemaDowngrade(t *testing.T) {
+	source := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": ["string", "null"], "format": "email", "examples": ["a@b.c"]},
+			"kind": {"const": "user"},
+			"address": {"$ref": "#/$defs/Address", "description": "Адрес"},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+			"value": {"oneOf": [{"type": "integer", "format": "int64"}, {"type": "null"}]}
+		},
+		"required": ["name", "missing"],
+		"$defs": {
+			"Address": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}
+		}
+	}`
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(source), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := geminiSchema(schema)
+	if err != nil {
+		t.Fatalf("geminiSchema() failed with error: %v", err)
+	}
+
+	expected := `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "nullable": true},
+			"kind": {"type": "string", "enum": ["user"]},
+			"address": {"type": "object", "description": "Адрес", "properties": {"city": {"type": "string"}}, "required": ["city"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"value": {"type": "integer", "format": "int64", "nullable": true}
+		},
+		"required": ["name"]
+	}`
+	var expectedSchema interface{}
+	json.Unmarshal([]byte(expected), &expectedSchema)
+
+	// Сравниваем через JSON, чтобы не зависеть от конкретных типов срезов
+	convertedJSON, _ := json.Marshal(converted)
+	var actual interface{}
+	json.Unmarshal(convertedJSON, &actual)
+	if !reflect.DeepEqual(actual, expectedSchema) {
+		t.Errorf("Unexpected schema:\n%s", convertedJSON)
+	}
+}
+
+func TestGeminiSchemaRecursiveRef(t *testing.T) {
+	schema := map[string]interface{}{
+		"$ref": "#/definitions/Node",
+		"definitions": map[string]interface{}{
+			"Node": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"child": map[string]interface{}{"$ref": "#/definitions/Node"}},
+			},
+		},
+	}
+
+	converted, err := geminiSchema(schema)
+	if err != nil {
+		t.Fatalf("geminiSchema() failed with error: %v", err)
+	}
+	if converted.(map[string]interface{})["type"] != "object" {
+		t.Errorf("Expected resolved root object, got %v", converted)
+	}
+}
@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/gemini"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// newFakeGeminiServer создает сервер Gemini API, который отвечает на generateContent и streamGenerateContent через handler.
+func newFakeGeminiServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, request gemini.GenerateContentRequest)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": {"code": 403, "message": "API key not valid", "status": "PERMISSION_DENIED"}}`))
+			return
+		}
+		if r.URL.Path == "/v1beta/models" {
+			w.Write([]byte(`{"models": [{"name": "models/gemini-2.5-flash", "displayName": "Gemini 2.5 Flash"}]}`))
+			return
+		}
+
+		var request gemini.GenerateContentRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		handler(w, r, request)
+	}))
+}
+
+func newTestGeminiProvider(t *testing.T, server *httptest.Server) *GeminiProvider {
+	// Курс валют не запрашиваем у ЦБ в тестах
+	p, err := NewGeminiProvider("test-key", WithBaseURL(server.URL+"/v1beta"), WithUSDToRUBRate(FixedUSDToRUBRate(80)))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return p
+}
+
+func TestGeminiSendMessageWithFunctionCalls(t *testing.T) {
+	var received gemini.GenerateContentRequest
+	var path string
+	server := newFakeGeminiServer(t, func(w http.ResponseWriter, r *http.Request, request gemini.GenerateContentRequest) {
+		received = request
+		path = r.URL.Path
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [
+					{"text": "думаю", "thought": true},
+					{"text": "Проверю."},
+					{"functionCall": {"name": "get_weather", "args": {"city": "Казань"}}}
+				]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 1000, "candidatesTokenCount": 100, "thoughtsTokenCount": 100, "totalTokenCount": 1200}
+		}`))
+	})
+	defer server.Close()
+
+	p := newTestGeminiProvider(t, server)
+
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Погода в Москве?", AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{URL: "data:image/png;base64,cG5n"}}},
+		{
+			AuthorType:  entities.AuthorTypeRobot,
+			ToolCalls:   []mcpgo.CallToolRequest{{Params: mcpgo.CallToolParams{Name: "get_weather", Arguments: map[string]interface{}{"city": "Москва"}}}},
+			ToolCallIDs: []string{"call_1"},
+		},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+	}
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithString("city", mcpgo.Required(), mcpgo.Enum("Москва", "Казань")))
+
+	response, err := p.SendMessage(context.Background(), messages, "gemini-2-5-flash", options.WithMCPTools([]mcpgo.Tool{tool}), options.WithSeed(7))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if path != "/v1beta/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("Unexpected request path %s", path)
+	}
+	if received.SystemInstruction == nil || received.SystemInstruction.Parts[0].Text != "Ты синоптик" {
+		t.Errorf("Expected system instruction, got %+v", received.SystemInstruction)
+	}
+	if received.GenerationConfig == nil || received.GenerationConfig.Seed == nil || *received.GenerationConfig.Seed != 7 {
+		t.Errorf("Expected seed in generation config, got %+v", received.GenerationConfig)
+	}
+	if len(received.Contents) != 3 {
+		t.Fatalf("Expected user, model and function response contents, got %+v", received.Contents)
+	}
+	if image := received.Contents[0].Parts[1].InlineData; image == nil || image.MimeType != "image/png" || image.Data != "cG5n" {
+		t.Errorf("Expected inline image, got %+v", received.Contents[0].Parts[1])
+	}
+	if call := received.Contents[1].Parts[0].FunctionCall; received.Contents[1].Role != gemini.RoleModel || call == nil || call.Args["city"] != "Москва" {
+		t.Errorf("Expected model function call, got %+v", received.Contents[1])
+	}
+	if result := received.Contents[2].Parts[0].FunctionResponse; received.Contents[2].Role != gemini.RoleUser || result == nil || result.Name != "get_weather" || result.Response["result"] != "+5" {
+		t.Errorf("Expected function response with tool name, got %+v", received.Contents[2])
+	}
+	parameters, _ := received.Tools[0].FunctionDeclarations[0].Parameters.(map[string]interface{})
+	if _, hasAdditional := parameters["additionalProperties"]; hasAdditional || parameters["type"] != "object" {
+		t.Errorf("Expected downgraded parameters schema, got %v", parameters)
+	}
+
+	if response.MessageText != "Проверю." || len(response.ToolCalls) != 1 || response.ToolCallIDs[0] == "" {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+	// (1000 * 0.3 + 200 * 2.5) / 1e6 USD * 80 RUB
+	if response.TotalTokens != 1200 || response.PriceInRubles.String() != "0.064" {
+		t.Errorf("Unexpected usage: tokens %d, price %s", response.TotalTokens, response.PriceInRubles)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "gemini-2-5-flash", options.WithLogitBias(map[string]int{"1": 1})); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected ErrUnsupportedParameter for logit_bias, got %v", err)
+	}
+}
+
+func TestGeminiSendMessageStream(t *testing.T) {
+	server := newFakeGeminiServer(t, func(w http.ResponseWriter, r *http.Request, request gemini.GenerateContentRequest) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("Unexpected stream request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "При"}]}}], "usageMetadata": {"promptTokenCount": 10}}` + "\n\n"))
+		w.Write([]byte(`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "вет"}]}, "finishReason": "MAX_TOKENS"}], "usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "totalTokenCount": 15}}` + "\n\n"))
+	})
+	defer server.Close()
+
+	p := newTestGeminiProvider(t, server)
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "gemini-2-5-flash")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var text string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if text != "Привет" || final == nil || final.MessageText != "Привет" || final.TotalTokens != 15 {
+		t.Fatalf("Unexpected stream: text %q, final %+v", text, final)
+	}
+	if final.FinishReason == nil || *final.FinishReason != entities.FinishReasonLength {
+		t.Errorf("Expected finish reason length, got %v", final.FinishReason)
+	}
+}
+
+func TestGeminiErrors(t *testing.T) {
+	server := newFakeGeminiServer(t, func(w http.ResponseWriter, r *http.Request, request gemini.GenerateContentRequest) {
+		if request.GenerationConfig != nil && request.GenerationConfig.Temperature != nil {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"code": 429, "message": "Resource has been exhausted (e.g. check quota).", "status": "RESOURCE_EXHAUSTED"}}`))
+			return
+		}
+		w.Write([]byte(`{"promptFeedback": {"blockReason": "SAFETY"}}`))
+	})
+	defer server.Close()
+
+	p := newTestGeminiProvider(t, server)
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+
+	if _, err := p.SendMessage(context.Background(), messages, "gemini-2-5-flash"); !errors.Is(err, ErrContentFilter) {
+		t.Errorf("Expected ErrContentFilter for blocked prompt, got %v", err)
+	}
+	if _, err := p.SendMessage(context.Background(), messages, "gemini-2-5-flash", options.WithTemperature(1)); !errors.Is(err, ErrRateLimit) {
+		t.Errorf("Expected ErrRateLimit for RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if _, err := p.SendMessage(context.Background(), messages, "gpt-4o"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for unmapped model, got %v", err)
+	}
+}
+
+func TestGeminiImagePart(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	part, err := geminiImagePart(entities.ImageContent{Data: png})
+	if err != nil || part.InlineData == nil || part.InlineData.MimeType != "image/png" {
+		t.Errorf("Expected inline image with detected mime type, got %+v (%v)", part, err)
+	}
+
+	fileURI := "https://generativelanguage.googleapis.com/v1beta/files/abc"
+	part, err = geminiImagePart(entities.ImageContent{URL: fileURI, MIMEType: "image/jpeg"})
+	if err != nil || part.FileData == nil || part.FileData.FileURI != fileURI || part.FileData.MimeType != "image/jpeg" {
+		t.Errorf("Expected fileData for Files API uri, got %+v (%v)", part, err)
+	}
+	if _, err := geminiImagePart(entities.ImageContent{URL: fileURI}); err == nil {
+		t.Error("Expected error for Files API uri without mime type")
+	}
+
+	if _, err := geminiImagePart(entities.ImageContent{URL: "https://example.com/cat.png"}); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for web url, got %v", err)
+	}
+}
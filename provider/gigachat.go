@@ -0,0 +1,579 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/config"
+	"github.com/Murolando/m_ai_provider/internal/entities/gigachat"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+// Константы для провайдера GigaChat
+const (
+	gigaChatProviderName   = "GigaChat"
+	gigaChatDefaultBaseURL = "https://gigachat.devices.sberbank.ru/api/v1"
+	gigaChatDefaultAuthURL = "https://ngw.devices.sberbank.ru:9443/api/v2/oauth"
+)
+
+var (
+	_ Provider       = (*GigaChatProvider)(nil)
+	_ Namer          = (*GigaChatProvider)(nil)
+	_ ModelRefresher = (*GigaChatProvider)(nil)
+)
+
+// gigaChatPrices цены моделей в рублях по префиксу ID; более точные префиксы идут раньше.
+// GigaChat не отдает цены через API, поэтому они взяты из https://developers.sber.ru/docs/ru/gigachat/tariffs.
+var gigaChatPrices = []rublePrice{
+	{"GigaChat-2-Max", 1950},
+	{"GigaChat-2-Pro", 1500},
+	{"GigaChat-Max", 1950},
+	{"GigaChat-Pro", 1500},
+	{"GigaChat", 200},
+}
+
+// GigaChatConfig содержит параметры провайдера GigaChat.
+type GigaChatConfig struct {
+	AuthKey string         // Ключ авторизации (Base64 от Client ID и Client Secret) из личного кабинета
+	Scope   string         // Версия API: GIGACHAT_API_PERS (по умолчанию), GIGACHAT_API_B2B или GIGACHAT_API_CORP
+	AuthURL string         // Адрес OAuth сервера (по умолчанию https://ngw.devices.sberbank.ru:9443/api/v2/oauth)
+	BaseURL string         // Базовый URL API (по умолчанию https://gigachat.devices.sberbank.ru/api/v1)
+	Options []ClientOption // Настройки HTTP клиента (сертификаты НУЦ Минцифры подключаются через WithTransport)
+}
+
+// GigaChatProvider представляет провайдера для работы с GigaChat API.
+// Токен доступа получается по OAuth (client credentials) и кэшируется до истечения срока.
+type GigaChatProvider struct {
+	baseURL     string               // Базовый URL для API запросов
+	authURL     string               // Адрес OAuth сервера
+	authKey     string               // Ключ авторизации
+	scope       string               // Версия API
+	tokens      *tokenCache          // Кэш токена доступа
+	models      *modelCache          // Кэш информации о моделях
+	toolsMapper *mappers.ToolsMapper // Маппер для конвертации инструментов
+	httpClient  *http.Client         // HTTP клиент для запросов к API
+}
+
+// NewGigaChatProvider создает провайдера GigaChat и загружает список моделей.
+func NewGigaChatProvider(cfg GigaChatConfig) (*GigaChatProvider, error) {
+	if cfg.AuthKey == "" {
+		return nil, fmt.Errorf("GIGACHAT_AUTH_KEY is not set")
+	}
+
+	httpConfig, err := newClientConfig(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if httpConfig.baseURL != "" {
+		cfg.BaseURL = httpConfig.baseURL
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = gigaChatDefaultBaseURL
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = gigaChatDefaultAuthURL
+	}
+	if cfg.Scope == "" {
+		cfg.Scope = gigachat.ScopePersonal
+	}
+
+	provider := &GigaChatProvider{
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		authURL:     cfg.AuthURL,
+		authKey:     cfg.AuthKey,
+		scope:       cfg.Scope,
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		httpClient:  httpClient,
+	}
+	provider.tokens = newTokenCache(provider.requestToken)
+
+	if err := provider.getModels(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", err)
+	}
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *GigaChatProvider) Name() string {
+	return gigaChatProviderName
+}
+
+// SendMessage отправляет сообщения в модель через /chat/completions.
+func (p *GigaChatProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.do(ctx, "POST", "/chat/completions", request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(gigaChatProviderName, err))
+	}
+
+	var chatResponse gigachat.ChatResponse
+	if err := json.Unmarshal(responseBody, &chatResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(gigaChatProviderName, responseBody, err))
+	}
+	if len(chatResponse.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response: %w", newDecodeError(gigaChatProviderName, responseBody, nil))
+	}
+
+	choice := chatResponse.Choices[0]
+	accumulator := newStreamAccumulator()
+	accumulator.addText(choice.Message.Content)
+	if choice.Message.FunctionCall != nil {
+		delta, err := gigaChatToolCallDelta(choice.Message.FunctionCall)
+		if err != nil {
+			return nil, err
+		}
+		accumulator.addToolCallDelta(delta)
+	}
+	accumulator.setFinishReason(mapGigaChatFinishReason(choice.FinishReason))
+
+	result, err := accumulator.response(p.toolsMapper)
+	if err != nil {
+		return nil, err
+	}
+	applyGigaChatUsage(result, modelID, chatResponse.Usage)
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в модель и возвращает ответ потоком (SSE).
+func (p *GigaChatProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	response, err := p.do(ctx, "POST", "/chat/completions", request)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage *gigachat.Usage
+
+		err := utils.ReadSSEData(response.Body, func(data []byte) error {
+			if err := streamPayloadError(gigaChatProviderName, data); err != nil {
+				return err
+			}
+
+			var chunk gigachat.StreamResponse
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return newDecodeError(gigaChatProviderName, data, err)
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if len(chunk.Choices) == 0 {
+				return nil
+			}
+
+			choice := chunk.Choices[0]
+			accumulator.setFinishReason(mapGigaChatFinishReason(choice.FinishReason))
+			if choice.Delta.Content != "" {
+				accumulator.addText(choice.Delta.Content)
+				if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: choice.Delta.Content}) {
+					return ctx.Err()
+				}
+			}
+			// Вызов функции приходит в потоке целиком одним chunk
+			if choice.Delta.FunctionCall != nil {
+				delta, err := gigaChatToolCallDelta(choice.Delta.FunctionCall)
+				if err != nil {
+					return err
+				}
+				accumulator.addToolCallDelta(delta)
+				if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(gigaChatProviderName, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		applyGigaChatUsage(result, modelID, usage)
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo получает информацию о конкретной модели из кэша.
+func (p *GigaChatProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(gigaChatProviderName, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *GigaChatProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список моделей.
+func (p *GigaChatProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
+}
+
+// buildRequest конвертирует сообщения и опции в запрос /chat/completions.
+// Возвращает ID модели в GigaChat и тело запроса.
+func (p *GigaChatProvider) buildRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (string, *gigachat.ChatRequest, error) {
+	modelID, exists := config.GigaChatNamesMap[modelName]
+	if !exists {
+		return "", nil, newModelNotSupportedError(gigaChatProviderName, string(modelName))
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Изображения в GigaChat передаются только через предварительную загрузку файлов
+	if err := checkImageInput(gigaChatProviderName, prepared, modelName, p.models.get(modelName)); err != nil {
+		return "", nil, err
+	}
+
+	chatMessages, err := p.convertToMessages(prepared)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &gigachat.ChatRequest{
+		Model:    modelID,
+		Messages: chatMessages,
+	}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to convert MCP tools to GigaChat: %w", err)
+		}
+		request.Functions = make([]gigachat.Function, len(openaiTools))
+		for i, openaiTool := range openaiTools {
+			var description string
+			if openaiTool.Function.Description != nil {
+				description = *openaiTool.Function.Description
+			}
+			request.Functions[i] = gigachat.Function{
+				Name:        openaiTool.Function.Name,
+				Description: description,
+				Parameters:  openaiTool.Function.Parameters,
+			}
+		}
+		request.FunctionCall = gigachat.FunctionCallAuto
+	}
+
+	if _, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		return "", nil, &UnsupportedParameterError{Provider: gigaChatProviderName, Parameter: options.OptionTypeResponseFormat, Reason: "not supported by GigaChat API"}
+	}
+
+	params := options.ExtractGenerationParams(opts)
+	unsupported := []struct {
+		parameter string
+		set       bool
+	}{
+		{options.OptionTypeStop, len(params.Stop) > 0},
+		{options.OptionTypeSeed, params.Seed != nil},
+		{options.OptionTypePresencePenalty, params.PresencePenalty != nil},
+		{options.OptionTypeFrequencyPenalty, params.FrequencyPenalty != nil},
+		{options.OptionTypeLogitBias, len(params.LogitBias) > 0},
+	}
+	for _, param := range unsupported {
+		if param.set {
+			return "", nil, &UnsupportedParameterError{Provider: gigaChatProviderName, Parameter: param.parameter, Reason: "not supported by GigaChat API"}
+		}
+	}
+	request.Temperature = params.Temperature
+	request.TopP = params.TopP
+	request.MaxTokens = params.MaxTokens
+
+	return modelID, request, nil
+}
+
+// convertToMessages конвертирует внутренние сообщения в формат GigaChat.
+// Результат функции передается сообщением с ролью function и именем функции, которое берется по ID вызова.
+func (p *GigaChatProvider) convertToMessages(messages []*entities.Message) ([]gigachat.Message, error) {
+	chatMessages := make([]gigachat.Message, 0, len(messages))
+	toolNames := make(map[string]string)
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			chatMessages = append(chatMessages, gigachat.Message{Role: gigachat.RoleSystem, Content: msg.MessageText})
+		case entities.AuthorTypeUser, "":
+			chatMessages = append(chatMessages, gigachat.Message{Role: gigachat.RoleUser, Content: msg.MessageText})
+		case entities.AuthorTypeRobot:
+			chatMessage := gigachat.Message{Role: gigachat.RoleAssistant, Content: msg.MessageText}
+			if len(msg.ToolCalls) > 0 && len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+				return nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+			}
+			if len(msg.ToolCalls) > 1 {
+				return nil, fmt.Errorf("assistant message at index %d has %d tool calls, GigaChat supports one function call per message", i, len(msg.ToolCalls))
+			}
+			for j, mcpCall := range msg.ToolCalls {
+				openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+				if err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to GigaChat: %w", j, err)
+				}
+				var arguments map[string]interface{}
+				if err := json.Unmarshal([]byte(openaiToolCall.Function.Arguments), &arguments); err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to GigaChat: %w", j, err)
+				}
+				toolNames[msg.ToolCallIDs[j]] = openaiToolCall.Function.Name
+				chatMessage.FunctionCall = &gigachat.FunctionCall{Name: openaiToolCall.Function.Name, Arguments: arguments}
+			}
+			chatMessages = append(chatMessages, chatMessage)
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			name, exists := toolNames[msg.ToolCallIDs[0]]
+			if !exists {
+				return nil, fmt.Errorf("tool message at index %d references unknown tool call %q", i, msg.ToolCallIDs[0])
+			}
+			chatMessages = append(chatMessages, gigachat.Message{
+				Role:    gigachat.RoleFunction,
+				Name:    name,
+				Content: gigaChatFunctionResult(msg.MessageText),
+			})
+		default:
+			return nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	return chatMessages, nil
+}
+
+// do отправляет запрос к API с токеном доступа и возвращает ответ со статусом 200.
+// Если сервер отклонил токен до истечения срока, токен обновляется и запрос повторяется один раз.
+func (p *GigaChatProvider) do(ctx context.Context, method string, path string, request interface{}) (*http.Response, error) {
+	var requestBody []byte
+	if request != nil {
+		var err error
+		requestBody, err = json.Marshal(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := p.tokens.get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access token: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if request != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		response, err := p.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", newTransportError(gigaChatProviderName, err))
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			response.Body.Close()
+			p.tokens.invalidate(token)
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			defer response.Body.Close()
+			responseBody, _ := io.ReadAll(response.Body)
+			return nil, fmt.Errorf("API request failed: %w", newHTTPError(gigaChatProviderName, response.StatusCode, response.Header, responseBody))
+		}
+
+		return response, nil
+	}
+}
+
+// requestToken получает токен доступа у OAuth сервера по ключу авторизации.
+func (p *GigaChatProvider) requestToken(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"scope": {p.scope}}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.authURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Basic "+p.authKey)
+	req.Header.Set("RqUID", newRequestUID())
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, newTransportError(gigaChatProviderName, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", time.Time{}, newTransportError(gigaChatProviderName, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", time.Time{}, newHTTPError(gigaChatProviderName, response.StatusCode, response.Header, body)
+	}
+
+	var tokenResponse gigachat.TokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil || tokenResponse.AccessToken == "" {
+		return "", time.Time{}, newDecodeError(gigaChatProviderName, body, err)
+	}
+	return tokenResponse.AccessToken, time.UnixMilli(tokenResponse.ExpiresAt), nil
+}
+
+// getModels получает модели из GigaChat API и заполняет кэш моделей.
+func (p *GigaChatProvider) getModels(ctx context.Context) error {
+	response, err := p.do(ctx, "GET", "/models", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", newTransportError(gigaChatProviderName, err))
+	}
+
+	var modelsResponse gigachat.ModelsResponse
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return fmt.Errorf("failed to decode response: %w", newDecodeError(gigaChatProviderName, body, err))
+	}
+
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo)
+	for _, model := range modelsResponse.Data {
+		for ourModelName, mappedModelID := range config.GigaChatNamesMap {
+			if model.ID != mappedModelID {
+				continue
+			}
+			price, _ := findRublePrice(gigaChatPrices, model.ID)
+			modelsInfo[ourModelName] = &entities.ModelInfo{
+				Name:            model.ID,
+				Alias:           ourModelName,
+				PriceInRubles:   price.priceInRubles(),
+				InputModalities: []string{entities.ModalityText},
+			}
+		}
+	}
+	p.models.replace(modelsInfo)
+
+	return nil
+}
+
+// applyGigaChatUsage заполняет токены и стоимость в рублях из usage.
+func applyGigaChatUsage(result *entities.ProviderMessageResponseDTO, modelID string, usage *gigachat.Usage) {
+	if usage == nil {
+		return
+	}
+	result.TotalTokens = usage.TotalTokens
+	if price, exists := findRublePrice(gigaChatPrices, modelID); exists {
+		result.PriceInRubles = price.cost(usage.TotalTokens)
+	}
+}
+
+// gigaChatToolCallDelta конвертирует вызов функции GigaChat в дельту с новым ID.
+// GigaChat вызывает не больше одной функции за ответ, поэтому индекс всегда 0.
+func gigaChatToolCallDelta(functionCall *gigachat.FunctionCall) (entities.ToolCallDelta, error) {
+	arguments := functionCall.Arguments
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	argumentsJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return entities.ToolCallDelta{}, fmt.Errorf("failed to marshal function call arguments: %w", err)
+	}
+	return entities.ToolCallDelta{
+		ID:             newToolCallID(),
+		Name:           functionCall.Name,
+		ArgumentsDelta: string(argumentsJSON),
+	}, nil
+}
+
+// gigaChatFunctionResult возвращает результат функции в виде JSON объекта: GigaChat не принимает произвольный текст.
+func gigaChatFunctionResult(content string) string {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &object); err == nil && object != nil {
+		return content
+	}
+	result, _ := json.Marshal(map[string]string{"result": content})
+	return string(result)
+}
+
+// mapGigaChatFinishReason маппит finish_reason GigaChat в общие константы entities.
+func mapGigaChatFinishReason(finishReason string) *string {
+	var mappedReason string
+	switch finishReason {
+	case "":
+		return nil
+	case gigachat.FinishReasonStop:
+		mappedReason = entities.FinishReasonStop
+	case gigachat.FinishReasonLength:
+		mappedReason = entities.FinishReasonLength
+	case gigachat.FinishReasonFunctionCall:
+		mappedReason = entities.FinishReasonToolCalls
+	case gigachat.FinishReasonBlacklist:
+		mappedReason = entities.FinishReasonContentFilter
+	default:
+		mappedReason = finishReason
+	}
+	return &mappedReason
+}
+
+// newRequestUID создает случайный UUID v4 для заголовка RqUID.
+func newRequestUID() string {
+	uid := make([]byte, 16)
+	rand.Read(uid)
+	uid[6] = (uid[6] & 0x0f) | 0x40
+	uid[8] = (uid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uid[0:4], uid[4:6], uid[6:8], uid[8:10], uid[10:])
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/gigachat"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeGigaChatServer OAuth сервер и API GigaChat в одном httptest сервере.
+// Каждый запрос к /oauth выдает новый токен, API принимает только последний выданный.
+type fakeGigaChatServer struct {
+	*httptest.Server
+	tokensIssued atomic.Int32
+}
+
+func newFakeGigaChatServer(t *testing.T, handler func(w http.ResponseWriter, request gigachat.ChatRequest)) *fakeGigaChatServer {
+	server := &fakeGigaChatServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth" {
+			r.ParseForm()
+			if r.Header.Get("Authorization") != "Basic auth-key" || r.Header.Get("RqUID") == "" || r.Form.Get("scope") != gigachat.ScopePersonal {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code": 6, "message": "credentials doesn't match db data"}`))
+				return
+			}
+			n := server.tokensIssued.Add(1)
+			json.NewEncoder(w).Encode(gigachat.TokenResponse{
+				AccessToken: "token-" + string(rune('0'+n)),
+				ExpiresAt:   time.Now().Add(30 * time.Minute).UnixMilli(),
+			})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer token-"+string(rune('0'+server.tokensIssued.Load())) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status": 401, "message": "Token has expired"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/models":
+			w.Write([]byte(`{"data": [{"id": "GigaChat-2", "owned_by": "salutedevices"}, {"id": "GigaChat-2-Pro", "owned_by": "salutedevices"}]}`))
+		case "/api/v1/chat/completions":
+			var request gigachat.ChatRequest
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			handler(w, request)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func newTestGigaChatProvider(t *testing.T, server *fakeGigaChatServer) *GigaChatProvider {
+	p, err := NewGigaChatProvider(GigaChatConfig{
+		AuthKey: "auth-key",
+		AuthURL: server.URL + "/oauth",
+		BaseURL: server.URL + "/api/v1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return p
+}
+
+func TestGigaChatSendMessageWithFunctionCall(t *testing.T) {
+	var received gigachat.ChatRequest
+	server := newFakeGigaChatServer(t, func(w http.ResponseWriter, request gigachat.ChatRequest) {
+		received = request
+		w.Write([]byte(`{
+			"choices": [{
+				"index": 0,
+				"message": {"role": "assistant", "content": "", "function_call": {"name": "get_weather", "arguments": {"city": "Казань"}}, "functions_state_id": "77d3fb14-457a-46ba-937e-8d856156d003"},
+				"finish_reason": "function_call"
+			}],
+			"model": "GigaChat-2-Pro",
+			"usage": {"prompt_tokens": 1500, "completion_tokens": 500, "total_tokens": 2000}
+		}`))
+	})
+	defer server.Close()
+
+	p := newTestGigaChatProvider(t, server)
+
+	modelInfo, err := p.GetModelInfo("gigachat-2-pro")
+	if err != nil || modelInfo.PriceInRubles.String() != "3000" {
+		t.Fatalf("Expected gigachat-2-pro with ruble price, got %+v, %v", modelInfo, err)
+	}
+	if _, err := p.GetModelInfo("gigachat-2-max"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected model missing from /models to be not found, got %v", err)
+	}
+
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Погода в Москве?", AuthorType: entities.AuthorTypeUser},
+		{
+			AuthorType:  entities.AuthorTypeRobot,
+			ToolCalls:   []mcpgo.CallToolRequest{{Params: mcpgo.CallToolParams{Name: "get_weather", Arguments: map[string]interface{}{"city": "Москва"}}}},
+			ToolCallIDs: []string{"call_1"},
+		},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+	}
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithString("city", mcpgo.Required()))
+
+	response, err := p.SendMessage(context.Background(), messages, "gigachat-2-pro", options.WithMCPTools([]mcpgo.Tool{tool}), options.WithMaxTokens(100))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if received.Model != "GigaChat-2-Pro" || received.MaxTokens == nil || *received.MaxTokens != 100 {
+		t.Errorf("Unexpected model or max_tokens: %s, %v", received.Model, received.MaxTokens)
+	}
+	if len(received.Functions) != 1 || received.Functions[0].Name != "get_weather" || received.FunctionCall != gigachat.FunctionCallAuto {
+		t.Errorf("Unexpected functions: %+v, %v", received.Functions, received.FunctionCall)
+	}
+	if len(received.Messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %+v", received.Messages)
+	}
+	if call := received.Messages[2].FunctionCall; call == nil || call.Name != "get_weather" || call.Arguments["city"] != "Москва" {
+		t.Errorf("Expected assistant function call, got %+v", received.Messages[2])
+	}
+	if result := received.Messages[3]; result.Role != gigachat.RoleFunction || result.Name != "get_weather" || result.Content != `{"result":"+5"}` {
+		t.Errorf("Expected function result as JSON object, got %+v", result)
+	}
+
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Params.Name != "get_weather" || response.ToolCallIDs[0] == "" {
+		t.Fatalf("Unexpected tool calls: %+v", response)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+	// 2000 токенов по 1500 рублей за миллион
+	if response.TotalTokens != 2000 || response.PriceInRubles.String() != "3" {
+		t.Errorf("Unexpected usage: tokens %d, price %s", response.TotalTokens, response.PriceInRubles)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "gigachat-2-pro", options.WithSeed(1)); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected ErrUnsupportedParameter for seed, got %v", err)
+	}
+	images := []*entities.Message{{MessageText: "Что на фото?", AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{URL: "https://example.com/cat.png"}}}}
+	if _, err := p.SendMessage(context.Background(), images, "gigachat-2-pro"); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for images, got %v", err)
+	}
+}
+
+func TestGigaChatStreamAndTokenRefresh(t *testing.T) {
+	server := newFakeGigaChatServer(t, func(w http.ResponseWriter, request gigachat.ChatRequest) {
+		if !request.Stream {
+			t.Errorf("Expected stream request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices": [{"index": 0, "delta": {"role": "assistant", "content": "При"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices": [{"index": 0, "delta": {"content": "вет"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	})
+	defer server.Close()
+
+	p := newTestGigaChatProvider(t, server)
+	// Токен отозван на сервере до истечения срока: провайдер должен получить новый и повторить запрос
+	server.tokensIssued.Add(1)
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	chunks, err := p.SendMessageStream(context.Background(), messages, "gigachat-2")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var text string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if text != "Привет" || final == nil || final.TotalTokens != 15 {
+		t.Fatalf("Unexpected stream: text %q, final %+v", text, final)
+	}
+	if final.FinishReason == nil || *final.FinishReason != entities.FinishReasonStop {
+		t.Errorf("Expected finish reason stop, got %v", final.FinishReason)
+	}
+	if server.tokensIssued.Load() != 3 {
+		t.Errorf("Expected token refresh after 401, got %d tokens issued", server.tokensIssued.Load())
+	}
+
+	if _, err := NewGigaChatProvider(GigaChatConfig{AuthKey: "wrong", AuthURL: server.URL + "/oauth", BaseURL: server.URL + "/api/v1"}); !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected ErrAuth for wrong auth key, got %v", err)
+	}
+}
@@ -1,20 +1,15 @@
 package provider
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
 	"github.com/Murolando/m_ai_provider/entities"
 	"github.com/Murolando/m_ai_provider/internal/config"
 	internalEnt "github.com/Murolando/m_ai_provider/internal/entities"
 	"github.com/Murolando/m_ai_provider/internal/entities/openai"
-	"github.com/Murolando/m_ai_provider/internal/mappers"
 	"github.com/Murolando/m_ai_provider/options"
-	mcpgo "github.com/mark3labs/mcp-go/mcp"
 	"github.com/shopspring/decimal"
 )
 
@@ -22,229 +17,114 @@ const (
 	hydraAIProviderName = "HydraAI"
 )
 
-var _ Provider = (*HydraAIProvider)(nil)
+var (
+	_ Provider       = (*HydraAIProvider)(nil)
+	_ Namer          = (*HydraAIProvider)(nil)
+	_ ModelRefresher = (*HydraAIProvider)(nil)
+)
 
 // HydraAIProvider представляет провайдера для работы с HydraAI API.
+// HydraAI реализует OpenAI Chat Completions, поэтому провайдер построен на OpenAICompatibleProvider
+// и отличается форматом списка моделей и стоимостью запроса в usage.
 type HydraAIProvider struct {
-	apiKey      string                                     // API ключ для аутентификации
-	baseURL     string                                     // Базовый URL для API запросов
-	modelMap    map[entities.ModelName]*entities.ModelInfo // Кэш информации о моделях
-	toolsMapper *mappers.ToolsMapper                       // Маппер для конвертации инструментов
+	*OpenAICompatibleProvider
 }
 
 // NewHydraAIProvider создает новый экземпляр HydraAI провайдера.
 // apiKey - API ключ для аутентификации в HydraAI
 // baseURL - базовый URL для API запросов
+// opts - настройки HTTP клиента (прокси, таймауты, заголовки)
 // Возвращает настроенный провайдер или ошибку при неудачной инициализации.
-func NewHydraAIProvider(apiKey string, baseURL string) (*HydraAIProvider, error) {
+func NewHydraAIProvider(apiKey string, baseURL string, opts ...ClientOption) (*HydraAIProvider, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("HYDRAAI_TOKEN is not set")
 	}
 
-	if baseURL == "" {
-		return nil, fmt.Errorf("HYDRAAI_URL is not set")
-	}
-
-	provider := &HydraAIProvider{
-		apiKey:      apiKey,
-		baseURL:     baseURL,
-		modelMap:    make(map[entities.ModelName]*entities.ModelInfo),
-		toolsMapper: mappers.NewToolsMapper(),
-	}
-
-	if err := provider.getModels(); err != nil {
-		return nil, fmt.Errorf("failed to get models: %w", err)
-	}
-
-	return provider, nil
-}
-
-// SendMessage отправляет сообщения в AI модель через HydraAI API.
-func (p *HydraAIProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
-	// Получаем модель из маппинга
-	hydraModel, exists := config.HydraNamesMap[modelName]
-	if !exists {
-		return nil, fmt.Errorf("model %s not supported by %s provider", modelName, hydraAIProviderName)
-	}
-
-	// Конвертируем сообщения в формат HydraAI
-	chatMessages, err := p.convertToChatMessages(messages)
+	clientConfig, err := newClientConfig(opts)
 	if err != nil {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to convert messages: %w. Messages: %s", err, string(messagesJSON))
+		return nil, err
 	}
-	request := internalEnt.NewHydraChatCompletionRequest(hydraModel, chatMessages)
-
-	// Обрабатываем MCP tools опцию если она есть
-	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
-		// Конвертируем MCP tools в OpenAI формат
-		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
-		if err != nil {
-			messagesJSON, _ := json.Marshal(messages)
-			return nil, fmt.Errorf("failed to convert MCP tools to OpenAI: %w. Messages: %s", err, string(messagesJSON))
-		}
-
-		// Добавляем инструменты к запросу
-		request.Tools = openaiTools
-		request.ToolChoice = openai.ToolChoiceAuto
-	}
-
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to marshal request: %w. Messages: %s", err, string(messagesJSON))
+	if baseURL == "" && clientConfig.baseURL == "" {
+		return nil, fmt.Errorf("HYDRAAI_URL is not set")
 	}
 
-	url := p.baseURL + "/chat/completions"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	provider, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:         hydraAIProviderName,
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		ModelMapping: config.HydraNamesMap,
+		Quirks:       hydraAIQuirks(),
+		Options:      opts,
+	})
 	if err != nil {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to create request: %w. Messages: %s", err, string(messagesJSON))
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return &HydraAIProvider{OpenAICompatibleProvider: provider}, nil
+}
 
-	// Выполняем запрос
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to send request: %w. Messages: %s", err, string(messagesJSON))
+// hydraAIQuirks описывает отличия HydraAI от OpenAI API.
+func hydraAIQuirks() OpenAICompatibleQuirks {
+	return OpenAICompatibleQuirks{
+		// HydraAI проксирует не только модели OpenAI, роль developer понимают не все
+		DeveloperRole:     openai.RoleSystem,
+		RequireModalities: true,
+		ParseModels:       parseHydraAIModels,
+		Cost:              hydraAICost,
 	}
-	defer response.Body.Close()
+}
 
-	responseBody, err := io.ReadAll(response.Body)
+// SendMessage отправляет сообщения в AI модель через HydraAI API.
+// В текст ошибки добавляется история сообщений для отладки.
+func (p *HydraAIProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	result, err := p.OpenAICompatibleProvider.SendMessage(ctx, messages, modelName, opts...)
 	if err != nil {
 		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to read response: %w. Messages: %s", err, string(messagesJSON))
-	}
-
-	if response.StatusCode != http.StatusOK {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("API request failed with status %d: %s. Messages: %s", response.StatusCode, string(responseBody), string(messagesJSON))
+		return nil, fmt.Errorf("%w. Messages: %s", err, string(messagesJSON))
 	}
-
-	var chatResponse internalEnt.HydraChatCompletionResponse
-	if err := json.Unmarshal(responseBody, &chatResponse); err != nil {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("failed to unmarshal response: %w. Messages: %s", err, string(messagesJSON))
-	}
-
-	if len(chatResponse.Choices) == 0 {
-		messagesJSON, _ := json.Marshal(messages)
-		return nil, fmt.Errorf("no choices in response. Messages: %s", string(messagesJSON))
-	}
-
-	choice := chatResponse.Choices[0]
-	result := &entities.ProviderMessageResponseDTO{
-		TotalTokens:   int64(chatResponse.Usage.TotalTokens),
-		PriceInRubles: decimal.NewFromFloat(chatResponse.Usage.CostRequest).Round(3),
-		FinishReason:  mapFinishReason(choice.FinishReason),
-	}
-
-	// Обрабатываем tool calls если они есть
-	if len(choice.Message.ToolCalls) > 0 {
-		mcpToolCalls := make([]mcpgo.CallToolRequest, len(choice.Message.ToolCalls))
-		toolCallIDs := make([]string, len(choice.Message.ToolCalls))
-
-		for i, toolCall := range choice.Message.ToolCalls {
-			mcpToolCall, err := p.toolsMapper.OpenAIToolCallToMCP(toolCall)
-			if err != nil {
-				messagesJSON, _ := json.Marshal(messages)
-				return nil, fmt.Errorf("failed to convert tool call %d to MCP: %w. Messages: %s", i, err, string(messagesJSON))
-			}
-			mcpToolCalls[i] = mcpToolCall
-			toolCallIDs[i] = toolCall.ID // Извлекаем ID из OpenAI ToolCall
-		}
-
-		result.ToolCalls = mcpToolCalls
-		result.ToolCallIDs = toolCallIDs // Сохраняем массив ID
-	}
-
-	// Извлекаем текст ответа если есть
-	switch content := choice.Message.Content.(type) {
-	case string:
-		result.MessageText = content
-	case []interface{}:
-		// Если это массив, ищем текстовые элементы
-		for _, item := range content {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				if itemType, ok := itemMap["type"].(string); ok && itemType == "text" {
-					if text, ok := itemMap["text"].(string); ok {
-						result.MessageText += text
-					}
-				}
-			}
-		}
-	}
-
 	return result, nil
 }
 
-// GetModelInfo получает информацию о конкретной модели из кэша.
-func (p *HydraAIProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
-	if modelInfo, exists := p.modelMap[modelName]; exists {
-		return modelInfo, nil
-	}
-	return nil, fmt.Errorf("model %s not found in %s provider", modelName, hydraAIProviderName)
-}
-
-// getModels получает все модели от HydraAI API и заполняет кэш моделей.
-func (p *HydraAIProvider) getModels() error {
-	url := p.baseURL + "/models"
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(response.Body)
-		return fmt.Errorf("API request failed with status %d: %s", response.StatusCode, string(body))
-	}
-
+// parseHydraAIModels разбирает список моделей HydraAI: учитывает только активные модели и рассчитывает цену.
+func parseHydraAIModels(body []byte) ([]OpenAICompatibleModel, error) {
 	var modelsResponse internalEnt.ModelsResponse
-	if err := json.NewDecoder(response.Body).Decode(&modelsResponse); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return nil, err
 	}
 
-	// Проходим по всем моделям от API
+	models := make([]OpenAICompatibleModel, 0, len(modelsResponse.Data))
 	for _, hydraModel := range modelsResponse.Data {
-		// Проверяем, есть ли эта модель в нашем маппинге
-		for ourModelName, hydraModelID := range config.HydraNamesMap {
-			if hydraModel.ID == hydraModelID && hydraModel.Active {
-				// Рассчитываем цену
-				pricingParams := internalEnt.HydraPricingParams{Pricing: hydraModel.Pricing}
-				price, err := p.calculatePrice(pricingParams)
-				if err != nil {
-					// Если ошибка расчета, используем нулевую цену
-					price = decimal.Zero
-				}
+		if !hydraModel.Active {
+			continue
+		}
 
-				// Сохраняем в кэш
-				p.modelMap[ourModelName] = &entities.ModelInfo{
-					Name:          hydraModel.Name,
-					Alias:         ourModelName,
-					PriceInRubles: price,
-				}
-				break
-			}
+		price, err := calculateHydraAIPrice(internalEnt.HydraPricingParams{Pricing: hydraModel.Pricing})
+		if err != nil {
+			// Если ошибка расчета, используем нулевую цену
+			price = decimal.Zero
 		}
+
+		models = append(models, OpenAICompatibleModel{
+			ID:              hydraModel.ID,
+			Name:            hydraModel.Name,
+			PriceInRubles:   price,
+			InputModalities: hydraModel.InputModalities,
+		})
 	}
+	return models, nil
+}
 
-	return nil
+// hydraAICost извлекает стоимость запроса в рублях из usage HydraAI (поле cost_request).
+func hydraAICost(usage json.RawMessage) (decimal.Decimal, error) {
+	var hydraUsage internalEnt.HydraChatCompletionUsage
+	if err := json.Unmarshal(usage, &hydraUsage); err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromFloat(hydraUsage.CostRequest).Round(3), nil
 }
 
-// calculatePrice рассчитывает цену на основе переданных параметров
-func (p *HydraAIProvider) calculatePrice(params internalEnt.PricingParams) (decimal.Decimal, error) {
+// calculateHydraAIPrice рассчитывает цену на основе параметров HydraAI.
+func calculateHydraAIPrice(params internalEnt.PricingParams) (decimal.Decimal, error) {
 	switch pricingParams := params.(type) {
 	case internalEnt.HydraPricingParams:
 		pricing := pricingParams.Pricing
@@ -272,75 +152,3 @@ func (p *HydraAIProvider) calculatePrice(params internalEnt.PricingParams) (deci
 		return decimal.Zero, fmt.Errorf("unsupported pricing params type for HydraAI: %T", params)
 	}
 }
-
-// convertToChatMessages конвертирует внутренние сообщения в формат OpenAI/HydraAI
-func (p *HydraAIProvider) convertToChatMessages(messages []*entities.Message) ([]openai.ChatMessage, error) {
-	chatMessages := make([]openai.ChatMessage, len(messages))
-
-	for i, msg := range messages {
-		var role string
-		switch msg.AuthorType {
-		case entities.AuthorTypeUser:
-			role = openai.RoleUser
-		case entities.AuthorTypeRobot:
-			role = openai.RoleAssistant
-			// Создаем базовое сообщение
-			chatMessages[i] = openai.NewTextMessage(role, msg.MessageText)
-			// Добавляем tool calls если они есть
-			if len(msg.ToolCalls) > 0 {
-				openaiToolCalls := make([]openai.ToolCall, len(msg.ToolCalls))
-				for j, mcpCall := range msg.ToolCalls {
-					openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
-					if err != nil {
-						return nil, fmt.Errorf("failed to convert MCP tool call %d to OpenAI: %w", j, err)
-					}
-					// Используем сохраненный ID
-					openaiToolCall.ID = msg.ToolCallIDs[j]
-					openaiToolCalls[j] = openaiToolCall
-				}
-				chatMessages[i].ToolCalls = openaiToolCalls
-			}
-			continue
-		case entities.AuthorTypeTool:
-			// Для tool сообщений создаем специальное сообщение с tool_call_id
-			if msg.ToolCallIDs[0] == "" {
-				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
-			}
-			chatMessages[i] = openai.ChatMessage{
-				Role:       openai.RoleTool,
-				Content:    msg.MessageText,
-				ToolCallID: &msg.ToolCallIDs[0],
-			}
-			continue
-		default:
-			role = openai.RoleUser // дефолтная роль
-		}
-		chatMessages[i] = openai.NewTextMessage(role, msg.MessageText)
-	}
-
-	return chatMessages, nil
-}
-
-// mapFinishReason маппит OpenAI finish reason в общие константы entities.
-func mapFinishReason(openaiReason *string) *string {
-	if openaiReason == nil {
-		return nil
-	}
-
-	var mappedReason string
-	switch *openaiReason {
-	case openai.FinishReasonStop:
-		mappedReason = entities.FinishReasonStop
-	case openai.FinishReasonLength:
-		mappedReason = entities.FinishReasonLength
-	case openai.FinishReasonToolCalls:
-		mappedReason = entities.FinishReasonToolCalls
-	case openai.FinishReasonContentFilter:
-		mappedReason = entities.FinishReasonContentFilter
-	default:
-		// Если неизвестная причина, возвращаем как есть
-		mappedReason = *openaiReason
-	}
-
-	return &mappedReason
-}
@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+)
+
+// Константы для провайдера llama.cpp
+const (
+	llamaCppProviderName   = "LlamaCpp"
+	llamaCppDefaultBaseURL = "http://localhost:8080/v1"
+)
+
+// NewLlamaCppProvider создает провайдера для llama.cpp server (llama-server) с OpenAI-совместимым API.
+// baseURL - адрес API сервера (пустой - http://localhost:8080/v1)
+// opts - настройки HTTP клиента; если сервер запущен с --api-key, передайте WithHeader("Authorization", "Bearer ...")
+func NewLlamaCppProvider(baseURL string, opts ...ClientOption) (*OpenAICompatibleProvider, error) {
+	if baseURL == "" {
+		baseURL = llamaCppDefaultBaseURL
+	}
+
+	return NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:    llamaCppProviderName,
+		BaseURL: baseURL,
+		Quirks: OpenAICompatibleQuirks{
+			// Шаблоны чата большинства локальных моделей не знают роль developer
+			DeveloperRole: openai.RoleSystem,
+		},
+		Options: opts,
+	})
+}
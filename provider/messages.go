@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/entities/mappers"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+// prepareMessages добавляет к истории сообщения из опций: системный промпт и сообщение с MCP ресурсами
+// (options.WithResources) перед историей. Исходный срез сообщений не изменяется.
+func prepareMessages(messages []*entities.Message, opts []options.SendMessageOption) ([]*entities.Message, error) {
+	prompt, hasPrompt := options.ExtractSystemPromptOption(opts)
+	hasPrompt = hasPrompt && prompt != ""
+	resources, hasResources := options.ExtractResourcesOption(opts)
+	hasResources = hasResources && len(resources) > 0
+	if !hasPrompt && !hasResources {
+		return messages, nil
+	}
+
+	prepared := make([]*entities.Message, 0, len(messages)+2)
+	if hasPrompt {
+		prepared = append(prepared, &entities.Message{
+			MessageText: prompt,
+			AuthorType:  entities.AuthorTypeSystem,
+			MessageType: entities.MessageText,
+		})
+	}
+	if hasResources {
+		resourcesMessage, err := mappers.ResourcesToMessage(resources)
+		if err != nil {
+			if errors.Is(err, mappers.ErrUnsupportedContent) {
+				return nil, fmt.Errorf("%w: %w", ErrUnsupportedModality, err)
+			}
+			return nil, err
+		}
+		prepared = append(prepared, resourcesMessage)
+	}
+	return append(prepared, messages...), nil
+}
+
+// hasImages проверяет, есть ли в сообщениях изображения.
+func hasImages(messages []*entities.Message) bool {
+	for _, msg := range messages {
+		if len(msg.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImageInput проверяет, что модель принимает изображения, если они есть в сообщениях.
+// Модель без известных модальностей считается текстовой. Изображения допускаются только в сообщениях
+// пользователя, в том числе без типа автора.
+func checkImageInput(providerName string, messages []*entities.Message, modelName entities.ModelName, modelInfo *entities.ModelInfo) error {
+	if !hasImages(messages) {
+		return nil
+	}
+	for i, msg := range messages {
+		if len(msg.Images) > 0 && msg.AuthorType != entities.AuthorTypeUser && msg.AuthorType != "" {
+			return fmt.Errorf("message at index %d: images are supported only in user messages", i)
+		}
+	}
+	if modelInfo == nil || !modelInfo.SupportsInputModality(entities.ModalityImage) {
+		return fmt.Errorf("%w: model %s does not accept image input in %s provider", ErrUnsupportedModality, modelName, providerName)
+	}
+	return nil
+}
+
+// splitDataURI разбирает data URI изображения на MIME тип и данные в base64.
+func splitDataURI(dataURI string) (string, string, error) {
+	header, data, found := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !found || !isBase64 {
+		return "", "", fmt.Errorf("image data URI must be base64 encoded")
+	}
+	return mediaType, data, nil
+}
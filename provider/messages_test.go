@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPrepareMessagesSystemPrompt(t *testing.T) {
+	messages := []*entities.Message{
+		{MessageText: "Привет", AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText},
+	}
+
+	prepared, err := prepareMessages(messages, []options.SendMessageOption{options.WithSystemPrompt("Отвечай кратко")})
+	if err != nil {
+		t.Fatalf("prepareMessages() failed with error: %v", err)
+	}
+
+	if len(prepared) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(prepared))
+	}
+	if prepared[0].AuthorType != entities.AuthorTypeSystem || prepared[0].MessageText != "Отвечай кратко" {
+		t.Errorf("Expected system prompt first, got %+v", prepared[0])
+	}
+	if len(messages) != 1 {
+		t.Errorf("Expected original history to stay unchanged, got %d messages", len(messages))
+	}
+
+	if unchanged, _ := prepareMessages(messages, nil); len(unchanged) != 1 {
+		t.Errorf("Expected history without options to stay the same, got %d messages", len(unchanged))
+	}
+}
+
+func TestPrepareMessagesResources(t *testing.T) {
+	messages := []*entities.Message{
+		{MessageText: "Что в документах?", AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText},
+	}
+	opts := []options.SendMessageOption{
+		options.WithSystemPrompt("Отвечай по документам"),
+		options.WithResources(mcpgo.TextResourceContents{URI: "kb://docs/1", MIMEType: "text/markdown", Text: "# Отпуск"}),
+		options.WithResources(mcpgo.BlobResourceContents{URI: "kb://docs/2", MIMEType: "image/png", Blob: "iVA="}),
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		t.Fatalf("prepareMessages() failed with error: %v", err)
+	}
+	if len(prepared) != 3 || prepared[0].AuthorType != entities.AuthorTypeSystem || prepared[2] != messages[0] {
+		t.Fatalf("Expected system prompt, resources and history, got %d messages", len(prepared))
+	}
+	resources := prepared[1]
+	if resources.AuthorType != entities.AuthorTypeUser || resources.MessageText != "<resource uri=\"kb://docs/1\" mime_type=\"text/markdown\">\n# Отпуск\n</resource>" {
+		t.Errorf("Expected text resource in user message, got %+v", resources)
+	}
+	if resources.MessageType != entities.MessageImage || len(resources.Images) != 1 || resources.Images[0].MIMEType != "image/png" {
+		t.Errorf("Expected image resource, got %+v", resources.Images)
+	}
+
+	// Изображение из ресурса проверяется так же, как изображения в истории
+	textModel := &entities.ModelInfo{InputModalities: []string{entities.ModalityText}}
+	if err := checkImageInput("test", prepared, "text-model", textModel); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for image resource, got %v", err)
+	}
+
+	pdf := options.WithResources(mcpgo.BlobResourceContents{URI: "kb://docs/3", MIMEType: "application/pdf", Blob: "JVBERg=="})
+	if _, err := prepareMessages(messages, []options.SendMessageOption{pdf}); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for PDF resource, got %v", err)
+	}
+}
+
+// newTestHydraAIProvider создает HydraAI провайдера без загрузки моделей.
+func newTestHydraAIProvider() *HydraAIProvider {
+	return &HydraAIProvider{OpenAICompatibleProvider: &OpenAICompatibleProvider{
+		name:        hydraAIProviderName,
+		quirks:      hydraAIQuirks(),
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+	}}
+}
+
+func TestHydraConvertToChatMessagesRoles(t *testing.T) {
+	p := newTestHydraAIProvider()
+
+	chatMessages, err := p.convertToChatMessages([]*entities.Message{
+		{MessageText: "system", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "developer", AuthorType: entities.AuthorTypeDeveloper},
+		{MessageText: "user", AuthorType: entities.AuthorTypeUser},
+		{MessageText: "robot", AuthorType: entities.AuthorTypeRobot},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+
+	expectedRoles := []string{openai.RoleSystem, openai.RoleSystem, openai.RoleUser, openai.RoleAssistant}
+	for i, role := range expectedRoles {
+		if chatMessages[i].Role != role {
+			t.Errorf("Expected message %d role '%s', got '%s'", i, role, chatMessages[i].Role)
+		}
+	}
+
+	chatMessages, err = p.convertToChatMessages([]*entities.Message{{MessageText: "Привет"}})
+	if err != nil || chatMessages[0].Role != openai.RoleUser {
+		t.Errorf("Expected empty author type to map to user role, got %+v (%v)", chatMessages, err)
+	}
+
+	if _, err := p.convertToChatMessages([]*entities.Message{{MessageText: "?", AuthorType: "unknown"}}); err == nil {
+		t.Error("Expected error for unknown author type")
+	}
+}
+
+func TestHydraConvertToChatMessagesImages(t *testing.T) {
+	p := newTestHydraAIProvider()
+
+	chatMessages, err := p.convertToChatMessages([]*entities.Message{
+		{
+			MessageText: "Что на картинках?",
+			AuthorType:  entities.AuthorTypeUser,
+			MessageType: entities.MessageImage,
+			Images: []entities.ImageContent{
+				{URL: "https://example.com/cat.png", Detail: entities.ImageDetailLow},
+				{Data: []byte{0x89, 0x50}, MIMEType: "image/png"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+
+	parts, ok := chatMessages[0].Content.([]openai.ContentPart)
+	if !ok || len(parts) != 3 {
+		t.Fatalf("Expected 3 content parts, got %#v", chatMessages[0].Content)
+	}
+	if parts[0].Type != openai.ContentTypeText || *parts[0].Text != "Что на картинках?" {
+		t.Errorf("Expected text part first, got %+v", parts[0])
+	}
+	if parts[1].ImageURL.URL != "https://example.com/cat.png" || *parts[1].ImageURL.Detail != entities.ImageDetailLow {
+		t.Errorf("Expected image url part with low detail, got %+v", parts[1].ImageURL)
+	}
+	if parts[2].ImageURL.URL != "data:image/png;base64,iVA=" || parts[2].ImageURL.Detail != nil {
+		t.Errorf("Expected data URI part without detail, got %+v", parts[2].ImageURL)
+	}
+
+	_, err = p.convertToChatMessages([]*entities.Message{
+		{AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{Data: []byte{1}, MIMEType: "text/plain"}}},
+	})
+	if err == nil {
+		t.Error("Expected error for image data with non-image mime type")
+	}
+}
+
+func TestCheckImageInput(t *testing.T) {
+	messages := []*entities.Message{
+		{AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{URL: "https://example.com/cat.png"}}},
+	}
+
+	textModel := &entities.ModelInfo{InputModalities: []string{entities.ModalityText}}
+	if err := checkImageInput("test", messages, "text-model", textModel); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for text model, got %v", err)
+	}
+
+	if err := checkImageInput("test", messages, "unknown-model", nil); !errors.Is(err, ErrUnsupportedModality) {
+		t.Errorf("Expected ErrUnsupportedModality for unknown model, got %v", err)
+	}
+
+	visionModel := &entities.ModelInfo{InputModalities: []string{entities.ModalityText, entities.ModalityImage}}
+	if err := checkImageInput("test", messages, "vision-model", visionModel); err != nil {
+		t.Errorf("Expected vision model to accept images, got %v", err)
+	}
+
+	messages[0].AuthorType = ""
+	if err := checkImageInput("test", messages, "vision-model", visionModel); err != nil {
+		t.Errorf("Expected message without author type to accept images, got %v", err)
+	}
+	messages[0].AuthorType = entities.AuthorTypeRobot
+	if err := checkImageInput("test", messages, "vision-model", visionModel); err == nil {
+		t.Error("Expected error for images in assistant message")
+	}
+}
+
+// TestConvertEmptyAuthorType проверяет, что каждый провайдер отправляет сообщение без типа автора
+// как сообщение пользователя, а неизвестный тип отклоняет.
+func TestConvertEmptyAuthorType(t *testing.T) {
+	toolsMapper := mappers.NewToolsMapper()
+	converters := map[string]func([]*entities.Message) (string, error){
+		"OpenRouter": func(messages []*entities.Message) (string, error) {
+			result, err := (&OpenRouterProvider{toolsMapper: toolsMapper}).convertToChatMessages(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+		"Anthropic": func(messages []*entities.Message) (string, error) {
+			_, result, err := (&AnthropicProvider{}).convertToMessages(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+		"Gemini": func(messages []*entities.Message) (string, error) {
+			_, result, err := (&GeminiProvider{}).convertToContents(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+		"GigaChat": func(messages []*entities.Message) (string, error) {
+			result, err := (&GigaChatProvider{toolsMapper: toolsMapper}).convertToMessages(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+		"Ollama": func(messages []*entities.Message) (string, error) {
+			result, err := (&OllamaProvider{toolsMapper: toolsMapper}).convertToMessages(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+		"YandexGPT": func(messages []*entities.Message) (string, error) {
+			result, err := (&YandexGPTProvider{toolsMapper: toolsMapper}).convertToMessages(messages)
+			if err != nil {
+				return "", err
+			}
+			return result[0].Role, nil
+		},
+	}
+
+	for name, convert := range converters {
+		role, err := convert([]*entities.Message{{MessageText: "Привет"}})
+		if err != nil || role != "user" {
+			t.Errorf("%s: expected empty author type to map to user role, got %q (%v)", name, role, err)
+		}
+		if _, err := convert([]*entities.Message{{MessageText: "?", AuthorType: "unknown"}}); err == nil {
+			t.Errorf("%s: expected error for unknown author type", name)
+		}
+	}
+}
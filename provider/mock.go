@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+// mockProviderName название провайдера для тестов.
+const mockProviderName = "Mock"
+
+var (
+	_ Provider = (*MockProvider)(nil)
+	_ Namer    = (*MockProvider)(nil)
+)
+
+// ErrNoMockResponse возвращается, когда для запроса нет подходящего ответа в сценарии или кассете.
+var ErrNoMockResponse = errors.New("no scripted response for request")
+
+// MockRequest описывает запрос, полученный тестовым провайдером.
+type MockRequest struct {
+	ModelName entities.ModelName          // Модель из запроса
+	Messages  []*entities.Message         // Сообщения с примененным системным промптом из опций
+	Options   []options.SendMessageOption // Опции запроса
+}
+
+// LastMessage возвращает последнее сообщение запроса или nil.
+func (r *MockRequest) LastMessage() *entities.Message {
+	if len(r.Messages) == 0 {
+		return nil
+	}
+	return r.Messages[len(r.Messages)-1]
+}
+
+// MockMatcher проверяет, подходит ли ответ сценария к запросу.
+type MockMatcher func(request *MockRequest) bool
+
+// MatchModel выбирает ответ для запросов к указанной модели.
+func MatchModel(modelName entities.ModelName) MockMatcher {
+	return func(request *MockRequest) bool {
+		return request.ModelName == modelName
+	}
+}
+
+// MatchLastMessage выбирает ответ, если текст последнего сообщения содержит подстроку.
+func MatchLastMessage(substring string) MockMatcher {
+	return func(request *MockRequest) bool {
+		last := request.LastMessage()
+		return last != nil && strings.Contains(last.MessageText, substring)
+	}
+}
+
+// MatchAuthorType выбирает ответ, если последнее сообщение от указанного автора
+// (например, entities.AuthorTypeTool - после выполнения инструментов).
+func MatchAuthorType(authorType string) MockMatcher {
+	return func(request *MockRequest) bool {
+		last := request.LastMessage()
+		return last != nil && last.AuthorType == authorType
+	}
+}
+
+// MockResponse описывает ответ сценария тестового провайдера.
+type MockResponse struct {
+	Match    MockMatcher                          // Условие выбора ответа (nil - любой запрос)
+	Response *entities.ProviderMessageResponseDTO // Ответ модели
+	Err      error                                // Ошибка вместо ответа
+	Repeat   bool                                 // Ответ не расходуется и подходит для всех следующих запросов
+}
+
+// MockText создает текстовый ответ с причиной завершения stop.
+func MockText(text string) *entities.ProviderMessageResponseDTO {
+	finishReason := entities.FinishReasonStop
+	return &entities.ProviderMessageResponseDTO{
+		MessageText:  text,
+		FinishReason: &finishReason,
+	}
+}
+
+// MockToolCalls создает ответ с вызовами инструментов (ID call_1, call_2, ...) и причиной завершения tool_calls.
+func MockToolCalls(calls ...mcpgo.CallToolRequest) *entities.ProviderMessageResponseDTO {
+	finishReason := entities.FinishReasonToolCalls
+	toolCallIDs := make([]string, len(calls))
+	for i := range calls {
+		toolCallIDs[i] = fmt.Sprintf("call_%d", i+1)
+	}
+	return &entities.ProviderMessageResponseDTO{
+		ToolCalls:    calls,
+		ToolCallIDs:  toolCallIDs,
+		FinishReason: &finishReason,
+	}
+}
+
+// MockToolCall создает вызов инструмента для MockToolCalls.
+func MockToolCall(name string, arguments map[string]interface{}) mcpgo.CallToolRequest {
+	return mcpgo.CallToolRequest{Params: mcpgo.CallToolParams{Name: name, Arguments: arguments}}
+}
+
+// MockProvider тестовый провайдер, который отвечает по сценарию.
+// Для каждого запроса выбирается первый неизрасходованный ответ, условие которого подходит к запросу.
+// Полученные запросы сохраняются и доступны через Requests.
+type MockProvider struct {
+	mu        sync.Mutex
+	responses []*MockResponse // Ответы сценария
+	used      []bool          // Израсходованные ответы
+	requests  []*MockRequest  // Полученные запросы
+	models    *modelCache     // Модели, о которых знает провайдер
+}
+
+// NewMockProvider создает тестового провайдера с указанными моделями.
+// Стоимость ответов без PriceInRubles считается по цене модели за миллион токенов, если задано количество токенов.
+func NewMockProvider(models ...*entities.ModelInfo) *MockProvider {
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo, len(models))
+	for _, model := range models {
+		modelsInfo[model.Alias] = model
+	}
+	cache := newModelCache()
+	cache.replace(modelsInfo)
+	return &MockProvider{models: cache}
+}
+
+// Name возвращает название провайдера.
+func (p *MockProvider) Name() string {
+	return mockProviderName
+}
+
+// Reply добавляет в сценарий ответ на любой запрос.
+func (p *MockProvider) Reply(responses ...*entities.ProviderMessageResponseDTO) *MockProvider {
+	for _, response := range responses {
+		p.Script(&MockResponse{Response: response})
+	}
+	return p
+}
+
+// ReplyError добавляет в сценарий ошибку на любой запрос.
+func (p *MockProvider) ReplyError(err error) *MockProvider {
+	return p.Script(&MockResponse{Err: err})
+}
+
+// Script добавляет в сценарий ответы с условиями.
+func (p *MockProvider) Script(responses ...*MockResponse) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.responses = append(p.responses, responses...)
+	p.used = append(p.used, make([]bool, len(responses))...)
+	return p
+}
+
+// Requests возвращает запросы, полученные провайдером, в порядке поступления.
+func (p *MockProvider) Requests() []*MockRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*MockRequest(nil), p.requests...)
+}
+
+// Pending возвращает количество неизрасходованных ответов сценария (без Repeat).
+func (p *MockProvider) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := 0
+	for i, response := range p.responses {
+		if !response.Repeat && !p.used[i] {
+			pending++
+		}
+	}
+	return pending
+}
+
+// SendMessage возвращает ответ сценария для запроса.
+func (p *MockProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	request := &MockRequest{
+		ModelName: modelName,
+		Messages:  prepared,
+		Options:   opts,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, request)
+	for i, response := range p.responses {
+		if p.used[i] || (response.Match != nil && !response.Match(request)) {
+			continue
+		}
+		if !response.Repeat {
+			p.used[i] = true
+		}
+		if response.Err != nil {
+			return nil, response.Err
+		}
+		return p.complete(modelName, response.Response), nil
+	}
+
+	return nil, fmt.Errorf("%w: model %s, last message %q", ErrNoMockResponse, modelName, lastMessageText(request))
+}
+
+// SendMessageStream возвращает ответ сценария потоком: текст по словам, затем вызовы инструментов и итоговый ответ.
+func (p *MockProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	response, err := p.SendMessage(ctx, messages, modelName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return streamResponse(ctx, response), nil
+}
+
+// GetModelInfo возвращает информацию о модели, переданной в NewMockProvider.
+func (p *MockProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(mockProviderName, string(modelName))
+}
+
+// ListModels возвращает модели, переданные в NewMockProvider.
+func (p *MockProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// complete возвращает копию ответа сценария, чтобы вызывающий код не менял сценарий.
+// Если в ответе есть токены, но нет цены, цена считается по цене модели за миллион токенов.
+func (p *MockProvider) complete(modelName entities.ModelName, response *entities.ProviderMessageResponseDTO) *entities.ProviderMessageResponseDTO {
+	if response == nil {
+		response = MockText("")
+	}
+	result := *response
+	result.ToolCalls = append([]mcpgo.CallToolRequest(nil), response.ToolCalls...)
+	result.ToolCallIDs = append([]string(nil), response.ToolCallIDs...)
+
+	if modelInfo := p.models.get(modelName); modelInfo != nil && result.PriceInRubles.IsZero() && result.TotalTokens > 0 {
+		result.PriceInRubles = modelInfo.PriceInRubles.Mul(decimal.NewFromInt(result.TotalTokens)).Div(decimal.NewFromInt(1_000_000)).Round(3)
+	}
+	return &result
+}
+
+// streamResponse отдает готовый ответ потоком: текст по словам, вызовы инструментов целиком, затем итоговый ответ.
+func streamResponse(ctx context.Context, response *entities.ProviderMessageResponseDTO) <-chan entities.StreamChunk {
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+
+		for _, word := range strings.SplitAfter(response.MessageText, " ") {
+			if word == "" {
+				continue
+			}
+			if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: word}) {
+				return
+			}
+		}
+		for i, call := range response.ToolCalls {
+			delta := entities.ToolCallDelta{Index: i, Name: call.Params.Name}
+			if i < len(response.ToolCallIDs) {
+				delta.ID = response.ToolCallIDs[i]
+			}
+			if arguments, err := json.Marshal(call.Params.Arguments); err == nil {
+				delta.ArgumentsDelta = string(arguments)
+			}
+			if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+				return
+			}
+		}
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: response})
+	}()
+	return chunks
+}
+
+// lastMessageText возвращает текст последнего сообщения запроса для сообщений об ошибках.
+func lastMessageText(request *MockRequest) string {
+	if last := request.LastMessage(); last != nil {
+		return last.MessageText
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+func TestMockProviderToolLoop(t *testing.T) {
+	mock := NewMockProvider(&entities.ModelInfo{Name: "GPT-4o", Alias: "gpt-4o", PriceInRubles: decimal.NewFromInt(1000)})
+
+	toolCall := MockToolCalls(MockToolCall("get_weather", map[string]interface{}{"city": "Москва"}))
+	toolCall.TotalTokens = 1500
+	answer := MockText("В Москве +5")
+	answer.TotalTokens = 2000
+	answer.PriceInRubles = decimal.NewFromFloat(0.5)
+	mock.Script(
+		&MockResponse{Match: MatchAuthorType(entities.AuthorTypeTool), Response: answer},
+		&MockResponse{Match: MatchLastMessage("погода"), Response: toolCall},
+		&MockResponse{Match: MatchModel("gpt-4o-mini"), Err: &ProviderError{Kind: ErrRateLimit}, Repeat: true},
+	)
+
+	messages := []*entities.Message{{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser}}
+	response, err := mock.SendMessage(context.Background(), messages, "gpt-4o", options.WithSystemPrompt("Ты синоптик"))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCallIDs[0] != "call_1" || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Fatalf("Expected scripted tool call, got %+v", response)
+	}
+	// Цена не задана в сценарии и считается по цене модели: 1500 токенов по 1000 рублей за миллион
+	if response.PriceInRubles.String() != "1.5" {
+		t.Errorf("Expected price from model info, got %s", response.PriceInRubles)
+	}
+
+	messages = append(messages,
+		&entities.Message{AuthorType: entities.AuthorTypeRobot, ToolCalls: response.ToolCalls, ToolCallIDs: response.ToolCallIDs},
+		&entities.Message{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: response.ToolCallIDs},
+	)
+	chunks, err := mock.SendMessageStream(context.Background(), messages, "gpt-4o")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	var text string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		}
+	}
+	if text != "В Москве +5" || final == nil || final.TotalTokens != 2000 || final.PriceInRubles.String() != "0.5" {
+		t.Errorf("Unexpected stream: text %q, final %+v", text, final)
+	}
+
+	requests := mock.Requests()
+	if len(requests) != 2 || requests[0].Messages[0].MessageText != "Ты синоптик" || len(requests[1].Messages) != 3 {
+		t.Errorf("Unexpected recorded requests: %+v", requests)
+	}
+	if mock.Pending() != 0 {
+		t.Errorf("Expected all scripted responses used, %d pending", mock.Pending())
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := mock.SendMessage(context.Background(), messages, "gpt-4o-mini"); !errors.Is(err, ErrRateLimit) {
+			t.Errorf("Expected repeated ErrRateLimit, got %v", err)
+		}
+	}
+	if _, err := mock.SendMessage(context.Background(), messages, "gpt-4o"); !errors.Is(err, ErrNoMockResponse) {
+		t.Errorf("Expected ErrNoMockResponse when script is exhausted, got %v", err)
+	}
+	if _, err := mock.GetModelInfo("gpt-5"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for unknown model, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Murolando/m_ai_provider/entities"
+)
+
+// modelCache хранит информацию о моделях провайдера. Безопасен для конкурентного использования:
+// список моделей можно обновлять, пока идут запросы.
+type modelCache struct {
+	mu     sync.RWMutex
+	models map[entities.ModelName]*entities.ModelInfo
+}
+
+// newModelCache создает пустой кэш моделей.
+func newModelCache() *modelCache {
+	return &modelCache{models: make(map[entities.ModelName]*entities.ModelInfo)}
+}
+
+// get возвращает информацию о модели или nil, если модели нет в кэше.
+func (c *modelCache) get(modelName entities.ModelName) *entities.ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.models[modelName]
+}
+
+// list возвращает все модели, отсортированные по алиасу.
+func (c *modelCache) list() []*entities.ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	models := make([]*entities.ModelInfo, 0, len(c.models))
+	for _, modelInfo := range c.models {
+		models = append(models, modelInfo)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Alias < models[j].Alias
+	})
+	return models
+}
+
+// replace заменяет содержимое кэша целиком.
+func (c *modelCache) replace(models map[entities.ModelName]*entities.ModelInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models = models
+}
@@ -0,0 +1,502 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/ollama"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/shopspring/decimal"
+)
+
+// Константы для провайдера Ollama
+const (
+	ollamaProviderName   = "Ollama"
+	ollamaDefaultBaseURL = "http://localhost:11434"
+	// ollamaDefaultTag тег, который Ollama подставляет к названию модели без тега
+	ollamaDefaultTag = ":latest"
+)
+
+var (
+	_ Provider       = (*OllamaProvider)(nil)
+	_ Namer          = (*OllamaProvider)(nil)
+	_ ModelRefresher = (*OllamaProvider)(nil)
+	_ Embedder       = (*OllamaProvider)(nil)
+)
+
+// OllamaProvider представляет провайдера для локальных моделей Ollama (/api/chat).
+// Названия моделей передаются как есть (например, llama3.2 или qwen2.5:7b), цена моделей нулевая.
+type OllamaProvider struct {
+	baseURL     string               // Базовый URL Ollama
+	models      *modelCache          // Кэш установленных моделей
+	toolsMapper *mappers.ToolsMapper // Маппер для конвертации инструментов
+	httpClient  *http.Client         // HTTP клиент для запросов к API
+}
+
+// NewOllamaProvider создает провайдера для Ollama и загружает список установленных моделей.
+// baseURL - адрес Ollama (пустой - http://localhost:11434)
+// opts - настройки HTTP клиента (прокси, таймауты, заголовки)
+func NewOllamaProvider(baseURL string, opts ...ClientOption) (*OllamaProvider, error) {
+	httpConfig, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if httpConfig.baseURL != "" {
+		baseURL = httpConfig.baseURL
+	}
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+
+	provider := &OllamaProvider{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		httpClient:  httpClient,
+	}
+
+	if err := provider.getModels(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", err)
+	}
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *OllamaProvider) Name() string {
+	return ollamaProviderName
+}
+
+// SendMessage отправляет сообщения в локальную модель через /api/chat.
+func (p *OllamaProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, "/api/chat", request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(ollamaProviderName, err))
+	}
+
+	var chatResponse ollama.ChatResponse
+	if err := json.Unmarshal(responseBody, &chatResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(ollamaProviderName, responseBody, err))
+	}
+
+	accumulator := newStreamAccumulator()
+	toolCalls, err := p.accumulate(accumulator, &chatResponse, 0)
+	if err != nil {
+		return nil, err
+	}
+	return p.finalResponse(accumulator, &chatResponse, toolCalls > 0)
+}
+
+// SendMessageStream отправляет сообщения в локальную модель и возвращает ответ потоком (NDJSON).
+func (p *OllamaProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+
+	response, err := p.post(ctx, "/api/chat", request)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var last ollama.ChatResponse
+		toolCalls := 0
+
+		err := utils.ReadJSONLines(response.Body, func(line []byte) error {
+			var chunk ollama.ChatResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				return newDecodeError(ollamaProviderName, line, err)
+			}
+			// Ошибка посреди потока приходит строкой {"error": "..."}
+			if chunk.Error != "" {
+				return streamPayloadError(ollamaProviderName, line)
+			}
+			last = chunk
+
+			if chunk.Message.Content != "" {
+				accumulator.addText(chunk.Message.Content)
+				if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: chunk.Message.Content}) {
+					return ctx.Err()
+				}
+			}
+			for _, toolCall := range chunk.Message.ToolCalls {
+				delta, err := ollamaToolCallDelta(toolCall, toolCalls)
+				if err != nil {
+					return err
+				}
+				toolCalls++
+				accumulator.addToolCallDelta(delta)
+				if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(ollamaProviderName, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		result, err := p.finalResponse(accumulator, &last, toolCalls > 0)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// Embed возвращает эмбеддинги текстов через /api/embeddings (по одному запросу на текст).
+func (p *OllamaProvider) Embed(ctx context.Context, modelName entities.ModelName, inputs []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		response, err := p.post(ctx, "/api/embeddings", ollama.EmbeddingsRequest{Model: string(modelName), Prompt: input})
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", newTransportError(ollamaProviderName, err))
+		}
+
+		var embeddingResponse ollama.EmbeddingsResponse
+		if err := json.Unmarshal(body, &embeddingResponse); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(ollamaProviderName, body, err))
+		}
+		embeddings[i] = embeddingResponse.Embedding
+	}
+	return embeddings, nil
+}
+
+// GetModelInfo получает информацию об установленной модели; название без тега ищется с тегом latest.
+func (p *OllamaProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	if !strings.Contains(string(modelName), ":") {
+		if modelInfo := p.models.get(modelName + ollamaDefaultTag); modelInfo != nil {
+			return modelInfo, nil
+		}
+	}
+	return nil, newModelNotFoundError(ollamaProviderName, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех установленных моделях.
+func (p *OllamaProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список установленных моделей.
+func (p *OllamaProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
+}
+
+// buildRequest конвертирует сообщения и опции в запрос /api/chat.
+func (p *OllamaProvider) buildRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (*ollama.ChatRequest, error) {
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем изображения, только если про модель известно, что она их принимает или нет
+	if modelInfo, err := p.GetModelInfo(modelName); err == nil && len(modelInfo.InputModalities) > 0 {
+		if err := checkImageInput(ollamaProviderName, prepared, modelName, modelInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	chatMessages, err := p.convertToMessages(prepared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &ollama.ChatRequest{
+		Model:    string(modelName),
+		Messages: chatMessages,
+	}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert MCP tools to Ollama: %w", err)
+		}
+		request.Tools = make([]ollama.Tool, len(openaiTools))
+		for i, openaiTool := range openaiTools {
+			var description string
+			if openaiTool.Function.Description != nil {
+				description = *openaiTool.Function.Description
+			}
+			request.Tools[i] = ollama.Tool{
+				Type: openai.ToolTypeFunction,
+				Function: ollama.ToolFunction{
+					Name:        openaiTool.Function.Name,
+					Description: description,
+					Parameters:  openaiTool.Function.Parameters,
+				},
+			}
+		}
+	}
+
+	params := options.ExtractGenerationParams(opts)
+	if len(params.LogitBias) > 0 {
+		return nil, &UnsupportedParameterError{Provider: ollamaProviderName, Parameter: options.OptionTypeLogitBias, Reason: "not supported by Ollama API"}
+	}
+	request.Options = &ollama.Options{
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		NumPredict:       params.MaxTokens,
+		Stop:             params.Stop,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+	}
+
+	// Ollama принимает в format либо "json", либо JSON Schema ответа
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		request.Format = ollama.FormatJSON
+		if responseFormat.Type == options.ResponseFormatJSONSchema && responseFormat.Schema != nil {
+			request.Format = responseFormat.Schema
+		}
+	}
+
+	return request, nil
+}
+
+// convertToMessages конвертирует внутренние сообщения в формат Ollama.
+// Ollama не возвращает ID вызовов, поэтому результат инструмента связывается с вызовом по имени.
+func (p *OllamaProvider) convertToMessages(messages []*entities.Message) ([]ollama.Message, error) {
+	chatMessages := make([]ollama.Message, len(messages))
+	toolNames := make(map[string]string)
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			chatMessages[i] = ollama.Message{Role: ollama.RoleSystem, Content: msg.MessageText}
+		case entities.AuthorTypeUser, "":
+			chatMessages[i] = ollama.Message{Role: ollama.RoleUser, Content: msg.MessageText}
+			for j, image := range msg.Images {
+				data, err := ollamaImageData(image)
+				if err != nil {
+					return nil, fmt.Errorf("message at index %d: invalid image %d: %w", i, j, err)
+				}
+				chatMessages[i].Images = append(chatMessages[i].Images, data)
+			}
+		case entities.AuthorTypeRobot:
+			chatMessages[i] = ollama.Message{Role: ollama.RoleAssistant, Content: msg.MessageText}
+			if len(msg.ToolCalls) > 0 && len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+				return nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+			}
+			for j, mcpCall := range msg.ToolCalls {
+				openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+				if err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to Ollama: %w", j, err)
+				}
+				var arguments map[string]interface{}
+				if err := json.Unmarshal([]byte(openaiToolCall.Function.Arguments), &arguments); err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to Ollama: %w", j, err)
+				}
+				toolNames[msg.ToolCallIDs[j]] = openaiToolCall.Function.Name
+				chatMessages[i].ToolCalls = append(chatMessages[i].ToolCalls, ollama.ToolCall{
+					Function: ollama.ToolCallFunction{Name: openaiToolCall.Function.Name, Arguments: arguments},
+				})
+			}
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			chatMessages[i] = ollama.Message{
+				Role:     ollama.RoleTool,
+				Content:  msg.MessageText,
+				ToolName: toolNames[msg.ToolCallIDs[0]],
+			}
+		default:
+			return nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	return chatMessages, nil
+}
+
+// accumulate добавляет текст и вызовы инструментов из ответа в аккумулятор.
+// Возвращает новое количество вызовов инструментов.
+func (p *OllamaProvider) accumulate(accumulator *streamAccumulator, response *ollama.ChatResponse, toolCalls int) (int, error) {
+	accumulator.addText(response.Message.Content)
+	for _, toolCall := range response.Message.ToolCalls {
+		delta, err := ollamaToolCallDelta(toolCall, toolCalls)
+		if err != nil {
+			return toolCalls, err
+		}
+		accumulator.addToolCallDelta(delta)
+		toolCalls++
+	}
+	return toolCalls, nil
+}
+
+// finalResponse собирает итоговый ответ: причина завершения и токены берутся из последней строки (done=true).
+func (p *OllamaProvider) finalResponse(accumulator *streamAccumulator, last *ollama.ChatResponse, hasToolCalls bool) (*entities.ProviderMessageResponseDTO, error) {
+	// Ollama завершает ответ с вызовом инструментов причиной stop
+	finishReason := last.DoneReason
+	if hasToolCalls {
+		finishReason = entities.FinishReasonToolCalls
+	}
+	accumulator.setFinishReason(&finishReason)
+
+	result, err := accumulator.response(p.toolsMapper)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalTokens = last.PromptEvalCount + last.EvalCount
+	result.PriceInRubles = decimal.Zero
+	return result, nil
+}
+
+// post отправляет JSON запрос и возвращает ответ со статусом 200.
+func (p *OllamaProvider) post(ctx context.Context, path string, request interface{}) (*http.Response, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+path, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(ollamaProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		responseBody, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("API request failed: %w", newHTTPError(ollamaProviderName, response.StatusCode, response.Header, responseBody))
+	}
+
+	return response, nil
+}
+
+// getModels загружает список установленных моделей из /api/tags.
+func (p *OllamaProvider) getModels(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", newTransportError(ollamaProviderName, err))
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", newTransportError(ollamaProviderName, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed: %w", newHTTPError(ollamaProviderName, response.StatusCode, response.Header, body))
+	}
+
+	var tagsResponse ollama.TagsResponse
+	if err := json.Unmarshal(body, &tagsResponse); err != nil {
+		return fmt.Errorf("failed to decode response: %w", newDecodeError(ollamaProviderName, body, err))
+	}
+
+	models := make(map[entities.ModelName]*entities.ModelInfo)
+	for _, model := range tagsResponse.Models {
+		modelInfo := &entities.ModelInfo{
+			Name:          model.Name,
+			Alias:         entities.ModelName(model.Name),
+			PriceInRubles: decimal.Zero,
+		}
+		// Мультимодальные модели Ollama содержат vision энкодер семейства clip или mllama
+		for _, family := range model.Details.Families {
+			if family == "clip" || family == "mllama" {
+				modelInfo.InputModalities = []string{entities.ModalityText, entities.ModalityImage}
+			}
+		}
+		models[modelInfo.Alias] = modelInfo
+	}
+	p.models.replace(models)
+
+	return nil
+}
+
+// ollamaToolCallDelta конвертирует вызов инструмента Ollama в дельту с новым ID.
+func ollamaToolCallDelta(toolCall ollama.ToolCall, index int) (entities.ToolCallDelta, error) {
+	arguments := toolCall.Function.Arguments
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	argumentsJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return entities.ToolCallDelta{}, fmt.Errorf("failed to marshal tool call arguments: %w", err)
+	}
+	return entities.ToolCallDelta{
+		Index:          index,
+		ID:             newToolCallID(),
+		Name:           toolCall.Function.Name,
+		ArgumentsDelta: string(argumentsJSON),
+	}, nil
+}
+
+// ollamaImageData возвращает изображение в base64 без префикса: Ollama не загружает изображения по ссылке.
+func ollamaImageData(image entities.ImageContent) (string, error) {
+	imageURL, err := image.DataURL()
+	if err != nil {
+		return "", err
+	}
+	if len(image.Data) > 0 {
+		return base64.StdEncoding.EncodeToString(image.Data), nil
+	}
+	if !strings.HasPrefix(imageURL, "data:") {
+		return "", fmt.Errorf("ollama accepts only inline image data, not urls")
+	}
+	_, data, err := splitDataURI(imageURL)
+	return data, err
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/ollama"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// newFakeOllamaServer создает сервер Ollama API с одной моделью llama3.2:latest; /api/chat обрабатывает handler.
+func newFakeOllamaServer(t *testing.T, handler func(w http.ResponseWriter, request ollama.ChatRequest)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "llama3.2:latest", "model": "llama3.2:latest", "details": {"family": "llama", "families": ["llama"]}}]}`))
+		case "/api/embeddings":
+			var request ollama.EmbeddingsRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			if request.Model != "nomic-embed-text" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": "model \"` + request.Model + `\" not found, try pulling it first"}`))
+				return
+			}
+			w.Write([]byte(`{"embedding": [` + map[string]string{"a": "0.1, 0.2", "b": "0.3, 0.4"}[request.Prompt] + `]}`))
+		case "/api/chat":
+			var request ollama.ChatRequest
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			handler(w, request)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestOllamaProvider(t *testing.T, server *httptest.Server) *OllamaProvider {
+	p, err := NewOllamaProvider(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return p
+}
+
+func TestOllamaSendMessageWithTools(t *testing.T) {
+	var received ollama.ChatRequest
+	server := newFakeOllamaServer(t, func(w http.ResponseWriter, request ollama.ChatRequest) {
+		received = request
+		w.Write([]byte(`{
+			"model": "llama3.2:latest",
+			"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "Казань"}}}]},
+			"done": true,
+			"done_reason": "stop",
+			"prompt_eval_count": 40,
+			"eval_count": 12
+		}`))
+	})
+	defer server.Close()
+
+	p := newTestOllamaProvider(t, server)
+
+	modelInfo, err := p.GetModelInfo("llama3.2")
+	if err != nil || modelInfo.Alias != "llama3.2:latest" || !modelInfo.PriceInRubles.IsZero() {
+		t.Fatalf("Expected llama3.2:latest with zero price, got %+v, %v", modelInfo, err)
+	}
+
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeDeveloper},
+		{MessageText: "Погода в Москве?", AuthorType: entities.AuthorTypeUser, Images: []entities.ImageContent{{URL: "data:image/png;base64,cG5n"}}},
+		{
+			AuthorType:  entities.AuthorTypeRobot,
+			ToolCalls:   []mcpgo.CallToolRequest{{Params: mcpgo.CallToolParams{Name: "get_weather", Arguments: map[string]interface{}{"city": "Москва"}}}},
+			ToolCallIDs: []string{"call_1"},
+		},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+	}
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithString("city", mcpgo.Required()))
+
+	response, err := p.SendMessage(context.Background(), messages, "llama3.2", options.WithMCPTools([]mcpgo.Tool{tool}), options.WithMaxTokens(100), options.WithJSONObject())
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if received.Model != "llama3.2" || received.Stream {
+		t.Errorf("Unexpected model or stream flag: %s, %v", received.Model, received.Stream)
+	}
+	if len(received.Messages) != 4 || received.Messages[0].Role != ollama.RoleSystem {
+		t.Fatalf("Expected developer message as system, got %+v", received.Messages)
+	}
+	if images := received.Messages[1].Images; len(images) != 1 || images[0] != "cG5n" {
+		t.Errorf("Expected raw base64 image, got %v", images)
+	}
+	if calls := received.Messages[2].ToolCalls; len(calls) != 1 || calls[0].Function.Arguments["city"] != "Москва" {
+		t.Errorf("Expected assistant tool call, got %+v", received.Messages[2])
+	}
+	if result := received.Messages[3]; result.Role != ollama.RoleTool || result.ToolName != "get_weather" || result.Content != "+5" {
+		t.Errorf("Expected tool result with tool name, got %+v", result)
+	}
+	if len(received.Tools) != 1 || received.Tools[0].Type != openai.ToolTypeFunction || received.Tools[0].Function.Name != "get_weather" {
+		t.Errorf("Unexpected tools: %+v", received.Tools)
+	}
+	if received.Options == nil || received.Options.NumPredict == nil || *received.Options.NumPredict != 100 || received.Format != ollama.FormatJSON {
+		t.Errorf("Expected num_predict and json format, got %+v, %v", received.Options, received.Format)
+	}
+
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Params.Name != "get_weather" || response.ToolCallIDs[0] == "" {
+		t.Fatalf("Unexpected tool calls: %+v", response)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+	if response.TotalTokens != 52 || !response.PriceInRubles.IsZero() {
+		t.Errorf("Unexpected usage: tokens %d, price %s", response.TotalTokens, response.PriceInRubles)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "llama3.2", options.WithLogitBias(map[string]int{"1": 1})); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected ErrUnsupportedParameter for logit_bias, got %v", err)
+	}
+}
+
+func TestOllamaSendMessageStream(t *testing.T) {
+	server := newFakeOllamaServer(t, func(w http.ResponseWriter, request ollama.ChatRequest) {
+		if !request.Stream {
+			t.Errorf("Expected stream request")
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "При"}, "done": false}` + "\n"))
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "вет"}, "done": false}` + "\n"))
+		if request.Options != nil && request.Options.Temperature != nil {
+			w.Write([]byte(`{"error": "model runner has unexpectedly stopped"}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"message": {"role": "assistant", "content": ""}, "done": true, "done_reason": "length", "prompt_eval_count": 10, "eval_count": 5}` + "\n"))
+	})
+	defer server.Close()
+
+	p := newTestOllamaProvider(t, server)
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "llama3.2")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var text string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if text != "Привет" || final == nil || final.MessageText != "Привет" || final.TotalTokens != 15 {
+		t.Fatalf("Unexpected stream: text %q, final %+v", text, final)
+	}
+	if final.FinishReason == nil || *final.FinishReason != entities.FinishReasonLength {
+		t.Errorf("Expected finish reason length, got %v", final.FinishReason)
+	}
+
+	// Ошибка посреди потока приходит последним чанком
+	chunks, err = p.SendMessageStream(context.Background(), messages, "llama3.2", options.WithTemperature(0.5))
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Type == entities.StreamChunkError {
+			streamErr = chunk.Err
+		}
+	}
+	var providerErr *ProviderError
+	if !errors.As(streamErr, &providerErr) || providerErr.Provider != ollamaProviderName {
+		t.Errorf("Expected ProviderError from stream, got %v", streamErr)
+	}
+}
+
+func TestLocalProvidersEmbed(t *testing.T) {
+	server := newFakeOllamaServer(t, nil)
+	defer server.Close()
+
+	p := newTestOllamaProvider(t, server)
+	embeddings, err := p.Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() failed with error: %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[0][0] != 0.1 || embeddings[1][1] != 0.4 {
+		t.Errorf("Unexpected embeddings: %v", embeddings)
+	}
+	if _, err := p.Embed(context.Background(), "missing", []string{"a"}); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for missing model, got %v", err)
+	}
+
+	// llama.cpp server отдает эмбеддинги через OpenAI-совместимый /v1/embeddings
+	var received openai.EmbeddingRequest
+	llamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object": "list", "data": [{"id": "qwen2.5-7b-instruct-q4_k_m.gguf"}]}`))
+		case "/v1/embeddings":
+			json.NewDecoder(r.Body).Decode(&received)
+			w.Write([]byte(`{"data": [{"index": 1, "embedding": [0.3]}, {"index": 0, "embedding": [0.1]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer llamaServer.Close()
+
+	llama, err := NewLlamaCppProvider(llamaServer.URL + "/v1")
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if _, err := llama.GetModelInfo("qwen2.5-7b-instruct-q4_k_m.gguf"); err != nil {
+		t.Errorf("Expected model from /v1/models, got %v", err)
+	}
+	embeddings, err = llama.Embed(context.Background(), "qwen2.5-7b-instruct-q4_k_m.gguf", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() failed with error: %v", err)
+	}
+	if len(received.Input) != 2 || embeddings[0][0] != 0.1 || embeddings[1][0] != 0.3 {
+		t.Errorf("Expected embeddings ordered by index, got %v", embeddings)
+	}
+}
@@ -0,0 +1,708 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	_ Provider       = (*OpenAICompatibleProvider)(nil)
+	_ Namer          = (*OpenAICompatibleProvider)(nil)
+	_ ModelRefresher = (*OpenAICompatibleProvider)(nil)
+	_ Embedder       = (*OpenAICompatibleProvider)(nil)
+)
+
+// OpenAICompatibleConfig содержит параметры провайдера с OpenAI Chat Completions API
+// (OpenAI, vLLM, LocalAI, Together, DeepSeek, собственные шлюзы).
+type OpenAICompatibleConfig struct {
+	Name         string                        // Название провайдера для ошибок и логов
+	BaseURL      string                        // Базовый URL API, например https://api.openai.com/v1
+	APIKey       string                        // API ключ (пустой ключ не передается, например для локальных серверов)
+	ModelMapping map[entities.ModelName]string // Маппинг наших названий моделей на названия провайдера (nil - названия передаются как есть)
+	Quirks       OpenAICompatibleQuirks        // Отличия конкретного API от OpenAI
+	Options      []ClientOption                // Настройки HTTP клиента
+}
+
+// OpenAICompatibleQuirks описывает отличия конкретного API от OpenAI Chat Completions.
+type OpenAICompatibleQuirks struct {
+	ExtraBody          map[string]interface{}                               // Дополнительные поля тела запроса (перекрывают стандартные)
+	DeveloperRole      string                                               // Роль для сообщений developer (по умолчанию developer)
+	AuthHeader         string                                               // Заголовок с ключом без префикса Bearer (например, api-key); по умолчанию Authorization: Bearer
+	ModelsPath         string                                               // Путь списка моделей (по умолчанию /models)
+	SkipModelList      bool                                                 // Не загружать список моделей (API без /models)
+	ParseModels        func(body []byte) ([]OpenAICompatibleModel, error)   // Разбор списка моделей (по умолчанию {"data": [{"id": ...}]})
+	Cost               func(usage json.RawMessage) (decimal.Decimal, error) // Стоимость запроса в рублях из usage (по умолчанию 0)
+	DisableStreamUsage bool                                                 // Не отправлять stream_options.include_usage
+	RequireModalities  bool                                                 // Отклонять изображения, если модель не объявила вход image
+}
+
+// OpenAICompatibleModel описывает модель из списка моделей провайдера.
+type OpenAICompatibleModel struct {
+	ID              string          // Идентификатор модели у провайдера
+	Name            string          // Человекочитаемое название
+	PriceInRubles   decimal.Decimal // Цена модели в рублях
+	InputModalities []string        // Поддерживаемые входные модальности (nil - неизвестно)
+}
+
+// OpenAICompatibleProvider представляет провайдера с OpenAI-совместимым Chat Completions API.
+type OpenAICompatibleProvider struct {
+	name         string                        // Название провайдера
+	baseURL      string                        // Базовый URL для API запросов
+	apiKey       string                        // API ключ для аутентификации
+	modelMapping map[entities.ModelName]string // Маппинг названий моделей
+	quirks       OpenAICompatibleQuirks        // Отличия API от OpenAI
+	models       *modelCache                   // Кэш информации о моделях
+	toolsMapper  *mappers.ToolsMapper          // Маппер для конвертации инструментов
+	httpClient   *http.Client                  // HTTP клиент для запросов к API
+}
+
+// openAICompatibleResponse ответ Chat Completions; usage разбирается отдельно, чтобы достать стоимость.
+type openAICompatibleResponse struct {
+	Choices []openai.ChatCompletionChoice `json:"choices"`
+	Usage   json.RawMessage               `json:"usage"`
+}
+
+// openAICompatibleStreamResponse chunk потокового ответа Chat Completions.
+type openAICompatibleStreamResponse struct {
+	Choices []openai.ChatCompletionStreamChoice `json:"choices"`
+	Usage   json.RawMessage                     `json:"usage"`
+}
+
+// NewOpenAICompatibleProvider создает провайдера для OpenAI-совместимого API и загружает список моделей.
+func NewOpenAICompatibleProvider(cfg OpenAICompatibleConfig) (*OpenAICompatibleProvider, error) {
+	clientConfig, err := newClientConfig(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	if clientConfig.baseURL != "" {
+		cfg.BaseURL = clientConfig.baseURL
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base url is not set")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "OpenAICompatible"
+	}
+
+	httpClient, err := clientConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &OpenAICompatibleProvider{
+		name:         cfg.Name,
+		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:       cfg.APIKey,
+		modelMapping: cfg.ModelMapping,
+		quirks:       cfg.Quirks,
+		models:       newModelCache(),
+		toolsMapper:  mappers.NewToolsMapper(),
+		httpClient:   httpClient,
+	}
+
+	if !cfg.Quirks.SkipModelList {
+		if err := provider.getModels(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to get models: %w", err)
+		}
+	}
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// SendMessage отправляет сообщения в модель через Chat Completions API.
+func (p *OpenAICompatibleProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	request, err := p.buildChatRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, "/chat/completions", request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(p.name, err))
+	}
+
+	var chatResponse openAICompatibleResponse
+	if err := json.Unmarshal(responseBody, &chatResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(p.name, responseBody, err))
+	}
+
+	if len(chatResponse.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response: %w", newDecodeError(p.name, responseBody, nil))
+	}
+
+	choice := chatResponse.Choices[0]
+	result := &entities.ProviderMessageResponseDTO{
+		MessageText:  openAIContentText(choice.Message.Content),
+		FinishReason: mapFinishReason(choice.FinishReason),
+	}
+	if err := p.applyUsage(result, chatResponse.Usage); err != nil {
+		return nil, err
+	}
+
+	// Обрабатываем tool calls если они есть
+	if len(choice.Message.ToolCalls) > 0 {
+		mcpToolCalls := make([]mcpgo.CallToolRequest, len(choice.Message.ToolCalls))
+		toolCallIDs := make([]string, len(choice.Message.ToolCalls))
+
+		for i, toolCall := range choice.Message.ToolCalls {
+			mcpToolCall, err := p.toolsMapper.OpenAIToolCallToMCP(toolCall)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool call %d to MCP: %w", i, err)
+			}
+			mcpToolCalls[i] = mcpToolCall
+			toolCallIDs[i] = toolCall.ID
+		}
+
+		result.ToolCalls = mcpToolCalls
+		result.ToolCallIDs = toolCallIDs
+	}
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в модель и возвращает ответ потоком (SSE).
+func (p *OpenAICompatibleProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	request, err := p.buildChatRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	stream := true
+	request.Stream = &stream
+	if !p.quirks.DisableStreamUsage {
+		request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+
+	response, err := p.post(ctx, "/chat/completions", request, true)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage json.RawMessage
+
+		err := utils.ReadSSEData(response.Body, func(data []byte) error {
+			// Ошибка может прийти посреди потока отдельным событием {"error": ...}
+			if err := streamPayloadError(p.name, data); err != nil {
+				return err
+			}
+
+			var chunk openAICompatibleStreamResponse
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return newDecodeError(p.name, data, err)
+			}
+			if len(chunk.Usage) > 0 && string(chunk.Usage) != "null" {
+				usage = chunk.Usage
+			}
+
+			for _, choice := range chunk.Choices {
+				accumulator.setFinishReason(choice.FinishReason)
+
+				if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+					accumulator.addText(*choice.Delta.Content)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: *choice.Delta.Content}) {
+						return ctx.Err()
+					}
+				}
+
+				for i, toolCallDelta := range choice.Delta.ToolCalls {
+					delta := toolCallDeltaFromOpenAI(toolCallDelta, i)
+					accumulator.addToolCallDelta(delta)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+						return ctx.Err()
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(p.name, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		if err := p.applyUsage(result, usage); err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo получает информацию о конкретной модели из кэша.
+func (p *OpenAICompatibleProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(p.name, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *OpenAICompatibleProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список моделей.
+func (p *OpenAICompatibleProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
+}
+
+// Embed возвращает эмбеддинги текстов через Embeddings API в порядке входных текстов.
+func (p *OpenAICompatibleProvider) Embed(ctx context.Context, modelName entities.ModelName, inputs []string) ([][]float64, error) {
+	modelID, err := p.modelID(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, "/embeddings", openai.EmbeddingRequest{Model: modelID, Input: inputs}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(p.name, err))
+	}
+
+	var embeddingResponse openai.EmbeddingResponse
+	if err := json.Unmarshal(responseBody, &embeddingResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(p.name, responseBody, err))
+	}
+
+	// API может вернуть эмбеддинги не по порядку, поэтому раскладываем их по index
+	embeddings := make([][]float64, len(inputs))
+	for _, embedding := range embeddingResponse.Data {
+		if embedding.Index < 0 || embedding.Index >= len(inputs) {
+			return nil, fmt.Errorf("embedding index %d out of range: %w", embedding.Index, newDecodeError(p.name, responseBody, nil))
+		}
+		embeddings[embedding.Index] = embedding.Embedding
+	}
+	return embeddings, nil
+}
+
+// buildChatRequest конвертирует сообщения и опции в Chat Completions запрос.
+func (p *OpenAICompatibleProvider) buildChatRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (*openai.ChatCompletionRequest, error) {
+	modelID, err := p.modelID(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем заранее, что модель принимает изображения
+	if modelInfo := p.models.get(modelName); p.quirks.RequireModalities || (modelInfo != nil && len(modelInfo.InputModalities) > 0) {
+		if err := checkImageInput(p.name, prepared, modelName, modelInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	chatMessages, err := p.convertToChatMessages(prepared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+	request := openai.NewChatCompletionRequest(modelID, chatMessages)
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert MCP tools to OpenAI: %w", err)
+		}
+		request.Tools = openaiTools
+		request.ToolChoice = openai.ToolChoiceAuto
+	}
+
+	applyGenerationParams(request, options.ExtractGenerationParams(opts))
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		request.ResponseFormat = newResponseFormat(responseFormat)
+	}
+
+	return request, nil
+}
+
+// modelID возвращает название модели у провайдера.
+func (p *OpenAICompatibleProvider) modelID(modelName entities.ModelName) (string, error) {
+	if p.modelMapping == nil {
+		return string(modelName), nil
+	}
+	modelID, exists := p.modelMapping[modelName]
+	if !exists {
+		return "", newModelNotSupportedError(p.name, string(modelName))
+	}
+	return modelID, nil
+}
+
+// post отправляет JSON запрос и возвращает ответ со статусом 200.
+// Тело запроса дополняется полями ExtraBody.
+func (p *OpenAICompatibleProvider) post(ctx context.Context, path string, request interface{}, stream bool) (*http.Response, error) {
+	requestBody, err := p.requestBody(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+path, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	p.setAuth(req)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", newTransportError(p.name, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		responseBody, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("API request failed: %w", newHTTPError(p.name, response.StatusCode, response.Header, responseBody))
+	}
+
+	return response, nil
+}
+
+// requestBody сериализует запрос и добавляет поля ExtraBody.
+func (p *OpenAICompatibleProvider) requestBody(request interface{}) ([]byte, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil || len(p.quirks.ExtraBody) == 0 {
+		return requestBody, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(requestBody, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range p.quirks.ExtraBody {
+		fields[key] = value
+	}
+	return json.Marshal(fields)
+}
+
+// setAuth добавляет ключ API в заголовки запроса.
+func (p *OpenAICompatibleProvider) setAuth(req *http.Request) {
+	if p.apiKey == "" {
+		return
+	}
+	if p.quirks.AuthHeader != "" {
+		req.Header.Set(p.quirks.AuthHeader, p.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+}
+
+// applyUsage заполняет токены и стоимость из usage ответа.
+func (p *OpenAICompatibleProvider) applyUsage(result *entities.ProviderMessageResponseDTO, usage json.RawMessage) error {
+	if len(usage) == 0 || string(usage) == "null" {
+		return nil
+	}
+
+	var tokens openai.Usage
+	if err := json.Unmarshal(usage, &tokens); err != nil {
+		return fmt.Errorf("failed to unmarshal usage: %w", newDecodeError(p.name, usage, err))
+	}
+	result.TotalTokens = int64(tokens.TotalTokens)
+
+	if p.quirks.Cost != nil {
+		price, err := p.quirks.Cost(usage)
+		if err != nil {
+			return fmt.Errorf("failed to extract cost: %w", newDecodeError(p.name, usage, err))
+		}
+		result.PriceInRubles = price
+	}
+	return nil
+}
+
+// getModels загружает список моделей и заполняет кэш.
+// При заданном маппинге в кэш попадают только модели из маппинга.
+func (p *OpenAICompatibleProvider) getModels(ctx context.Context) error {
+	modelsPath := p.quirks.ModelsPath
+	if modelsPath == "" {
+		modelsPath = "/models"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+modelsPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setAuth(req)
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", newTransportError(p.name, err))
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", newTransportError(p.name, err))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed: %w", newHTTPError(p.name, response.StatusCode, response.Header, body))
+	}
+
+	parseModels := p.quirks.ParseModels
+	if parseModels == nil {
+		parseModels = parseOpenAIModels
+	}
+	providerModels, err := parseModels(body)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", newDecodeError(p.name, body, err))
+	}
+
+	models := make(map[entities.ModelName]*entities.ModelInfo)
+	for _, providerModel := range providerModels {
+		modelInfo := &entities.ModelInfo{
+			Name:            providerModel.Name,
+			Alias:           entities.ModelName(providerModel.ID),
+			PriceInRubles:   providerModel.PriceInRubles,
+			InputModalities: providerModel.InputModalities,
+		}
+		if modelInfo.Name == "" {
+			modelInfo.Name = providerModel.ID
+		}
+
+		if p.modelMapping == nil {
+			models[modelInfo.Alias] = modelInfo
+			continue
+		}
+		// Проверяем, есть ли эта модель в нашем маппинге
+		for ourModelName, providerModelID := range p.modelMapping {
+			if providerModel.ID == providerModelID {
+				mapped := *modelInfo
+				mapped.Alias = ourModelName
+				models[ourModelName] = &mapped
+			}
+		}
+	}
+	p.models.replace(models)
+
+	return nil
+}
+
+// parseOpenAIModels разбирает список моделей в формате OpenAI: {"data": [{"id": "..."}]}.
+func parseOpenAIModels(body []byte) ([]OpenAICompatibleModel, error) {
+	var modelsResponse struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return nil, err
+	}
+
+	models := make([]OpenAICompatibleModel, len(modelsResponse.Data))
+	for i, model := range modelsResponse.Data {
+		models[i] = OpenAICompatibleModel{ID: model.ID}
+	}
+	return models, nil
+}
+
+// convertToChatMessages конвертирует внутренние сообщения в формат OpenAI.
+func (p *OpenAICompatibleProvider) convertToChatMessages(messages []*entities.Message) ([]openai.ChatMessage, error) {
+	chatMessages := make([]openai.ChatMessage, len(messages))
+
+	for i, msg := range messages {
+		var role string
+		switch msg.AuthorType {
+		case entities.AuthorTypeUser, "": // пустой тип - сообщение пользователя, как раньше
+			role = openai.RoleUser
+			// Для сообщений с изображениями создаем мультимодальное сообщение
+			if len(msg.Images) > 0 {
+				contents, err := imageContentParts(msg)
+				if err != nil {
+					return nil, fmt.Errorf("message at index %d: %w", i, err)
+				}
+				chatMessages[i] = openai.NewMultimodalMessage(role, contents)
+				continue
+			}
+		case entities.AuthorTypeSystem:
+			role = openai.RoleSystem
+		case entities.AuthorTypeDeveloper:
+			role = openai.RoleDeveloper
+			if p.quirks.DeveloperRole != "" {
+				role = p.quirks.DeveloperRole
+			}
+		case entities.AuthorTypeRobot:
+			role = openai.RoleAssistant
+			chatMessages[i] = openai.NewTextMessage(role, msg.MessageText)
+			// Добавляем tool calls если они есть
+			if len(msg.ToolCalls) > 0 {
+				if len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+					return nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+				}
+				openaiToolCalls := make([]openai.ToolCall, len(msg.ToolCalls))
+				for j, mcpCall := range msg.ToolCalls {
+					openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+					if err != nil {
+						return nil, fmt.Errorf("failed to convert MCP tool call %d to OpenAI: %w", j, err)
+					}
+					openaiToolCall.ID = msg.ToolCallIDs[j]
+					openaiToolCalls[j] = openaiToolCall
+				}
+				chatMessages[i].ToolCalls = openaiToolCalls
+			}
+			continue
+		case entities.AuthorTypeTool:
+			// Для tool сообщений создаем специальное сообщение с tool_call_id
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			chatMessages[i] = openai.ChatMessage{
+				Role:       openai.RoleTool,
+				Content:    msg.MessageText,
+				ToolCallID: &msg.ToolCallIDs[0],
+			}
+			continue
+		default:
+			return nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+		chatMessages[i] = openai.NewTextMessage(role, msg.MessageText)
+	}
+
+	return chatMessages, nil
+}
+
+// openAIContentText извлекает текст из content ответа (строка или массив частей).
+func openAIContentText(content interface{}) string {
+	switch content := content.(type) {
+	case string:
+		return content
+	case []interface{}:
+		var text strings.Builder
+		for _, item := range content {
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				if itemType, ok := itemMap["type"].(string); ok && itemType == openai.ContentTypeText {
+					if itemText, ok := itemMap["text"].(string); ok {
+						text.WriteString(itemText)
+					}
+				}
+			}
+		}
+		return text.String()
+	default:
+		return ""
+	}
+}
+
+// imageContentParts собирает части мультимодального сообщения: текст и изображения.
+func imageContentParts(msg *entities.Message) ([]openai.ContentPart, error) {
+	contents := make([]openai.ContentPart, 0, len(msg.Images)+1)
+	if msg.MessageText != "" {
+		contents = append(contents, openai.NewTextContent(msg.MessageText))
+	}
+	for j, image := range msg.Images {
+		url, err := image.DataURL()
+		if err != nil {
+			return nil, fmt.Errorf("invalid image %d: %w", j, err)
+		}
+		var detail *string
+		if image.Detail != "" {
+			detail = &image.Detail
+		}
+		contents = append(contents, openai.NewImageURLContent(url, detail))
+	}
+	return contents, nil
+}
+
+// applyGenerationParams переносит параметры генерации в OpenAI запрос.
+// OpenAI формат поддерживает все параметры, поэтому ошибок нет.
+func applyGenerationParams(request *openai.ChatCompletionRequest, params options.GenerationParams) {
+	request.Temperature = params.Temperature
+	request.MaxTokens = params.MaxTokens
+	request.TopP = params.TopP
+	request.Seed = params.Seed
+	request.PresencePenalty = params.PresencePenalty
+	request.FrequencyPenalty = params.FrequencyPenalty
+	request.LogitBias = params.LogitBias
+	if len(params.Stop) > 0 {
+		request.Stop = params.Stop
+	}
+}
+
+// newResponseFormat конвертирует опцию формата ответа в OpenAI формат.
+func newResponseFormat(format options.ResponseFormatOption) *openai.ResponseFormat {
+	if format.Type != options.ResponseFormatJSONSchema {
+		return &openai.ResponseFormat{Type: format.Type}
+	}
+	strict := format.Strict
+	return &openai.ResponseFormat{
+		Type: openai.ResponseFormatJSONSchema,
+		JSONSchema: &openai.ResponseJSONSchema{
+			Name:   format.SchemaName,
+			Schema: format.Schema,
+			Strict: &strict,
+		},
+	}
+}
+
+// mapFinishReason маппит OpenAI finish reason в общие константы entities.
+func mapFinishReason(openaiReason *string) *string {
+	if openaiReason == nil {
+		return nil
+	}
+
+	var mappedReason string
+	switch *openaiReason {
+	case openai.FinishReasonStop:
+		mappedReason = entities.FinishReasonStop
+	case openai.FinishReasonLength:
+		mappedReason = entities.FinishReasonLength
+	case openai.FinishReasonToolCalls:
+		mappedReason = entities.FinishReasonToolCalls
+	case openai.FinishReasonContentFilter:
+		mappedReason = entities.FinishReasonContentFilter
+	default:
+		// Если неизвестная причина, возвращаем как есть
+		mappedReason = *openaiReason
+	}
+
+	return &mappedReason
+}
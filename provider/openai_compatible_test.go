@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/shopspring/decimal"
+)
+
+func TestOpenAICompatibleSendMessage(t *testing.T) {
+	var requestBody map[string]interface{}
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("api-key")
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object": "list", "data": [{"id": "deepseek-chat", "object": "model"}]}`))
+		case "/v1/chat/completions":
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &requestBody)
+			w.Write([]byte(`{
+				"choices": [{"index": 0, "message": {"role": "assistant", "content": "Привет!"}, "finish_reason": "stop"}],
+				"usage": {"prompt_tokens": 5, "completion_tokens": 3, "total_tokens": 8, "cost": 0.5}
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:    "DeepSeek",
+		BaseURL: server.URL + "/v1/",
+		APIKey:  "secret",
+		Quirks: OpenAICompatibleQuirks{
+			ExtraBody:  map[string]interface{}{"top_k": 20},
+			AuthHeader: "api-key",
+			Cost: func(usage json.RawMessage) (decimal.Decimal, error) {
+				var cost struct {
+					Cost float64 `json:"cost"`
+				}
+				err := json.Unmarshal(usage, &cost)
+				return decimal.NewFromFloat(cost.Cost), err
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	models, _ := p.ListModels()
+	if len(models) != 1 || models[0].Alias != "deepseek-chat" {
+		t.Fatalf("Expected model list to be loaded as is, got %+v", models)
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	response, err := p.SendMessage(context.Background(), messages, "deepseek-chat")
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if response.MessageText != "Привет!" || response.TotalTokens != 8 || !response.PriceInRubles.Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonStop {
+		t.Errorf("Expected finish reason stop, got %v", response.FinishReason)
+	}
+	if apiKey != "secret" {
+		t.Errorf("Expected key in api-key header, got %q", apiKey)
+	}
+	if requestBody["model"] != "deepseek-chat" || requestBody["top_k"] != float64(20) {
+		t.Errorf("Expected model and extra body fields in request, got %v", requestBody)
+	}
+}
+
+func TestOpenAICompatibleModelMappingAndStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header without API key")
+		}
+		if r.URL.Path == "/chat/completions" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\": [{\"index\": 0, \"delta\": {\"content\": \"При\"}}]}\n\n"))
+			w.Write([]byte("data: {\"choices\": [{\"index\": 0, \"delta\": {\"content\": \"вет\"}, \"finish_reason\": \"stop\"}]}\n\n"))
+			w.Write([]byte("data: {\"choices\": [], \"usage\": {\"total_tokens\": 12}}\n\n"))
+			w.Write([]byte("data: [DONE]\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		BaseURL:      server.URL,
+		ModelMapping: map[entities.ModelName]string{"llama-3-8b": "meta-llama/Meta-Llama-3-8B-Instruct"},
+		Quirks:       OpenAICompatibleQuirks{SkipModelList: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	if _, err := p.SendMessage(context.Background(), messages, "gpt-4o"); err == nil {
+		t.Error("Expected error for model outside of mapping")
+	}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "llama-3-8b")
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var text string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if text != "Привет" || final == nil || final.MessageText != "Привет" || final.TotalTokens != 12 {
+		t.Errorf("Unexpected stream result: text %q, final %+v", text, final)
+	}
+}
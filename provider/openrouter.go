@@ -2,49 +2,82 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/Murolando/m_ai_provider/entities"
 	"github.com/Murolando/m_ai_provider/internal/config"
 	internalEnt "github.com/Murolando/m_ai_provider/internal/entities"
-	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
 	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
 	"github.com/revrost/go-openrouter"
 	"github.com/shopspring/decimal"
 )
 
 // Константы для провайдера OpenRouter
 const (
-	openRouterProviderName     = "OpenRouter"
-	defaultUSDToRUBRateOnError = 80.0
+	openRouterProviderName = "OpenRouter"
 )
 
 // Проверяем, что OpenRouterProvider реализует интерфейс Provider
-var _ Provider = (*OpenRouterProvider)(nil)
+var (
+	_ Provider       = (*OpenRouterProvider)(nil)
+	_ Namer          = (*OpenRouterProvider)(nil)
+	_ ModelRefresher = (*OpenRouterProvider)(nil)
+)
 
 // OpenRouterProvider представляет провайдера для работы с OpenRouter API.
 type OpenRouterProvider struct {
-	client   *openrouter.Client                         // HTTP клиент для работы с OpenRouter API
-	modelMap map[entities.ModelName]*entities.ModelInfo // Кэш информации о моделях
+	client      *openrouter.Client   // HTTP клиент для работы с OpenRouter API
+	models      *modelCache          // Кэш информации о моделях
+	toolsMapper *mappers.ToolsMapper // Маппер для конвертации инструментов
+	rate        *exchangeRate        // Курс доллара к рублю для расчета цен
 }
 
 // NewOpenRouterProvider создает новый экземпляр OpenRouter провайдера.
 // token - API токен для аутентификации в OpenRouter
+// opts - настройки HTTP клиента (прокси, таймауты, заголовки, базовый URL)
 // Возвращает настроенный провайдер или ошибку при неудачной инициализации.
-func NewOpenRouterProvider(token string) (*OpenRouterProvider, error) {
+func NewOpenRouterProvider(token string, opts ...ClientOption) (*OpenRouterProvider, error) {
 	if token == "" {
 		return nil, fmt.Errorf("OPENROUTER_TOKEN is not set")
 	}
 
-	client := openrouter.NewClient(token)
+	httpConfig, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	rate, err := newExchangeRate(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Оборачиваем HTTP клиент, чтобы получить заголовки и тело ответа с ошибкой (Retry-After, код ошибки)
+	clientConfig := openrouter.DefaultConfig(token)
+	clientConfig.HTTPClient = &capturingDoer{next: httpClient}
+	if httpConfig.baseURL != "" {
+		clientConfig.BaseURL = httpConfig.baseURL
+	}
+	client := openrouter.NewClientWithConfig(*clientConfig)
 
 	provider := &OpenRouterProvider{
-		client:   client,
-		modelMap: make(map[entities.ModelName]*entities.ModelInfo),
+		client:      client,
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		rate:        rate,
 	}
 
-	if err := provider.getModels(); err != nil {
+	if err := provider.getModels(context.Background()); err != nil {
 		return nil, fmt.Errorf("failed to get models: %w", err)
 	}
 
@@ -52,54 +85,384 @@ func NewOpenRouterProvider(token string) (*OpenRouterProvider, error) {
 }
 
 // SendMessage отправляет сообщения в AI модель через OpenRouter API.
-func (p *OpenRouterProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, options ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
-	openRouterModel, exists := config.OpenRouterNamesMap[modelName]
-	if !exists {
-		return nil, fmt.Errorf("model %s not supported by %s provider", modelName, openRouterProviderName)
+func (p *OpenRouterProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	request, err := p.buildChatRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	message := utils.MakeRequestMessageString(messages)
-	response, err := p.client.CreateChatCompletion(ctx, openrouter.ChatCompletionRequest{
-		Model: openRouterModel,
-		Messages: []openrouter.ChatCompletionMessage{
-			openrouter.UserMessage(message),
-		},
-	})
+	requestCtx, capture := withResponseCapture(withRequestBody(ctx, request))
+	response, err := p.client.CreateChatCompletion(requestCtx, request.ChatCompletionRequest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", convertOpenRouterError(err, capture))
 	}
 
 	if len(response.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, fmt.Errorf("no choices in response: %w", newDecodeError(openRouterProviderName, nil, nil))
 	}
 
-	var priceInRubles decimal.Decimal
+	choice := response.Choices[0]
+	result := &entities.ProviderMessageResponseDTO{
+		MessageText:   contentText(choice.Message.Content),
+		PriceInRubles: p.usagePriceInRubles(response.Usage),
+	}
 	if response.Usage != nil {
-		costUSD := response.Usage.Cost
-		usdToRubRate, err := utils.GetUSDToRUBRate()
+		result.TotalTokens = int64(response.Usage.TotalTokens)
+	}
+	if choice.FinishReason != "" && choice.FinishReason != openrouter.FinishReasonNull {
+		finishReason := string(choice.FinishReason)
+		result.FinishReason = mapFinishReason(&finishReason)
+	}
+
+	// Обрабатываем tool calls если они есть
+	if len(choice.Message.ToolCalls) > 0 {
+		mcpToolCalls := make([]mcpgo.CallToolRequest, len(choice.Message.ToolCalls))
+		toolCallIDs := make([]string, len(choice.Message.ToolCalls))
+
+		for i, toolCall := range choice.Message.ToolCalls {
+			mcpToolCall, err := p.toolsMapper.OpenAIToolCallToMCP(openai.NewToolCall(toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments))
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool call %d to MCP: %w", i, err)
+			}
+			mcpToolCalls[i] = mcpToolCall
+			toolCallIDs[i] = toolCall.ID
+		}
+
+		result.ToolCalls = mcpToolCalls
+		result.ToolCallIDs = toolCallIDs
+	}
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в AI модель через OpenRouter API и возвращает ответ потоком.
+func (p *OpenRouterProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	request, err := p.buildChatRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.Stream = true
+	request.Usage = &openrouter.IncludeUsage{Include: true}
+
+	requestCtx, capture := withResponseCapture(withRequestBody(ctx, request))
+	stream, err := p.client.CreateChatCompletionStream(requestCtx, request.ChatCompletionRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", convertOpenRouterError(err, capture))
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer stream.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage *openrouter.Usage
+
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+				return
+			}
+			if response.Usage != nil {
+				usage = response.Usage
+			}
+
+			for _, choice := range response.Choices {
+				if choice.FinishReason != "" && choice.FinishReason != openrouter.FinishReasonNull {
+					finishReason := string(choice.FinishReason)
+					accumulator.setFinishReason(&finishReason)
+				}
+
+				if choice.Delta.Content != "" {
+					accumulator.addText(choice.Delta.Content)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: choice.Delta.Content}) {
+						return
+					}
+				}
+
+				for i, toolCall := range choice.Delta.ToolCalls {
+					delta := entities.ToolCallDelta{
+						Index:          i,
+						ID:             toolCall.ID,
+						Name:           toolCall.Function.Name,
+						ArgumentsDelta: toolCall.Function.Arguments,
+					}
+					if toolCall.Index != nil {
+						delta.Index = *toolCall.Index
+					}
+					accumulator.addToolCallDelta(delta)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+						return
+					}
+				}
+			}
+		}
+
+		// Библиотека OpenRouter закрывает поток без ошибки при отмене контекста
+		if ctx.Err() != nil {
+			sendStreamError(ctx, chunks, ctx.Err())
+			return
+		}
+		// и при событии с ошибкой посреди потока
+		if streamErr := capture.streamError(); streamErr != nil {
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", streamErr))
+			return
+		}
+
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		if usage != nil {
+			result.TotalTokens = int64(usage.TotalTokens)
+		}
+		result.PriceInRubles = p.usagePriceInRubles(usage)
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// openRouterChatRequest запрос к OpenRouter API. В go-openrouter параметры генерации - float32 с omitempty,
+// поэтому нулевые значения не отправляются; поля-указатели заменяют их при сериализации тела запроса.
+type openRouterChatRequest struct {
+	openrouter.ChatCompletionRequest
+	Temperature      *float32 `json:"temperature,omitempty"`       // Температура (0 отправляется)
+	TopP             *float32 `json:"top_p,omitempty"`             // Top-p (0 отправляется)
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`  // Штраф за присутствие (0 отправляется)
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"` // Штраф за частоту (0 отправляется)
+}
+
+// buildChatRequest конвертирует сообщения и опции в запрос к OpenRouter API.
+func (p *OpenRouterProvider) buildChatRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (*openRouterChatRequest, error) {
+	openRouterModel, exists := config.OpenRouterNamesMap[modelName]
+	if !exists {
+		return nil, newModelNotSupportedError(openRouterProviderName, string(modelName))
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем заранее, что модель принимает изображения
+	if err := checkImageInput(openRouterProviderName, prepared, modelName, p.models.get(modelName)); err != nil {
+		return nil, err
+	}
+
+	chatMessages, err := p.convertToChatMessages(prepared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &openRouterChatRequest{ChatCompletionRequest: openrouter.ChatCompletionRequest{
+		Model:    openRouterModel,
+		Messages: chatMessages,
+	}}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		// Конвертируем MCP tools в OpenAI формат, он совпадает с форматом OpenRouter
+		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
 		if err != nil {
-			usdToRubRate = defaultUSDToRUBRateOnError
+			return nil, fmt.Errorf("failed to convert MCP tools to OpenAI: %w", err)
+		}
+
+		tools := make([]openrouter.Tool, len(openaiTools))
+		for i, openaiTool := range openaiTools {
+			definition := &openrouter.FunctionDefinition{
+				Name:       openaiTool.Function.Name,
+				Parameters: openaiTool.Function.Parameters,
+			}
+			if openaiTool.Function.Description != nil {
+				definition.Description = *openaiTool.Function.Description
+			}
+			tools[i] = openrouter.Tool{Type: openrouter.ToolTypeFunction, Function: definition}
+		}
+
+		// Добавляем инструменты к запросу
+		request.Tools = tools
+		request.ToolChoice = openai.ToolChoiceAuto
+	}
+
+	if err := p.applyGenerationParams(request, options.ExtractGenerationParams(opts)); err != nil {
+		return nil, err
+	}
+
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		request.ResponseFormat = &openrouter.ChatCompletionResponseFormat{
+			Type: openrouter.ChatCompletionResponseFormatType(responseFormat.Type),
+		}
+		if responseFormat.Type == options.ResponseFormatJSONSchema {
+			schemaJSON, err := json.Marshal(responseFormat.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response schema: %w", err)
+			}
+			request.ResponseFormat.JSONSchema = &openrouter.ChatCompletionResponseFormatJSONSchema{
+				Name:   responseFormat.SchemaName,
+				Schema: json.RawMessage(schemaJSON),
+				Strict: responseFormat.Strict,
+			}
+		}
+	}
+
+	return request, nil
+}
+
+// convertToChatMessages конвертирует внутренние сообщения в формат OpenRouter с сохранением ролей.
+func (p *OpenRouterProvider) convertToChatMessages(messages []*entities.Message) ([]openrouter.ChatCompletionMessage, error) {
+	chatMessages := make([]openrouter.ChatCompletionMessage, len(messages))
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeUser, "":
+			chatMessages[i] = openrouter.UserMessage(msg.MessageText)
+			// Для сообщений с изображениями передаем массив частей
+			if len(msg.Images) > 0 {
+				contents, err := imageContentParts(msg)
+				if err != nil {
+					return nil, fmt.Errorf("message at index %d: %w", i, err)
+				}
+				parts := make([]openrouter.ChatMessagePart, len(contents))
+				for j, content := range contents {
+					parts[j] = openrouter.ChatMessagePart{Type: openrouter.ChatMessagePartType(content.Type)}
+					if content.Text != nil {
+						parts[j].Text = *content.Text
+					}
+					if content.ImageURL != nil {
+						parts[j].ImageURL = &openrouter.ChatMessageImageURL{URL: content.ImageURL.URL}
+						if content.ImageURL.Detail != nil {
+							parts[j].ImageURL.Detail = openrouter.ImageURLDetail(*content.ImageURL.Detail)
+						}
+					}
+				}
+				chatMessages[i].Content = openrouter.Content{Multi: parts}
+			}
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			// OpenRouter проксирует разные модели, поэтому developer передаем как system
+			chatMessages[i] = openrouter.SystemMessage(msg.MessageText)
+		case entities.AuthorTypeRobot:
+			chatMessages[i] = openrouter.AssistantMessage(msg.MessageText)
+			// Добавляем tool calls если они есть
+			if len(msg.ToolCalls) > 0 {
+				if len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+					return nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+				}
+				toolCalls := make([]openrouter.ToolCall, len(msg.ToolCalls))
+				for j, mcpCall := range msg.ToolCalls {
+					openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+					if err != nil {
+						return nil, fmt.Errorf("failed to convert MCP tool call %d to OpenAI: %w", j, err)
+					}
+					// Используем сохраненный ID
+					toolCalls[j] = openrouter.ToolCall{
+						ID:   msg.ToolCallIDs[j],
+						Type: openrouter.ToolTypeFunction,
+						Function: openrouter.FunctionCall{
+							Name:      openaiToolCall.Function.Name,
+							Arguments: openaiToolCall.Function.Arguments,
+						},
+					}
+				}
+				chatMessages[i].ToolCalls = toolCalls
+			}
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			chatMessages[i] = openrouter.ToolMessage(msg.ToolCallIDs[0], msg.MessageText)
+		default:
+			return nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	return chatMessages, nil
+}
+
+// applyGenerationParams переносит параметры генерации в запрос OpenRouter.
+func (p *OpenRouterProvider) applyGenerationParams(request *openRouterChatRequest, params options.GenerationParams) error {
+	if params.Temperature != nil {
+		temperature := float32(*params.Temperature)
+		request.Temperature = &temperature
+	}
+	if params.TopP != nil {
+		topP := float32(*params.TopP)
+		request.TopP = &topP
+	}
+	if params.MaxTokens != nil {
+		if *params.MaxTokens <= 0 {
+			return &UnsupportedParameterError{Provider: openRouterProviderName, Parameter: options.OptionTypeMaxTokens, Reason: "value must be positive"}
 		}
-		costRUB := costUSD * usdToRubRate
-		priceInRubles = decimal.NewFromFloat(costRUB).Round(3)
+		request.MaxTokens = *params.MaxTokens
+	}
+	if params.PresencePenalty != nil {
+		presencePenalty := float32(*params.PresencePenalty)
+		request.PresencePenalty = &presencePenalty
 	}
+	if params.FrequencyPenalty != nil {
+		frequencyPenalty := float32(*params.FrequencyPenalty)
+		request.FrequencyPenalty = &frequencyPenalty
+	}
+	request.Seed = params.Seed
+	request.Stop = params.Stop
+	request.LogitBias = params.LogitBias
+	return nil
+}
 
-	return &entities.ProviderMessageResponseDTO{
-		MessageText:   response.Choices[0].Message.Content.Text,
-		PriceInRubles: priceInRubles,
-	}, nil
+// Name возвращает название провайдера.
+func (p *OpenRouterProvider) Name() string {
+	return openRouterProviderName
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *OpenRouterProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново загружает список моделей и цены.
+func (p *OpenRouterProvider) RefreshModels(ctx context.Context) error {
+	return p.getModels(ctx)
 }
 
 // GetModelInfo получает информацию о конкретной модели из кэша.
 func (p *OpenRouterProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
-	if modelInfo, exists := p.modelMap[modelName]; exists {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
 		return modelInfo, nil
 	}
-	return nil, fmt.Errorf("model %s not found in %s provider", modelName, openRouterProviderName)
+	return nil, newModelNotFoundError(openRouterProviderName, string(modelName))
+}
+
+// contentText извлекает текст из ответа OpenRouter (строка или массив частей).
+func contentText(content openrouter.Content) string {
+	if len(content.Multi) == 0 {
+		return content.Text
+	}
+	var text strings.Builder
+	for _, part := range content.Multi {
+		if part.Type == openrouter.ChatMessagePartTypeText {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
 }
 
-// calculatePrice рассчитывает цену на основе параметров OpenRouter.
-func (p *OpenRouterProvider) calculatePrice(params internalEnt.PricingParams) (decimal.Decimal, error) {
+// usagePriceInRubles переводит стоимость запроса из usage OpenRouter (USD) в рубли.
+func (p *OpenRouterProvider) usagePriceInRubles(usage *openrouter.Usage) decimal.Decimal {
+	if usage == nil {
+		return decimal.Zero
+	}
+	costRUB := usage.Cost * p.rate.value()
+	return decimal.NewFromFloat(costRUB).Round(3)
+}
+
+// calculatePrice рассчитывает цену на основе параметров OpenRouter по курсу usdToRubRate.
+func (p *OpenRouterProvider) calculatePrice(params internalEnt.PricingParams, usdToRubRate float64) (decimal.Decimal, error) {
 	switch pricingParams := params.(type) {
 	case internalEnt.OpenRouterPricingParams:
 		var basePriceUSD float64
@@ -117,11 +480,6 @@ func (p *OpenRouterProvider) calculatePrice(params internalEnt.PricingParams) (d
 			basePriceUSD += imagePriceFloat
 		}
 
-		usdToRubRate, err := utils.GetUSDToRUBRate()
-		if err != nil {
-			usdToRubRate = 100.0
-		}
-
 		basePriceRUB := basePriceUSD * usdToRubRate
 		finalPriceDecimal := decimal.NewFromFloat(basePriceRUB)
 		return finalPriceDecimal.Ceil(), nil
@@ -131,13 +489,17 @@ func (p *OpenRouterProvider) calculatePrice(params internalEnt.PricingParams) (d
 }
 
 // getModels получает все модели от OpenRouter API и заполняет кэш моделей.
-func (p *OpenRouterProvider) getModels() error {
-	ctx := context.Background()
-	models, err := p.client.ListModels(ctx)
+func (p *OpenRouterProvider) getModels(ctx context.Context) error {
+	requestCtx, capture := withResponseCapture(ctx)
+	models, err := p.client.ListModels(requestCtx)
 	if err != nil {
-		return fmt.Errorf("failed to list models: %w", err)
+		return fmt.Errorf("failed to list models: %w", convertOpenRouterError(err, capture))
 	}
 
+	// Курс запрашивается один раз на загрузку моделей, стоимость запросов считается по сохраненному курсу
+	usdToRubRate := p.rate.refresh(ctx)
+
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo)
 	for _, model := range models {
 		for ourModelName, openrouterModelID := range config.OpenRouterNamesMap {
 			if model.ID == openrouterModelID {
@@ -147,21 +509,23 @@ func (p *OpenRouterProvider) getModels() error {
 					RequestPrice:    model.Pricing.Request,
 					ImagePrice:      model.Pricing.Image,
 				}
-				price, err := p.calculatePrice(pricingParams)
+				price, err := p.calculatePrice(pricingParams, usdToRubRate)
 				if err != nil {
 					// Если ошибка расчета, используем нулевую цену
 					price = decimal.Zero
 				}
 
-				p.modelMap[ourModelName] = &entities.ModelInfo{
-					Name:          model.Name,
-					Alias:         ourModelName,
-					PriceInRubles: price,
+				modelsInfo[ourModelName] = &entities.ModelInfo{
+					Name:            model.Name,
+					Alias:           ourModelName,
+					PriceInRubles:   price,
+					InputModalities: model.Architecture.InputModalities,
 				}
 				break
 			}
 		}
 	}
 
+	p.models.replace(modelsInfo)
 	return nil
 }
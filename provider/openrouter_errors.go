@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/revrost/go-openrouter"
+)
+
+// responseCaptureKey ключ контекста, по которому capturingDoer находит responseCapture запроса.
+type responseCaptureKey struct{}
+
+// responseCapture сохраняет данные ответа, которые библиотека go-openrouter не отдает наружу:
+// заголовки и тело ответа с ошибкой, а также ошибку, пришедшую посреди потока.
+type responseCapture struct {
+	mu        sync.Mutex
+	header    http.Header    // Заголовки ответа с ошибкой
+	body      []byte         // Тело ответа с ошибкой
+	streamErr *ProviderError // Ошибка из события потока {"error": ...}
+}
+
+// withResponseCapture добавляет в контекст responseCapture для одного запроса.
+func withResponseCapture(ctx context.Context) (context.Context, *responseCapture) {
+	capture := &responseCapture{}
+	return context.WithValue(ctx, responseCaptureKey{}, capture), capture
+}
+
+// streamError возвращает ошибку, пришедшую посреди потока, если она была.
+func (c *responseCapture) streamError() *ProviderError {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.streamErr
+}
+
+// requestBodyKey ключ контекста, по которому capturingDoer находит тело запроса вместо сериализованного go-openrouter.
+type requestBodyKey struct{}
+
+// withRequestBody добавляет в контекст запрос, который capturingDoer отправит телом вместо запроса go-openrouter.
+func withRequestBody(ctx context.Context, body interface{}) context.Context {
+	return context.WithValue(ctx, requestBodyKey{}, body)
+}
+
+// capturingDoer оборачивает HTTP клиент go-openrouter: подменяет тело запроса из контекста
+// и заполняет responseCapture из контекста запроса.
+type capturingDoer struct {
+	next openrouter.HTTPDoer
+}
+
+// Do выполняет запрос и сохраняет данные ответа в responseCapture, если он есть в контексте.
+func (d *capturingDoer) Do(req *http.Request) (*http.Response, error) {
+	if body := req.Context().Value(requestBodyKey{}); body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	}
+
+	response, err := d.next.Do(req)
+	if err != nil {
+		return response, err
+	}
+
+	capture, ok := req.Context().Value(responseCaptureKey{}).(*responseCapture)
+	if !ok {
+		return response, nil
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		capture.mu.Lock()
+		capture.header = response.Header
+		capture.body = body
+		capture.mu.Unlock()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		return response, nil
+	}
+
+	response.Body = &streamErrorReader{ReadCloser: response.Body, providerName: openRouterProviderName, capture: capture}
+	return response, nil
+}
+
+// streamErrorReader просматривает строки SSE потока и запоминает событие с ошибкой.
+// go-openrouter разбирает такое событие как пустой чанк и не сообщает об ошибке.
+type streamErrorReader struct {
+	io.ReadCloser
+	providerName string
+	capture      *responseCapture
+	line         []byte
+}
+
+// Read читает данные из тела ответа и проверяет завершенные строки.
+func (r *streamErrorReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	data := p[:n]
+	for len(data) > 0 {
+		newline := bytes.IndexByte(data, '\n')
+		if newline < 0 {
+			r.line = append(r.line, data...)
+			break
+		}
+		r.line = append(r.line, data[:newline]...)
+		r.checkLine()
+		r.line = r.line[:0]
+		data = data[newline+1:]
+	}
+	return n, err
+}
+
+// checkLine проверяет строку data: на наличие ошибки.
+func (r *streamErrorReader) checkLine() {
+	line := bytes.TrimSpace(r.line)
+	if !bytes.HasPrefix(line, []byte("data:")) || !bytes.Contains(line, []byte(`"error"`)) {
+		return
+	}
+	payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if err := streamPayloadError(r.providerName, payload); err != nil {
+		r.capture.mu.Lock()
+		r.capture.streamErr = err
+		r.capture.mu.Unlock()
+	}
+}
+
+// convertOpenRouterError приводит ошибку go-openrouter к *ProviderError.
+func convertOpenRouterError(err error, capture *responseCapture) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var header http.Header
+	var body []byte
+	if capture != nil {
+		capture.mu.Lock()
+		header, body = capture.header, capture.body
+		capture.mu.Unlock()
+	}
+
+	var apiErr *openrouter.APIError
+	if errors.As(err, &apiErr) {
+		code := codeString(apiErr.Code)
+		return &ProviderError{
+			Kind:       classifyError(apiErr.HTTPStatusCode, code, apiErr.Message),
+			Provider:   openRouterProviderName,
+			StatusCode: apiErr.HTTPStatusCode,
+			Code:       code,
+			Message:    apiErr.Message,
+			RetryAfter: parseRetryAfter(header),
+			Body:       body,
+			Err:        err,
+		}
+	}
+
+	var requestErr *openrouter.RequestError
+	if errors.As(err, &requestErr) {
+		providerErr := newHTTPError(openRouterProviderName, requestErr.HTTPStatusCode, header, body)
+		providerErr.Err = err
+		return providerErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return newDecodeError(openRouterProviderName, body, err)
+	}
+
+	return newTransportError(openRouterProviderName, err)
+}
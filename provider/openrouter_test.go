@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/revrost/go-openrouter"
+	"github.com/shopspring/decimal"
+)
+
+func TestOpenRouterConvertToChatMessages(t *testing.T) {
+	p := &OpenRouterProvider{models: newModelCache(), toolsMapper: mappers.NewToolsMapper()}
+
+	toolCall := mcpgo.CallToolRequest{}
+	toolCall.Params.Name = "get_weather"
+	toolCall.Params.Arguments = map[string]interface{}{"location": "Moscow"}
+
+	chatMessages, err := p.convertToChatMessages([]*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser},
+		{AuthorType: entities.AuthorTypeRobot, ToolCalls: []mcpgo.CallToolRequest{toolCall}, ToolCallIDs: []string{"call_1"}},
+		{MessageText: "+20", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+
+	expectedRoles := []string{openrouter.ChatMessageRoleSystem, openrouter.ChatMessageRoleUser, openrouter.ChatMessageRoleAssistant, openrouter.ChatMessageRoleTool}
+	for i, role := range expectedRoles {
+		if chatMessages[i].Role != role {
+			t.Errorf("Expected message %d role '%s', got '%s'", i, role, chatMessages[i].Role)
+		}
+	}
+
+	assistant := chatMessages[2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_1" || assistant.ToolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("Expected assistant tool call call_1/get_weather, got %+v", assistant.ToolCalls)
+	}
+	if assistant.ToolCalls[0].Function.Arguments != `{"location":"Moscow"}` {
+		t.Errorf("Expected tool call arguments to round-trip, got %s", assistant.ToolCalls[0].Function.Arguments)
+	}
+
+	if chatMessages[3].ToolCallID != "call_1" || chatMessages[3].Content.Text != "+20" {
+		t.Errorf("Expected tool result for call_1, got %+v", chatMessages[3])
+	}
+}
+
+func TestOpenRouterBuildChatRequestTools(t *testing.T) {
+	p := &OpenRouterProvider{models: newModelCache(), toolsMapper: mappers.NewToolsMapper()}
+
+	tool := mcpgo.NewTool("get_weather",
+		mcpgo.WithDescription("Get the current weather"),
+		mcpgo.WithString("location", mcpgo.Required()),
+	)
+
+	request, err := p.buildChatRequest(
+		[]*entities.Message{{MessageText: "Какая погода?", AuthorType: entities.AuthorTypeUser}},
+		"glm-4-5-air",
+		[]options.SendMessageOption{options.WithMCPTools([]mcpgo.Tool{tool})},
+	)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+
+	if len(request.Tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(request.Tools))
+	}
+	if request.Tools[0].Type != openrouter.ToolTypeFunction || request.Tools[0].Function.Name != "get_weather" {
+		t.Errorf("Expected function tool 'get_weather', got %+v", request.Tools[0])
+	}
+	if request.Tools[0].Function.Description != "Get the current weather" {
+		t.Errorf("Expected tool description, got '%s'", request.Tools[0].Function.Description)
+	}
+	if request.ToolChoice != "auto" {
+		t.Errorf("Expected tool choice 'auto', got %v", request.ToolChoice)
+	}
+}
+
+func TestOpenRouterApplyGenerationParams(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/models") {
+			w.Write([]byte(`{"data": []}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Привет"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	p, err := NewOpenRouterProvider("token", WithBaseURL(server.URL), WithUSDToRUBRate(FixedUSDToRUBRate(80)))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	// Нулевые параметры генерации отправляются, хотя go-openrouter пропускает нулевые значения
+	_, err = p.SendMessage(context.Background(),
+		[]*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}},
+		"glm-4-5-air",
+		options.WithTemperature(0), options.WithTopP(0), options.WithMaxTokens(100),
+		options.WithPresencePenalty(0), options.WithFrequencyPenalty(0),
+	)
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	for field, expected := range map[string]float64{"temperature": 0, "top_p": 0, "max_tokens": 100, "presence_penalty": 0, "frequency_penalty": 0} {
+		if value, ok := body[field].(float64); !ok || value != expected {
+			t.Errorf("Expected %s %v in request, got %v", field, expected, body[field])
+		}
+	}
+	if body["model"] != "z-ai/glm-4.5-air:free" || body["messages"] == nil {
+		t.Errorf("Expected model and messages in request, got %v", body)
+	}
+
+	_, err = p.buildChatRequest(
+		[]*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}},
+		"glm-4-5-air",
+		[]options.SendMessageOption{options.WithMaxTokens(0)},
+	)
+	var paramErr *UnsupportedParameterError
+	if !errors.As(err, &paramErr) || paramErr.Parameter != options.OptionTypeMaxTokens || !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected UnsupportedParameterError for max_tokens, got %v", err)
+	}
+}
+
+func TestOpenRouterExchangeRateFetchedOnRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/models") {
+			w.Write([]byte(`{"data": [{"id": "z-ai/glm-4.5-air:free", "name": "GLM 4.5 Air", "pricing": {"prompt": "0.5", "completion": "1"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}], "usage": {"total_tokens": 20, "cost": 0.01}}`))
+	}))
+	defer server.Close()
+
+	calls := 0
+	rate := func(ctx context.Context) (float64, error) {
+		calls++
+		return 100, nil
+	}
+	p, err := NewOpenRouterProvider("token", WithBaseURL(server.URL), WithUSDToRUBRate(rate))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if modelInfo, err := p.GetModelInfo("glm-4-5-air"); err != nil || !modelInfo.PriceInRubles.Equal(decimal.NewFromInt(150)) {
+		t.Errorf("Expected model priced at 150 rubles, got %+v (%v)", modelInfo, err)
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	for i := 0; i < 2; i++ {
+		response, err := p.SendMessage(context.Background(), messages, "glm-4-5-air")
+		if err != nil || !response.PriceInRubles.Equal(decimal.NewFromInt(1)) {
+			t.Fatalf("Expected price by cached rate, got %+v (%v)", response, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected rate to be fetched once on construction, got %d calls", calls)
+	}
+	if err := p.RefreshModels(context.Background()); err != nil || calls != 2 {
+		t.Errorf("Expected RefreshModels to fetch rate again, got %d calls (%v)", calls, err)
+	}
+}
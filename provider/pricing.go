@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// exchangeRateTimeout ограничивает запрос курса к ЦБ РФ, чтобы загрузка моделей не зависала.
+	exchangeRateTimeout = 10 * time.Second
+	// defaultUSDToRUBRateOnError курс доллара к рублю, если курс еще ни разу не удалось получить.
+	defaultUSDToRUBRateOnError = 80.0
+)
+
+// tokenPrice цена модели в долларах за миллион токенов.
+type tokenPrice struct {
+	input  float64 // Цена миллиона входных токенов
+	output float64 // Цена миллиона токенов ответа
+}
+
+// modelPrice связывает префикс ID модели с ценой.
+type modelPrice struct {
+	prefix string
+	price  tokenPrice
+}
+
+// findTokenPrice находит цену модели по первому подходящему префиксу ID.
+// Более точные префиксы в списке должны идти раньше общих.
+func findTokenPrice(prices []modelPrice, modelID string) (tokenPrice, bool) {
+	for _, entry := range prices {
+		if strings.HasPrefix(modelID, entry.prefix) {
+			return entry.price, true
+		}
+	}
+	return tokenPrice{}, false
+}
+
+// priceInRubles рассчитывает цену модели для ModelInfo: сумма цен за миллион входных и выходных токенов.
+func (p tokenPrice) priceInRubles(usdToRubRate float64) decimal.Decimal {
+	// Округляем вверх до целого рубля
+	return decimal.NewFromFloat((p.input + p.output) * usdToRubRate).Ceil()
+}
+
+// USDToRUBRateFunc возвращает курс доллара к рублю. Задается через WithUSDToRUBRate.
+type USDToRUBRateFunc func(ctx context.Context) (float64, error)
+
+// FixedUSDToRUBRate возвращает источник с постоянным курсом (например, для тестов или внутреннего курса компании).
+func FixedUSDToRUBRate(rate float64) USDToRUBRateFunc {
+	return func(ctx context.Context) (float64, error) {
+		return rate, nil
+	}
+}
+
+// exchangeRate курс доллара к рублю для провайдеров с долларовыми ценами.
+// Курс запрашивается при загрузке моделей (создание провайдера и RefreshModels),
+// а стоимость запросов считается по сохраненному курсу без обращения к сети.
+type exchangeRate struct {
+	source USDToRUBRateFunc
+
+	mu   sync.RWMutex
+	rate float64 // Последний полученный курс (0 - курс еще не получен)
+}
+
+// newExchangeRate создает курс из источника WithUSDToRUBRate или, если он не задан,
+// из курса ЦБ РФ через HTTP клиент провайдера без дополнительных заголовков.
+func newExchangeRate(config *clientConfig) (*exchangeRate, error) {
+	if config.usdToRUBRate != nil {
+		return &exchangeRate{source: config.usdToRUBRate}, nil
+	}
+
+	// Заголовки провайдера (например, ключ API) не должны уходить на сторонний сервис курсов валют
+	rateConfig := *config
+	rateConfig.headers = nil
+	client, err := rateConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return &exchangeRate{source: func(ctx context.Context) (float64, error) {
+		ctx, cancel := context.WithTimeout(ctx, exchangeRateTimeout)
+		defer cancel()
+		return utils.GetUSDToRUBRateFromURLContext(ctx, client, utils.CBRDailyRatesURL)
+	}}, nil
+}
+
+// refresh запрашивает курс у источника и сохраняет его. Если курс получить не удалось,
+// остается предыдущий курс, а до первого успешного запроса - defaultUSDToRUBRateOnError.
+func (r *exchangeRate) refresh(ctx context.Context) float64 {
+	rate, err := r.source(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil && rate > 0 {
+		r.rate = rate
+	}
+	if r.rate == 0 {
+		return defaultUSDToRUBRateOnError
+	}
+	return r.rate
+}
+
+// value возвращает сохраненный курс без обращения к источнику.
+func (r *exchangeRate) value() float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.rate == 0 {
+		return defaultUSDToRUBRateOnError
+	}
+	return r.rate
+}
+
+// rublePrice цена модели в рублях за миллион токенов по префиксу ID.
+// Российские провайдеры тарифицируют входные токены и токены ответа одинаково, поэтому цена одна.
+type rublePrice struct {
+	prefix     string
+	perMillion float64
+}
+
+// findRublePrice находит рублевую цену модели по первому подходящему префиксу ID.
+// Более точные префиксы в списке должны идти раньше общих.
+func findRublePrice(prices []rublePrice, modelID string) (rublePrice, bool) {
+	for _, entry := range prices {
+		if strings.HasPrefix(modelID, entry.prefix) {
+			return entry, true
+		}
+	}
+	return rublePrice{}, false
+}
+
+// priceInRubles рассчитывает цену модели для ModelInfo так же, как для долларовых цен:
+// сумма цен за миллион входных и выходных токенов.
+func (p rublePrice) priceInRubles() decimal.Decimal {
+	return decimal.NewFromFloat(p.perMillion * 2).Ceil()
+}
+
+// cost рассчитывает стоимость запроса в рублях по количеству тарифицируемых токенов.
+func (p rublePrice) cost(tokens int64) decimal.Decimal {
+	return decimal.NewFromFloat(float64(tokens) * p.perMillion / 1_000_000).Round(3)
+}
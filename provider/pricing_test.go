@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExchangeRate(t *testing.T) {
+	calls := 0
+	rates := []float64{0, 95, 0}
+	errs := []error{errors.New("offline"), nil, errors.New("offline")}
+	rate := &exchangeRate{source: func(ctx context.Context) (float64, error) {
+		calls++
+		return rates[calls-1], errs[calls-1]
+	}}
+
+	// До первого успешного запроса используется курс по умолчанию
+	if value := rate.refresh(context.Background()); value != defaultUSDToRUBRateOnError {
+		t.Errorf("Expected default rate before first success, got %v", value)
+	}
+	if value := rate.refresh(context.Background()); value != 95 || rate.value() != 95 {
+		t.Errorf("Expected fetched rate 95, got %v", value)
+	}
+	// При ошибке остается предыдущий курс
+	if value := rate.refresh(context.Background()); value != 95 {
+		t.Errorf("Expected previous rate after error, got %v", value)
+	}
+	rate.value()
+	if calls != 3 {
+		t.Errorf("Expected value not to call source, got %d calls", calls)
+	}
+}
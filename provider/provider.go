@@ -5,16 +5,15 @@ import (
 	"context"
 
 	"github.com/Murolando/m_ai_provider/entities"
-	int_entities "github.com/Murolando/m_ai_provider/internal/entities"
 	"github.com/Murolando/m_ai_provider/options"
-	"github.com/shopspring/decimal"
 )
 
 // Provider представляет интерфейс для работы с AI провайдерами.
 // Провайдер - проводник до модели, будь то владелец модели или другой ai-hub.
+// Интерфейс можно реализовать вне пакета и зарегистрировать через Register.
 //
 // Поддерживаемые провайдеры:
-//   - openrouter - https://openrouter.ai/ - txt
+//   - openrouter - https://openrouter.ai/ - txt, mcp
 //   - hydraai - https://hydraai.app/ - txt, mcp
 type Provider interface {
 	// SendMessage отправляет сообщения в AI модель через провайдера.
@@ -25,18 +24,69 @@ type Provider interface {
 	// Возвращает ответ от модели с текстом, количеством токенов, стоимостью и возможными tool calls
 	SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, options ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error)
 
+	// SendMessageStream отправляет сообщения в AI модель и возвращает ответ потоком.
+	// Параметры совпадают с SendMessage.
+	// Возвращает канал chunks: части текста, части вызовов инструментов и финальный chunk
+	// с токенами, стоимостью и FinishReason (или chunk с ошибкой). Канал закрывается после
+	// последнего chunk или отмены ctx. Ошибка возвращается, если поток не удалось открыть.
+	SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, options ...options.SendMessageOption) (<-chan entities.StreamChunk, error)
+
 	// GetModelInfo получает информацию о конкретной модели.
 	// modelName - название модели для получения информации
 	// Возвращает структуру с названием, алиасом и ценой модели в рублях
 	GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error)
 
-	// getModels загружает и кэширует список доступных моделей от провайдера.
-	// Приватный метод для внутреннего использования провайдером.
-	// Возвращает ошибку если не удалось получить список моделей
-	getModels() error
+	// ListModels возвращает информацию обо всех моделях, доступных у провайдера.
+	ListModels() ([]*entities.ModelInfo, error)
+}
+
+// Namer реализуют провайдеры, которые сообщают свое название (например, для логов и ошибок).
+type Namer interface {
+	// Name возвращает название провайдера.
+	Name() string
+}
 
-	// calculatePrice рассчитывает цену на основе переданных параметров.
-	// params - параметры для расчета цены (поддерживает разные типы через интерфейс PricingParams)
-	// Возвращает цену в рублях и ошибку если расчет невозможен
-	calculatePrice(params int_entities.PricingParams) (decimal.Decimal, error)
+// ModelRefresher реализуют провайдеры, которые умеют перезагружать список моделей и цены.
+type ModelRefresher interface {
+	// RefreshModels заново загружает список моделей от провайдера и обновляет кэш.
+	RefreshModels(ctx context.Context) error
+}
+
+// Embedder реализуют провайдеры, которые умеют строить эмбеддинги текстов.
+type Embedder interface {
+	// Embed возвращает векторы эмбеддингов для текстов в том же порядке.
+	Embed(ctx context.Context, modelName entities.ModelName, inputs []string) ([][]float64, error)
+}
+
+// HealthReporter реализуют провайдеры, которые отслеживают доступность бэкендов (например, CircuitBreakerProvider).
+type HealthReporter interface {
+	// Health возвращает снимок состояния провайдера и его моделей.
+	Health() []HealthStatus
+}
+
+// Wrapper реализуют обертки над провайдером (например, RetryProvider).
+// Через Unwrap функция Capability находит возможности исходного провайдера.
+type Wrapper interface {
+	// Unwrap возвращает обернутый провайдер.
+	Unwrap() Provider
+}
+
+// Capability ищет у провайдера или у обернутых им провайдеров реализацию интерфейса T.
+//
+//	if refresher, ok := provider.Capability[provider.ModelRefresher](pr); ok {
+//	    err = refresher.RefreshModels(ctx)
+//	}
+func Capability[T any](p Provider) (T, bool) {
+	for p != nil {
+		if capability, ok := p.(T); ok {
+			return capability, true
+		}
+		wrapper, ok := p.(Wrapper)
+		if !ok {
+			break
+		}
+		p = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
 }
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Названия встроенных провайдеров в реестре.
+const (
+	HydraAIName    = "hydraai"
+	OpenRouterName = "openrouter"
+	AnthropicName  = "anthropic"
+	GeminiName     = "gemini"
+	OllamaName     = "ollama"
+	LlamaCppName   = "llamacpp"
+	DefaultName    = "default"
+	// OpenAICompatibleName провайдер с OpenAI-совместимым API; Config.Extra["name"] задает его название.
+	OpenAICompatibleName = "openai-compatible"
+	// GigaChatName провайдер GigaChat; Config.APIKey - ключ авторизации, Config.Extra["scope"] - версия API.
+	GigaChatName = "gigachat"
+	// YandexGPTName провайдер YandexGPT; Config.APIKey - авторизованный ключ сервисного аккаунта (JSON),
+	// Config.Extra["folder_id"] - ID каталога.
+	YandexGPTName = "yandexgpt"
+)
+
+// Config содержит параметры создания провайдера через реестр.
+type Config struct {
+	APIKey  string            // API ключ провайдера
+	BaseURL string            // Базовый URL API (если провайдер его поддерживает)
+	Options []ClientOption    // Настройки HTTP клиента
+	Extra   map[string]string // Дополнительные параметры, специфичные для провайдера
+}
+
+// Factory создает провайдера по конфигурации.
+type Factory func(cfg Config) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+func init() {
+	Register(HydraAIName, func(cfg Config) (Provider, error) {
+		p, err := NewHydraAIProvider(cfg.APIKey, cfg.BaseURL, cfg.Options...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(OpenRouterName, func(cfg Config) (Provider, error) {
+		opts := cfg.Options
+		if cfg.BaseURL != "" {
+			opts = append([]ClientOption{WithBaseURL(cfg.BaseURL)}, opts...)
+		}
+		p, err := NewOpenRouterProvider(cfg.APIKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(AnthropicName, func(cfg Config) (Provider, error) {
+		opts := cfg.Options
+		if cfg.BaseURL != "" {
+			opts = append([]ClientOption{WithBaseURL(cfg.BaseURL)}, opts...)
+		}
+		p, err := NewAnthropicProvider(cfg.APIKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(GeminiName, func(cfg Config) (Provider, error) {
+		opts := cfg.Options
+		if cfg.BaseURL != "" {
+			opts = append([]ClientOption{WithBaseURL(cfg.BaseURL)}, opts...)
+		}
+		p, err := NewGeminiProvider(cfg.APIKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(GigaChatName, func(cfg Config) (Provider, error) {
+		p, err := NewGigaChatProvider(GigaChatConfig{
+			AuthKey: cfg.APIKey,
+			Scope:   cfg.Extra["scope"],
+			AuthURL: cfg.Extra["auth_url"],
+			BaseURL: cfg.BaseURL,
+			Options: cfg.Options,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(YandexGPTName, func(cfg Config) (Provider, error) {
+		p, err := NewYandexGPTProvider(YandexGPTConfig{
+			ServiceAccountKey: []byte(cfg.APIKey),
+			FolderID:          cfg.Extra["folder_id"],
+			IAMURL:            cfg.Extra["iam_url"],
+			BaseURL:           cfg.BaseURL,
+			Options:           cfg.Options,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(OllamaName, func(cfg Config) (Provider, error) {
+		p, err := NewOllamaProvider(cfg.BaseURL, cfg.Options...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(LlamaCppName, func(cfg Config) (Provider, error) {
+		p, err := NewLlamaCppProvider(cfg.BaseURL, cfg.Options...)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(OpenAICompatibleName, func(cfg Config) (Provider, error) {
+		p, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+			Name:    cfg.Extra["name"],
+			BaseURL: cfg.BaseURL,
+			APIKey:  cfg.APIKey,
+			Options: cfg.Options,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	Register(DefaultName, func(cfg Config) (Provider, error) {
+		return NewDefaultProvider(), nil
+	})
+}
+
+// Register регистрирует фабрику провайдера под указанным названием.
+// Паникует, если фабрика nil или название уже занято, как database/sql.Register.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("provider: Register factory is nil")
+	}
+	if _, exists := registry[name]; exists {
+		panic("provider: Register called twice for provider " + name)
+	}
+	registry[name] = factory
+}
+
+// New создает провайдера, зарегистрированного под указанным названием.
+func New(name string, cfg Config) (Provider, error) {
+	registryMu.RLock()
+	factory, exists := registry[name]
+	registryMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("provider %q is not registered", name)
+	}
+	return factory(cfg)
+}
+
+// Registered возвращает отсортированный список зарегистрированных провайдеров.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,101 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+)
+
+// echoProvider реализует provider.Provider вне пакета provider.
+type echoProvider struct {
+	refreshed bool
+}
+
+func (p *echoProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	return &entities.ProviderMessageResponseDTO{MessageText: messages[len(messages)-1].MessageText}, nil
+}
+
+func (p *echoProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	chunks := make(chan entities.StreamChunk, 1)
+	chunks <- entities.StreamChunk{Type: entities.StreamChunkFinal, Response: &entities.ProviderMessageResponseDTO{}}
+	close(chunks)
+	return chunks, nil
+}
+
+func (p *echoProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	return &entities.ModelInfo{Name: "Echo", Alias: modelName}, nil
+}
+
+func (p *echoProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return []*entities.ModelInfo{{Name: "Echo", Alias: "echo"}}, nil
+}
+
+func (p *echoProvider) RefreshModels(ctx context.Context) error {
+	p.refreshed = true
+	return nil
+}
+
+func TestRegisterCustomProvider(t *testing.T) {
+	provider.Register("echo", func(cfg provider.Config) (provider.Provider, error) {
+		return &echoProvider{}, nil
+	})
+
+	found := false
+	for _, name := range provider.Registered() {
+		if name == "echo" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected echo in registered providers, got %v", provider.Registered())
+	}
+
+	p, err := provider.New("echo", provider.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create registered provider: %v", err)
+	}
+
+	response, err := p.SendMessage(context.Background(), []*entities.Message{{MessageText: "ping"}}, "echo")
+	if err != nil || response.MessageText != "ping" {
+		t.Errorf("Expected echo response, got %+v, %v", response, err)
+	}
+
+	if _, err := provider.New("missing", provider.Config{}); err == nil {
+		t.Error("Expected error for unregistered provider")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate registration")
+		}
+	}()
+	provider.Register("echo", func(cfg provider.Config) (provider.Provider, error) { return nil, nil })
+}
+
+func TestCapabilityThroughWrapper(t *testing.T) {
+	inner := &echoProvider{}
+	wrapped := provider.NewRetryProvider(inner, provider.DefaultRetryPolicy())
+
+	refresher, ok := provider.Capability[provider.ModelRefresher](wrapped)
+	if !ok {
+		t.Fatal("Expected ModelRefresher to be found through RetryProvider")
+	}
+	if err := refresher.RefreshModels(context.Background()); err != nil || !inner.refreshed {
+		t.Errorf("Expected inner provider to refresh models, got %v", err)
+	}
+
+	if _, ok := provider.Capability[provider.Namer](wrapped); ok {
+		t.Error("Expected no Namer for provider without Name method")
+	}
+
+	defaultProvider, err := provider.New(provider.DefaultName, provider.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create default provider: %v", err)
+	}
+	if namer, ok := provider.Capability[provider.Namer](defaultProvider); !ok || namer.Name() != "Default" {
+		t.Error("Expected default provider to implement Namer")
+	}
+}
@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+var (
+	_ Provider = (*RetryProvider)(nil)
+	_ Wrapper  = (*RetryProvider)(nil)
+)
+
+// RetryPolicy описывает правила повторных попыток запроса к провайдеру.
+type RetryPolicy struct {
+	MaxAttempts    int              // Максимальное число попыток, включая первую
+	InitialBackoff time.Duration    // Пауза перед второй попыткой
+	MaxBackoff     time.Duration    // Максимальная пауза между попытками
+	Multiplier     float64          // Множитель паузы для каждой следующей попытки
+	Jitter         float64          // Доля случайного отклонения паузы (0..1)
+	AttemptTimeout time.Duration    // Таймаут одной попытки (0 - без ограничения)
+	MaxRetryAfter  time.Duration    // Максимальное значение Retry-After, которое готовы ждать (отрицательное - без ограничения)
+	Retryable      func(error) bool // Какие ошибки повторять (nil - IsRetryable)
+}
+
+// DefaultRetryPolicy возвращает политику по умолчанию: 3 попытки, пауза от 500мс до 30с с jitter 20%,
+// Retry-After не дольше минуты.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxRetryAfter:  time.Minute,
+	}
+}
+
+// IsRetryable сообщает, имеет ли смысл повторить запрос после ошибки:
+// превышен лимит запросов, ошибка сервера провайдера или сетевая ошибка.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimit) || errors.Is(err, ErrServer) || errors.Is(err, ErrTransport)
+}
+
+// RetryProvider оборачивает провайдера и повторяет запросы при временных ошибках.
+// Успешный ответ, в том числе с tool calls, никогда не запрашивается повторно.
+// Поток повторяется только до получения первого chunk: после того как клиент получил
+// часть текста или вызова инструмента, ошибка передается как есть.
+type RetryProvider struct {
+	Provider
+	policy RetryPolicy
+}
+
+// NewRetryProvider создает обертку над провайдером с указанной политикой повторов.
+// Незаданные поля политики берутся из DefaultRetryPolicy.
+func NewRetryProvider(provider Provider, policy RetryPolicy) *RetryProvider {
+	defaults := DefaultRetryPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaults.InitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaults.MaxBackoff
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaults.Multiplier
+	}
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		policy.Jitter = defaults.Jitter
+	}
+	if policy.MaxRetryAfter == 0 {
+		policy.MaxRetryAfter = defaults.MaxRetryAfter
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+
+	return &RetryProvider{Provider: provider, policy: policy}
+}
+
+// Unwrap возвращает обернутый провайдер.
+func (p *RetryProvider) Unwrap() Provider {
+	return p.Provider
+}
+
+// SendMessage отправляет сообщения, повторяя запрос при временных ошибках.
+func (p *RetryProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := p.attemptContext(ctx)
+		response, err := p.Provider.SendMessage(attemptCtx, messages, modelName, opts...)
+		err = attemptError(ctx, attemptCtx, err)
+		cancel()
+		if err == nil {
+			return response, nil
+		}
+
+		delay, retry := p.retryDelay(ctx, attempt, err)
+		if !retry || sleep(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+// SendMessageStream открывает поток, повторяя попытку, если поток не открылся
+// или вернул ошибку до первого chunk. Вызов возвращается после получения первого chunk;
+// ошибка до первого chunk возвращается из метода. Таймаут попытки действует на весь поток.
+func (p *RetryProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := p.attemptContext(ctx)
+		chunks, err := p.Provider.SendMessageStream(attemptCtx, messages, modelName, opts...)
+		if err == nil {
+			first, ok := <-chunks
+			switch {
+			case ok && first.Type != entities.StreamChunkError:
+				return forwardStream(ctx, cancel, first, chunks), nil
+			case ok:
+				err = first.Err
+			case attemptCtx.Err() != nil:
+				err = attemptCtx.Err()
+			default:
+				// Поток закрылся без chunks, повторять нечего
+				return forwardStream(ctx, cancel, entities.StreamChunk{}, chunks), nil
+			}
+		}
+		err = attemptError(ctx, attemptCtx, err)
+		cancel()
+
+		delay, retry := p.retryDelay(ctx, attempt, err)
+		if !retry || sleep(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+// attemptContext создает контекст одной попытки с таймаутом из политики.
+func (p *RetryProvider) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.policy.AttemptTimeout > 0 {
+		return context.WithTimeout(ctx, p.policy.AttemptTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// retryDelay определяет, нужна ли следующая попытка и сколько ждать перед ней.
+// Не повторяет, если ошибка не временная, попытки закончились, Retry-After больше
+// MaxRetryAfter или отменен контекст. Retry-After от провайдера заменяет расчетную паузу.
+func (p *RetryProvider) retryDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.policy.MaxAttempts || ctx.Err() != nil || !p.policy.Retryable(err) {
+		return 0, false
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.RetryAfter > 0 {
+		if p.policy.MaxRetryAfter > 0 && providerErr.RetryAfter > p.policy.MaxRetryAfter {
+			return 0, false
+		}
+		return providerErr.RetryAfter, true
+	}
+
+	return p.backoff(attempt), true
+}
+
+// backoff рассчитывает экспоненциальную паузу перед попыткой attempt+1 со случайным отклонением.
+func (p *RetryProvider) backoff(attempt int) time.Duration {
+	delay := float64(p.policy.InitialBackoff) * math.Pow(p.policy.Multiplier, float64(attempt-1))
+	delay = math.Min(delay, float64(p.policy.MaxBackoff))
+	delay *= 1 + p.policy.Jitter*(2*rand.Float64()-1)
+	return time.Duration(math.Min(delay, float64(p.policy.MaxBackoff)))
+}
+
+// attemptError превращает истечение таймаута попытки в ErrTransport, чтобы попытку можно было повторить.
+// Отмена родительского контекста возвращается как есть.
+func attemptError(ctx context.Context, attemptCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return &ProviderError{Kind: ErrTransport, Message: "attempt timed out", Err: err}
+	}
+	return err
+}
+
+// sleep ждет указанное время или отмену контекста.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// forwardStream передает первый chunk и остаток потока попытки в новый канал.
+// Пустой первый chunk (без типа) не передается. cancel вызывается после завершения потока.
+func forwardStream(ctx context.Context, cancel context.CancelFunc, first entities.StreamChunk, chunks <-chan entities.StreamChunk) <-chan entities.StreamChunk {
+	forwarded := make(chan entities.StreamChunk)
+	go func() {
+		defer close(forwarded)
+		defer cancel()
+
+		if first.Type != "" && !sendStreamChunk(ctx, forwarded, first) {
+			return
+		}
+		for chunk := range chunks {
+			if !sendStreamChunk(ctx, forwarded, chunk) {
+				return
+			}
+		}
+	}()
+	return forwarded
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// flakyProvider возвращает заданные ошибки для первых попыток, затем успешный ответ.
+type flakyProvider struct {
+	DefaultProvider
+	errs     []error
+	response *entities.ProviderMessageResponseDTO
+	calls    int
+}
+
+func (p *flakyProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	p.calls++
+	if p.calls <= len(p.errs) && p.errs[p.calls-1] != nil {
+		return nil, p.errs[p.calls-1]
+	}
+	return p.response, nil
+}
+
+func (p *flakyProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	p.calls++
+	chunks := make(chan entities.StreamChunk, 2)
+	if p.calls <= len(p.errs) && p.errs[p.calls-1] != nil {
+		chunks <- entities.StreamChunk{Type: entities.StreamChunkError, Err: p.errs[p.calls-1]}
+	} else {
+		chunks <- entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: "ok"}
+		chunks <- entities.StreamChunk{Type: entities.StreamChunkFinal, Response: p.response}
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestRetryProviderRetriesTransientErrors(t *testing.T) {
+	inner := &flakyProvider{
+		errs: []error{
+			&ProviderError{Kind: ErrServer, StatusCode: 502},
+			&ProviderError{Kind: ErrRateLimit, StatusCode: 429, RetryAfter: time.Millisecond},
+		},
+		response: &entities.ProviderMessageResponseDTO{MessageText: "ok"},
+	}
+	p := NewRetryProvider(inner, testRetryPolicy())
+
+	response, err := p.SendMessage(context.Background(), nil, "test-model")
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if response.MessageText != "ok" || inner.calls != 3 {
+		t.Errorf("Expected 3 calls and ok response, got %d calls and %+v", inner.calls, response)
+	}
+}
+
+func TestRetryProviderStopsOnPermanentErrors(t *testing.T) {
+	inner := &flakyProvider{errs: []error{&ProviderError{Kind: ErrAuth, StatusCode: 401}}}
+	p := NewRetryProvider(inner, testRetryPolicy())
+
+	if _, err := p.SendMessage(context.Background(), nil, "test-model"); !errors.Is(err, ErrAuth) || inner.calls != 1 {
+		t.Errorf("Expected single call with ErrAuth, got %d calls and %v", inner.calls, err)
+	}
+
+	inner = &flakyProvider{errs: []error{&ProviderError{Kind: ErrRateLimit, RetryAfter: time.Hour}}}
+	p = NewRetryProvider(inner, testRetryPolicy())
+	if _, err := p.SendMessage(context.Background(), nil, "test-model"); !errors.Is(err, ErrRateLimit) || inner.calls != 1 {
+		t.Errorf("Expected no retry when Retry-After exceeds limit, got %d calls and %v", inner.calls, err)
+	}
+
+	serverErr := &ProviderError{Kind: ErrServer}
+	inner = &flakyProvider{errs: []error{serverErr, serverErr, serverErr, serverErr}}
+	p = NewRetryProvider(inner, testRetryPolicy())
+	if _, err := p.SendMessage(context.Background(), nil, "test-model"); !errors.Is(err, ErrServer) || inner.calls != 3 {
+		t.Errorf("Expected 3 attempts with ErrServer, got %d calls and %v", inner.calls, err)
+	}
+}
+
+func TestRetryProviderDoesNotRepeatToolCalls(t *testing.T) {
+	inner := &flakyProvider{response: &entities.ProviderMessageResponseDTO{
+		ToolCalls:   []mcpgo.CallToolRequest{{Params: mcpgo.CallToolParams{Name: "get_weather"}}},
+		ToolCallIDs: []string{"call_1"},
+	}}
+	p := NewRetryProvider(inner, testRetryPolicy())
+
+	response, err := p.SendMessage(context.Background(), nil, "test-model")
+	if err != nil || len(response.ToolCalls) != 1 || inner.calls != 1 {
+		t.Errorf("Expected single call returning tool calls, got %d calls, %v", inner.calls, err)
+	}
+}
+
+func TestRetryProviderStream(t *testing.T) {
+	inner := &flakyProvider{
+		errs:     []error{&ProviderError{Kind: ErrTransport}},
+		response: &entities.ProviderMessageResponseDTO{MessageText: "ok"},
+	}
+	p := NewRetryProvider(inner, testRetryPolicy())
+
+	chunks, err := p.SendMessageStream(context.Background(), nil, "test-model")
+	if err != nil {
+		t.Fatalf("Expected stream to open after retry, got %v", err)
+	}
+
+	var types []string
+	for chunk := range chunks {
+		types = append(types, chunk.Type)
+	}
+	if inner.calls != 2 || len(types) != 2 || types[0] != entities.StreamChunkText || types[1] != entities.StreamChunkFinal {
+		t.Errorf("Expected text and final chunks after 2 calls, got %v after %d calls", types, inner.calls)
+	}
+}
+
+func TestRetryProviderAttemptTimeout(t *testing.T) {
+	inner := &flakyProvider{errs: []error{context.DeadlineExceeded}, response: &entities.ProviderMessageResponseDTO{MessageText: "ok"}}
+	policy := testRetryPolicy()
+	policy.AttemptTimeout = time.Nanosecond
+	p := NewRetryProvider(inner, policy)
+
+	// Таймаут попытки истекает сразу, первая попытка считается сетевой ошибкой и повторяется
+	if _, err := p.SendMessage(context.Background(), nil, "test-model"); err != nil || inner.calls != 2 {
+		t.Errorf("Expected retry after attempt timeout, got %d calls and %v", inner.calls, err)
+	}
+}
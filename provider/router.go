@@ -0,0 +1,332 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+var (
+	_ Provider       = (*Router)(nil)
+	_ HealthReporter = (*Router)(nil)
+)
+
+// routerProviderName название роутера в ошибках.
+const routerProviderName = "Router"
+
+// RouteStrategy определяет порядок, в котором роутер перебирает провайдеров модели.
+type RouteStrategy string
+
+const (
+	// RoutePriority провайдеры перебираются в порядке, в котором переданы в NewRouter.
+	RoutePriority RouteStrategy = "priority"
+	// RouteCheapest сначала провайдер с минимальной ценой модели (ModelInfo.PriceInRubles),
+	// при равной цене - в порядке передачи в NewRouter. Провайдеры без информации о модели
+	// (GetModelInfo вернул nil, как DefaultProvider) идут последними.
+	RouteCheapest RouteStrategy = "cheapest"
+)
+
+// RouterPolicy описывает правила выбора провайдера.
+type RouterPolicy struct {
+	Strategy RouteStrategy    // Порядок перебора провайдеров (пусто - RoutePriority)
+	Fallback func(error) bool // После каких ошибок переходить к следующему провайдеру (nil - ShouldFallback)
+}
+
+// ShouldFallback сообщает, имеет ли смысл отправить запрос следующему провайдеру после ошибки:
+// временная ошибка (IsRetryable), срабатывание фильтра контента, модель не поддерживается провайдером
+// или провайдер недоступен (ErrCircuitOpen).
+func ShouldFallback(err error) bool {
+	return IsRetryable(err) || errors.Is(err, ErrContentFilter) || errors.Is(err, ErrModelNotFound) || errors.Is(err, ErrCircuitOpen)
+}
+
+// Router распределяет запросы между несколькими провайдерами одной и той же модели.
+// Запрос отправляется только провайдерам, у которых есть модель (GetModelInfo без ошибки),
+// в порядке стратегии; при ошибке, подходящей под Fallback, запрос уходит следующему провайдеру.
+// Название провайдера, выполнившего запрос, записывается в ответ (ProviderMessageResponseDTO.Provider).
+//
+// Чтобы история с вызовами инструментов продолжалась у любого провайдера, роутер генерирует ID
+// вызовам без ID в ответе, а ID в истории, которые не примет часть API (со спецсимволами или
+// длиннее 64 символов), одинаково заменяет в вызовах и в результатах. Исходные сообщения не меняются.
+type Router struct {
+	providers []Provider
+	policy    RouterPolicy
+}
+
+// NewRouter создает роутер над провайдерами. Порядок провайдеров - приоритет для RoutePriority.
+func NewRouter(policy RouterPolicy, providers ...Provider) *Router {
+	if policy.Strategy == "" {
+		policy.Strategy = RoutePriority
+	}
+	if policy.Fallback == nil {
+		policy.Fallback = ShouldFallback
+	}
+	return &Router{providers: providers, policy: policy}
+}
+
+// routeCandidate провайдер, у которого есть запрошенная модель.
+type routeCandidate struct {
+	provider  Provider
+	name      string              // Название провайдера для ответа
+	modelInfo *entities.ModelInfo // Информация о модели у провайдера (nil - цена неизвестна)
+}
+
+// SendMessage отправляет сообщения первому подходящему провайдеру, переходя к следующему при ошибках из Fallback.
+func (r *Router) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	candidates, err := r.candidates(modelName)
+	if err != nil {
+		return nil, err
+	}
+	messages = normalizeToolCallIDs(messages)
+
+	for _, candidate := range candidates {
+		var response *entities.ProviderMessageResponseDTO
+		response, err = candidate.provider.SendMessage(ctx, messages, modelName, opts...)
+		if err == nil {
+			fillToolCallIDs(response, nil)
+			response.Provider = candidate.name
+			return response, nil
+		}
+		if ctx.Err() != nil || !r.policy.Fallback(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// SendMessageStream открывает поток у первого подходящего провайдера. Переход к следующему провайдеру
+// возможен, пока поток не открылся или вернул ошибку до первого chunk; после этого ошибка передается как есть.
+func (r *Router) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	candidates, err := r.candidates(modelName)
+	if err != nil {
+		return nil, err
+	}
+	messages = normalizeToolCallIDs(messages)
+
+	for _, candidate := range candidates {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		var chunks <-chan entities.StreamChunk
+		chunks, err = candidate.provider.SendMessageStream(attemptCtx, messages, modelName, opts...)
+		if err == nil {
+			first, ok := <-chunks
+			switch {
+			case ok && first.Type != entities.StreamChunkError:
+				return routeStream(ctx, cancel, candidate.name, first, chunks), nil
+			case ok:
+				err = first.Err
+			default:
+				return routeStream(ctx, cancel, candidate.name, entities.StreamChunk{}, chunks), nil
+			}
+		}
+		cancel()
+		if ctx.Err() != nil || !r.policy.Fallback(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// GetModelInfo возвращает информацию о модели у провайдера, которому роутер отправит запрос первым.
+func (r *Router) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	candidates, err := r.candidates(modelName)
+	if err != nil {
+		return nil, err
+	}
+	return candidates[0].modelInfo, nil
+}
+
+// ListModels возвращает модели всех провайдеров. Для модели, доступной у нескольких провайдеров,
+// возвращается информация от провайдера, которому роутер отправит запрос первым.
+// Ошибка возвращается, только если список не удалось получить ни у одного провайдера.
+func (r *Router) ListModels() ([]*entities.ModelInfo, error) {
+	var lastErr error
+	loaded := false
+	models := make(map[entities.ModelName]*entities.ModelInfo)
+	for _, p := range r.providers {
+		list, err := p.ListModels()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		loaded = true
+		for _, model := range list {
+			if model == nil {
+				continue
+			}
+			current, exists := models[model.Alias]
+			if !exists || (r.policy.Strategy == RouteCheapest && model.PriceInRubles.LessThan(current.PriceInRubles)) {
+				models[model.Alias] = model
+			}
+		}
+	}
+	if !loaded && lastErr != nil {
+		return nil, lastErr
+	}
+
+	result := make([]*entities.ModelInfo, 0, len(models))
+	for _, model := range models {
+		result = append(result, model)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Alias < result[j].Alias })
+	return result, nil
+}
+
+// Health возвращает состояние всех провайдеров роутера, которые его отслеживают (HealthReporter).
+func (r *Router) Health() []HealthStatus {
+	var health []HealthStatus
+	for _, p := range r.providers {
+		if reporter, ok := Capability[HealthReporter](p); ok {
+			health = append(health, reporter.Health()...)
+		}
+	}
+	return health
+}
+
+// candidates возвращает провайдеров модели в порядке стратегии.
+func (r *Router) candidates(modelName entities.ModelName) ([]routeCandidate, error) {
+	candidates := make([]routeCandidate, 0, len(r.providers))
+	for i, p := range r.providers {
+		modelInfo, err := p.GetModelInfo(modelName)
+		if err != nil {
+			continue
+		}
+		name := fmt.Sprintf("provider %d", i)
+		if namer, ok := Capability[Namer](p); ok {
+			name = namer.Name()
+		}
+		candidates = append(candidates, routeCandidate{provider: p, name: name, modelInfo: modelInfo})
+	}
+	if len(candidates) == 0 {
+		return nil, newModelNotSupportedError(routerProviderName, string(modelName))
+	}
+
+	if r.policy.Strategy == RouteCheapest {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			left, right := candidates[i].modelInfo, candidates[j].modelInfo
+			if left == nil || right == nil {
+				return left != nil && right == nil
+			}
+			return left.PriceInRubles.LessThan(right.PriceInRubles)
+		})
+	}
+	return candidates, nil
+}
+
+// routeStream передает поток провайдера name: генерирует ID вызовам инструментов без ID
+// и записывает название провайдера в итоговый ответ. Пустой первый chunk (без типа) не передается.
+func routeStream(ctx context.Context, cancel context.CancelFunc, name string, first entities.StreamChunk, chunks <-chan entities.StreamChunk) <-chan entities.StreamChunk {
+	routed := make(chan entities.StreamChunk)
+	go func() {
+		defer close(routed)
+		defer cancel()
+
+		ids := make(map[int]string) // ID вызовов инструментов по индексу
+		route := func(chunk entities.StreamChunk) bool {
+			switch chunk.Type {
+			case "":
+				return true
+			case entities.StreamChunkToolCall:
+				if chunk.ToolCallDelta != nil {
+					delta := *chunk.ToolCallDelta
+					if _, seen := ids[delta.Index]; !seen {
+						if delta.ID == "" {
+							delta.ID = newToolCallID()
+						}
+						ids[delta.Index] = delta.ID
+					}
+					chunk.ToolCallDelta = &delta
+				}
+			case entities.StreamChunkFinal:
+				if chunk.Response != nil {
+					fillToolCallIDs(chunk.Response, ids)
+					chunk.Response.Provider = name
+				}
+			}
+			return sendStreamChunk(ctx, routed, chunk)
+		}
+
+		if !route(first) {
+			return
+		}
+		for chunk := range chunks {
+			if !route(chunk) {
+				return
+			}
+		}
+	}()
+	return routed
+}
+
+// fillToolCallIDs дополняет ответ ID для вызовов инструментов без ID.
+// ids - ID, уже отправленные клиенту в потоке, по индексу вызова.
+func fillToolCallIDs(response *entities.ProviderMessageResponseDTO, ids map[int]string) {
+	if len(response.ToolCalls) == 0 {
+		return
+	}
+	indexes := make([]int, 0, len(ids))
+	for index := range ids {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for len(response.ToolCallIDs) < len(response.ToolCalls) {
+		response.ToolCallIDs = append(response.ToolCallIDs, "")
+	}
+	for i, id := range response.ToolCallIDs {
+		if id != "" {
+			continue
+		}
+		if i < len(indexes) {
+			response.ToolCallIDs[i] = ids[indexes[i]]
+		} else {
+			response.ToolCallIDs[i] = newToolCallID()
+		}
+	}
+}
+
+// portableToolCallID ID вызова инструмента, который принимают API всех провайдеров.
+var portableToolCallID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// normalizeToolCallIDs возвращает историю, в которой ID вызовов инструментов подходят для любого провайдера.
+// Неподходящий ID заменяется производным от него, поэтому вызов и его результат остаются связаны.
+// Если менять нечего, возвращаются исходные сообщения; иначе измененные сообщения копируются.
+func normalizeToolCallIDs(messages []*entities.Message) []*entities.Message {
+	var result []*entities.Message
+	for i, msg := range messages {
+		ids := make([]string, len(msg.ToolCallIDs))
+		changed := false
+		for j, id := range msg.ToolCallIDs {
+			ids[j] = id
+			if id != "" && !portableToolCallID.MatchString(id) {
+				ids[j] = portableID(id)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if result == nil {
+			result = append([]*entities.Message(nil), messages...)
+		}
+		normalized := *msg
+		normalized.ToolCallIDs = ids
+		result[i] = &normalized
+	}
+	if result == nil {
+		return messages
+	}
+	return result
+}
+
+// portableID строит подходящий для всех API ID из произвольного ID вызова.
+func portableID(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return "call_" + hex.EncodeToString(hash[:12])
+}
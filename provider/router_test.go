@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/shopspring/decimal"
+)
+
+// namedMockProvider тестовый провайдер со своим названием, чтобы различать провайдеров роутера.
+type namedMockProvider struct {
+	*MockProvider
+	name string
+}
+
+func (p *namedMockProvider) Name() string {
+	return p.name
+}
+
+// newRouterMock создает тестового провайдера name с моделями по цене price.
+func newRouterMock(name string, price int64, models ...entities.ModelName) *namedMockProvider {
+	infos := make([]*entities.ModelInfo, len(models))
+	for i, model := range models {
+		infos[i] = &entities.ModelInfo{Name: string(model), Alias: model, PriceInRubles: decimal.NewFromInt(price)}
+	}
+	return &namedMockProvider{MockProvider: NewMockProvider(infos...), name: name}
+}
+
+func userMessage(text string) []*entities.Message {
+	return []*entities.Message{{MessageText: text, AuthorType: entities.AuthorTypeUser, MessageType: entities.MessageText}}
+}
+
+func TestRouterStrategies(t *testing.T) {
+	cheap := newRouterMock("cheap", 10, "gpt-4o")
+	expensive := newRouterMock("expensive", 100, "gpt-4o", "claude")
+	cheap.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+	expensive.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+
+	tests := []struct {
+		strategy RouteStrategy
+		model    entities.ModelName
+		expected string
+	}{
+		{RouteCheapest, "gpt-4o", "cheap"},
+		{RoutePriority, "gpt-4o", "expensive"},
+		{RouteCheapest, "claude", "expensive"},
+	}
+	for _, tt := range tests {
+		router := NewRouter(RouterPolicy{Strategy: tt.strategy}, expensive, cheap)
+		response, err := router.SendMessage(context.Background(), userMessage("Привет"), tt.model)
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", tt.strategy, tt.model, err)
+		}
+		if response.Provider != tt.expected {
+			t.Errorf("%s/%s: expected provider %s, got %s", tt.strategy, tt.model, tt.expected, response.Provider)
+		}
+	}
+
+	router := NewRouter(RouterPolicy{Strategy: RouteCheapest}, expensive, cheap)
+	if _, err := router.SendMessage(context.Background(), userMessage("Привет"), "unknown"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound for unknown model, got %v", err)
+	}
+	if info, err := router.GetModelInfo("gpt-4o"); err != nil || !info.PriceInRubles.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("Expected cheapest model info, got %+v (%v)", info, err)
+	}
+	models, err := router.ListModels()
+	if err != nil || len(models) != 2 || models[1].Alias != "gpt-4o" || !models[1].PriceInRubles.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("Expected merged models with cheapest price, got %+v (%v)", models, err)
+	}
+}
+
+func TestRouterCheapestWithoutModelInfo(t *testing.T) {
+	mock := newRouterMock("mock", 10, "gpt-4o")
+	mock.Script(&MockResponse{Response: MockText("ok"), Repeat: true})
+	router := NewRouter(RouterPolicy{Strategy: RouteCheapest}, NewDefaultProvider(), mock)
+
+	// DefaultProvider не сообщает цену модели и поэтому идет после провайдера с известной ценой
+	response, err := router.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+	if err != nil || response.Provider != "mock" {
+		t.Errorf("Expected provider with known price first, got %+v (%v)", response, err)
+	}
+	response, err = router.SendMessage(context.Background(), userMessage("Привет"), "unknown")
+	if err != nil || response.Provider != "Default" {
+		t.Errorf("Expected DefaultProvider for model without price, got %+v (%v)", response, err)
+	}
+	if models, err := router.ListModels(); err != nil || len(models) != 1 {
+		t.Errorf("Expected models of mock provider only, got %+v (%v)", models, err)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fallback bool
+	}{
+		{"rate limit", &ProviderError{Kind: ErrRateLimit, StatusCode: 429}, true},
+		{"server", &ProviderError{Kind: ErrServer, StatusCode: 503}, true},
+		{"content filter", &ProviderError{Kind: ErrContentFilter}, true},
+		{"model not found", &ProviderError{Kind: ErrModelNotFound, StatusCode: 404}, true},
+		{"bad request", &ProviderError{Kind: ErrBadRequest, StatusCode: 400}, false},
+		{"auth", &ProviderError{Kind: ErrAuth, StatusCode: 401}, false},
+	}
+	for _, tt := range tests {
+		primary := newRouterMock("primary", 10, "gpt-4o")
+		primary.ReplyError(tt.err)
+		secondary := newRouterMock("secondary", 10, "gpt-4o")
+		secondary.Reply(MockText("ok"))
+		router := NewRouter(RouterPolicy{}, primary, secondary)
+
+		response, err := router.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o")
+		if !tt.fallback {
+			if !errors.Is(err, tt.err) || len(secondary.Requests()) != 0 {
+				t.Errorf("%s: expected error without fallback, got %v and %d requests", tt.name, err, len(secondary.Requests()))
+			}
+			continue
+		}
+		if err != nil || response.Provider != "secondary" {
+			t.Errorf("%s: expected fallback to secondary, got %+v (%v)", tt.name, response, err)
+		}
+	}
+
+	primary := newRouterMock("primary", 10, "gpt-4o")
+	primary.ReplyError(&ProviderError{Kind: ErrServer})
+	secondary := newRouterMock("secondary", 10, "gpt-4o")
+	secondary.ReplyError(&ProviderError{Kind: ErrRateLimit})
+	router := NewRouter(RouterPolicy{}, primary, secondary)
+	if _, err := router.SendMessage(context.Background(), userMessage("Привет"), "gpt-4o"); !errors.Is(err, ErrRateLimit) {
+		t.Errorf("Expected error of the last provider, got %v", err)
+	}
+}
+
+func TestRouterToolCallIDs(t *testing.T) {
+	primary := newRouterMock("primary", 10, "gpt-4o")
+	primary.ReplyError(&ProviderError{Kind: ErrServer})
+	secondary := newRouterMock("secondary", 10, "gpt-4o")
+	answer := MockToolCalls(MockToolCall("get_weather", map[string]interface{}{"city": "Москва"}))
+	answer.ToolCallIDs = nil
+	secondary.Reply(answer)
+	router := NewRouter(RouterPolicy{}, primary, secondary)
+
+	messages := []*entities.Message{
+		{MessageText: "Погода в Казани?", AuthorType: entities.AuthorTypeUser},
+		{AuthorType: entities.AuthorTypeRobot, ToolCalls: answer.ToolCalls, ToolCallIDs: []string{"functions.get_weather:0"}},
+		{MessageText: "+3", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"functions.get_weather:0"}},
+		{MessageText: "А в Москве?", AuthorType: entities.AuthorTypeUser},
+	}
+	response, err := router.SendMessage(context.Background(), messages, "gpt-4o")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(response.ToolCallIDs) != 1 || !portableToolCallID.MatchString(response.ToolCallIDs[0]) {
+		t.Errorf("Expected generated tool call ID, got %v", response.ToolCallIDs)
+	}
+
+	sent := secondary.Requests()[0].Messages
+	callID, resultID := sent[1].ToolCallIDs[0], sent[2].ToolCallIDs[0]
+	if callID != resultID || !portableToolCallID.MatchString(callID) {
+		t.Errorf("Expected the same portable ID for call and result, got %q and %q", callID, resultID)
+	}
+	if primaryID := primary.Requests()[0].Messages[1].ToolCallIDs[0]; primaryID != callID {
+		t.Errorf("Expected the same ID for every provider, got %q and %q", primaryID, callID)
+	}
+	if messages[1].ToolCallIDs[0] != "functions.get_weather:0" {
+		t.Errorf("Expected original messages to be unchanged, got %v", messages[1].ToolCallIDs)
+	}
+}
+
+func TestRouterStream(t *testing.T) {
+	primary := newRouterMock("primary", 10, "gpt-4o")
+	primary.ReplyError(&ProviderError{Kind: ErrRateLimit, StatusCode: 429})
+	answer := MockToolCalls(MockToolCall("get_weather", map[string]interface{}{"city": "Москва"}))
+	answer.ToolCallIDs = nil
+	secondary := newRouterMock("secondary", 10, "gpt-4o")
+	secondary.Reply(answer)
+	router := NewRouter(RouterPolicy{}, primary, secondary)
+
+	chunks, err := router.SendMessageStream(context.Background(), userMessage("Погода?"), "gpt-4o")
+	if err != nil {
+		t.Fatalf("Expected fallback stream, got %v", err)
+	}
+
+	var deltaID string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkToolCall:
+			deltaID = chunk.ToolCallDelta.ID
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+	if final == nil || final.Provider != "secondary" {
+		t.Fatalf("Expected final response from secondary, got %+v", final)
+	}
+	if deltaID == "" || len(final.ToolCallIDs) != 1 || final.ToolCallIDs[0] != deltaID {
+		t.Errorf("Expected the same generated ID in delta and final response, got %q and %v", deltaID, final.ToolCallIDs)
+	}
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/openai"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// streamAccumulator собирает итоговый ответ из chunks потока.
+type streamAccumulator struct {
+	text         strings.Builder
+	toolCalls    map[int]*openai.ToolCall // Вызовы инструментов по индексу
+	finishReason *string
+}
+
+// newStreamAccumulator создает пустой аккумулятор потока.
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{
+		toolCalls: make(map[int]*openai.ToolCall),
+	}
+}
+
+// addText добавляет часть текста ответа.
+func (a *streamAccumulator) addText(text string) {
+	a.text.WriteString(text)
+}
+
+// addToolCallDelta добавляет часть вызова инструмента.
+func (a *streamAccumulator) addToolCallDelta(delta entities.ToolCallDelta) {
+	toolCall, exists := a.toolCalls[delta.Index]
+	if !exists {
+		toolCall = &openai.ToolCall{Type: openai.ToolTypeFunction}
+		a.toolCalls[delta.Index] = toolCall
+	}
+	if delta.ID != "" {
+		toolCall.ID = delta.ID
+	}
+	if delta.Name != "" {
+		toolCall.Function.Name = delta.Name
+	}
+	toolCall.Function.Arguments += delta.ArgumentsDelta
+}
+
+// setFinishReason сохраняет причину завершения генерации.
+func (a *streamAccumulator) setFinishReason(reason *string) {
+	if reason != nil && *reason != "" {
+		a.finishReason = mapFinishReason(reason)
+	}
+}
+
+// response собирает итоговый ответ с текстом, причиной завершения и вызовами инструментов в MCP формате.
+func (a *streamAccumulator) response(toolsMapper *mappers.ToolsMapper) (*entities.ProviderMessageResponseDTO, error) {
+	result := &entities.ProviderMessageResponseDTO{
+		MessageText:  a.text.String(),
+		FinishReason: a.finishReason,
+	}
+
+	if len(a.toolCalls) == 0 {
+		return result, nil
+	}
+
+	indexes := make([]int, 0, len(a.toolCalls))
+	for index := range a.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	result.ToolCalls = make([]mcpgo.CallToolRequest, len(indexes))
+	result.ToolCallIDs = make([]string, len(indexes))
+	for i, index := range indexes {
+		toolCall := a.toolCalls[index]
+		mcpToolCall, err := toolsMapper.OpenAIToolCallToMCP(*toolCall)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool call %d to MCP: %w", i, err)
+		}
+		result.ToolCalls[i] = mcpToolCall
+		result.ToolCallIDs[i] = toolCall.ID
+	}
+
+	return result, nil
+}
+
+// toolCallDeltaFromOpenAI конвертирует OpenAI дельту вызова инструмента в общий формат.
+func toolCallDeltaFromOpenAI(delta openai.ToolCallDelta, fallbackIndex int) entities.ToolCallDelta {
+	result := entities.ToolCallDelta{Index: fallbackIndex}
+	if delta.Index != nil {
+		result.Index = *delta.Index
+	}
+	if delta.ID != nil {
+		result.ID = *delta.ID
+	}
+	if delta.Function != nil {
+		if delta.Function.Name != nil {
+			result.Name = *delta.Function.Name
+		}
+		if delta.Function.Arguments != nil {
+			result.ArgumentsDelta = *delta.Function.Arguments
+		}
+	}
+	return result
+}
+
+// sendStreamChunk отправляет chunk в канал потока.
+// Возвращает false, если контекст отменен и читатель больше не ждет данных.
+func sendStreamChunk(ctx context.Context, stream chan<- entities.StreamChunk, chunk entities.StreamChunk) bool {
+	select {
+	case stream <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendStreamError отправляет chunk с ошибкой в канал потока.
+func sendStreamError(ctx context.Context, stream chan<- entities.StreamChunk, err error) {
+	sendStreamChunk(ctx, stream, entities.StreamChunk{Type: entities.StreamChunkError, Err: err})
+}
+
+// newToolCallID генерирует ID вызова инструмента для API, которые не возвращают свой ID (Gemini, Ollama).
+func newToolCallID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "call_" + hex.EncodeToString(bytes)
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+)
+
+func TestStreamAccumulatorToolCalls(t *testing.T) {
+	accumulator := newStreamAccumulator()
+	accumulator.addText("Ищу ")
+	accumulator.addText("погоду")
+
+	// Дельты двух вызовов приходят вперемешку
+	accumulator.addToolCallDelta(entities.ToolCallDelta{Index: 1, ID: "call_2", Name: "get_time"})
+	accumulator.addToolCallDelta(entities.ToolCallDelta{Index: 0, ID: "call_1", Name: "get_weather", ArgumentsDelta: `{"loc`})
+	accumulator.addToolCallDelta(entities.ToolCallDelta{Index: 0, ArgumentsDelta: `ation":"Moscow"}`})
+
+	finishReason := "tool_calls"
+	accumulator.setFinishReason(&finishReason)
+
+	result, err := accumulator.response(mappers.NewToolsMapper())
+	if err != nil {
+		t.Fatalf("Failed to build response: %v", err)
+	}
+
+	if result.MessageText != "Ищу погоду" {
+		t.Errorf("Expected message text 'Ищу погоду', got '%s'", result.MessageText)
+	}
+
+	if result.FinishReason == nil || *result.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason '%s', got %v", entities.FinishReasonToolCalls, result.FinishReason)
+	}
+
+	if len(result.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(result.ToolCalls))
+	}
+
+	if result.ToolCallIDs[0] != "call_1" || result.ToolCallIDs[1] != "call_2" {
+		t.Errorf("Expected tool call IDs [call_1 call_2], got %v", result.ToolCallIDs)
+	}
+
+	if result.ToolCalls[0].Params.Name != "get_weather" {
+		t.Errorf("Expected first tool 'get_weather', got '%s'", result.ToolCalls[0].Params.Name)
+	}
+
+	arguments, ok := result.ToolCalls[0].Params.Arguments.(map[string]interface{})
+	if !ok || arguments["location"] != "Moscow" {
+		t.Errorf("Expected location argument 'Moscow', got %v", result.ToolCalls[0].Params.Arguments)
+	}
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/schema"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/invopop/jsonschema"
+)
+
+// ErrInvalidStructuredOutput возвращается, когда ответ модели не соответствует ожидаемой JSON Schema.
+var ErrInvalidStructuredOutput = errors.New("invalid structured output")
+
+// schemaNameSanitizer заменяет символы, недопустимые в имени схемы.
+var schemaNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// SendStructured отправляет сообщения и декодирует ответ модели в значение типа T.
+// JSON Schema генерируется из T (теги json и jsonschema), передается провайдеру в строгом режиме,
+// ответ проверяется по схеме и декодируется через encoding/json.
+// Возвращает декодированное значение и исходный ответ провайдера (токены, стоимость).
+// Если ответ не соответствует схеме, ошибка оборачивает ErrInvalidStructuredOutput.
+func SendStructured[T any](ctx context.Context, p Provider, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (T, *entities.ProviderMessageResponseDTO, error) {
+	var result T
+
+	name, responseSchema, err := SchemaFor[T]()
+	if err != nil {
+		return result, nil, err
+	}
+
+	opts = append(opts, options.WithJSONSchema(name, responseSchema, true))
+	response, err := p.SendMessage(ctx, messages, modelName, opts...)
+	if err != nil {
+		return result, nil, err
+	}
+
+	data := []byte(extractJSON(response.MessageText))
+	if err := schema.ValidateJSON(responseSchema, data); err != nil {
+		return result, response, fmt.Errorf("%w: %w", ErrInvalidStructuredOutput, err)
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, response, fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidStructuredOutput, err)
+	}
+
+	return result, response, nil
+}
+
+// SchemaFor генерирует JSON Schema для типа T в формате, пригодном для structured output.
+// Возвращает имя схемы (по имени типа) и саму схему.
+func SchemaFor[T any]() (string, map[string]interface{}, error) {
+	var zero T
+	valueType := reflect.TypeOf(zero)
+	if valueType == nil {
+		return "", nil, fmt.Errorf("cannot generate schema for interface type")
+	}
+
+	reflector := &jsonschema.Reflector{
+		Anonymous:      true,
+		DoNotReference: true,
+		ExpandedStruct: true,
+	}
+	reflected := reflector.ReflectFromType(valueType)
+
+	schemaJSON, err := json.Marshal(reflected)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(schemaJSON, &result); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal schema: %w", err)
+	}
+	delete(result, "$schema")
+	delete(result, "$id")
+
+	for valueType.Kind() == reflect.Pointer || valueType.Kind() == reflect.Slice {
+		valueType = valueType.Elem()
+	}
+	name := schemaNameSanitizer.ReplaceAllString(valueType.Name(), "_")
+	if name == "" {
+		name = "response"
+	}
+
+	return name, result, nil
+}
+
+// extractJSON убирает markdown обертку ```json ... ```, которую иногда добавляют модели.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
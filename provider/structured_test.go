@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+// staticProvider возвращает заранее заданный текст и запоминает опции запроса.
+type staticProvider struct {
+	DefaultProvider
+	text string
+	opts []options.SendMessageOption
+}
+
+func (p *staticProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	p.opts = opts
+	return &entities.ProviderMessageResponseDTO{MessageText: p.text, TotalTokens: 10}, nil
+}
+
+type invoice struct {
+	Number string   `json:"number"`
+	Total  float64  `json:"total"`
+	Items  []string `json:"items"`
+	Note   string   `json:"note,omitempty"`
+}
+
+func TestSendStructured(t *testing.T) {
+	p := &staticProvider{text: "```json\n{\"number\":\"INV-1\",\"total\":99.5,\"items\":[\"кофе\"]}\n```"}
+
+	result, response, err := SendStructured[invoice](context.Background(), p, nil, "test-model")
+	if err != nil {
+		t.Fatalf("SendStructured() failed with error: %v", err)
+	}
+
+	if result.Number != "INV-1" || result.Total != 99.5 || len(result.Items) != 1 {
+		t.Errorf("Unexpected decoded value: %+v", result)
+	}
+	if response.TotalTokens != 10 {
+		t.Errorf("Expected provider response to be returned, got %+v", response)
+	}
+
+	format, ok := options.ExtractResponseFormatOption(p.opts)
+	if !ok || format.Type != options.ResponseFormatJSONSchema || !format.Strict {
+		t.Fatalf("Expected strict json_schema response format, got %+v", format)
+	}
+	if format.SchemaName != "invoice" {
+		t.Errorf("Expected schema name 'invoice', got '%s'", format.SchemaName)
+	}
+	if format.Schema["additionalProperties"] != false {
+		t.Errorf("Expected additionalProperties false, got %v", format.Schema["additionalProperties"])
+	}
+}
+
+func TestSendStructuredInvalidOutput(t *testing.T) {
+	p := &staticProvider{text: `{"number":"INV-1","total":"много"}`}
+
+	_, _, err := SendStructured[invoice](context.Background(), p, nil, "test-model")
+	if !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("Expected ErrInvalidStructuredOutput, got %v", err)
+	}
+}
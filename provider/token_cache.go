@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin запас до истечения токена, за который токен обновляется заранее.
+const tokenRefreshMargin = time.Minute
+
+// tokenRefreshFunc получает новый токен доступа и время его истечения.
+type tokenRefreshFunc func(ctx context.Context) (string, time.Time, error)
+
+// tokenCache хранит короткоживущий токен доступа (OAuth, IAM) и обновляет его при истечении.
+// Обновление выполняется под мьютексом, поэтому параллельные запросы получают один токен на всех.
+type tokenCache struct {
+	mu        sync.Mutex
+	token     string           // Текущий токен
+	expiresAt time.Time        // Время истечения текущего токена
+	refresh   tokenRefreshFunc // Получение нового токена
+	now       func() time.Time // Текущее время (подменяется в тестах)
+}
+
+// newTokenCache создает кэш токена с указанной функцией обновления.
+func newTokenCache(refresh tokenRefreshFunc) *tokenCache {
+	return &tokenCache{refresh: refresh, now: time.Now}
+}
+
+// get возвращает действующий токен, при необходимости получая новый.
+func (c *tokenCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Add(tokenRefreshMargin).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	token, expiresAt, err := c.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiresAt = expiresAt
+	return token, nil
+}
+
+// invalidate сбрасывает токен, который отклонил сервер (например, отозванный до истечения срока).
+// Токен, уже обновленный другим запросом, не сбрасывается.
+func (c *tokenCache) invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+		c.expiresAt = time.Time{}
+	}
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenCacheConcurrentRefresh(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var refreshes atomic.Int32
+	cache := newTokenCache(func(ctx context.Context) (string, time.Time, error) {
+		n := refreshes.Add(1)
+		// Медленный OAuth сервер: остальные запросы должны дождаться этого токена
+		time.Sleep(10 * time.Millisecond)
+		return fmt.Sprintf("token-%d", n), now.Add(30 * time.Minute), nil
+	})
+	cache.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := cache.get(context.Background())
+			if err != nil {
+				t.Errorf("get() failed with error: %v", err)
+			}
+			tokens[i] = token
+		}()
+	}
+	wg.Wait()
+
+	if refreshes.Load() != 1 {
+		t.Errorf("Expected one refresh for concurrent requests, got %d", refreshes.Load())
+	}
+	for _, token := range tokens {
+		if token != "token-1" {
+			t.Fatalf("Expected shared token-1, got %v", tokens)
+		}
+	}
+
+	// Токен обновляется заранее, за минуту до истечения
+	now = now.Add(29*time.Minute + 30*time.Second)
+	if token, _ := cache.get(context.Background()); token != "token-2" {
+		t.Errorf("Expected token refreshed before expiry, got %s", token)
+	}
+
+	// Сброс старого токена не затирает уже полученный новый
+	cache.invalidate("token-1")
+	if token, _ := cache.get(context.Background()); token != "token-2" {
+		t.Errorf("Expected token-2 kept after invalidating stale token, got %s", token)
+	}
+	cache.invalidate("token-2")
+	if token, _ := cache.get(context.Background()); token != "token-3" {
+		t.Errorf("Expected token-3 after invalidation, got %s", token)
+	}
+
+	failing := newTokenCache(func(ctx context.Context) (string, time.Time, error) {
+		return "", time.Time{}, errors.New("auth server down")
+	})
+	if _, err := failing.get(context.Background()); err == nil {
+		t.Error("Expected refresh error")
+	}
+}
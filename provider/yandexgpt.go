@@ -0,0 +1,628 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/config"
+	"github.com/Murolando/m_ai_provider/internal/entities/yandexgpt"
+	"github.com/Murolando/m_ai_provider/internal/mappers"
+	"github.com/Murolando/m_ai_provider/internal/utils"
+	"github.com/Murolando/m_ai_provider/options"
+)
+
+// Константы для провайдера YandexGPT
+const (
+	yandexGPTProviderName   = "YandexGPT"
+	yandexGPTDefaultBaseURL = "https://llm.api.cloud.yandex.net/foundationModels/v1"
+	yandexGPTDefaultIAMURL  = "https://iam.api.cloud.yandex.net/iam/v1/tokens"
+	// yandexIAMAudience получатель JWT, он не зависит от адреса, по которому отправляется запрос
+	yandexIAMAudience = "https://iam.api.cloud.yandex.net/iam/v1/tokens"
+	// yandexJWTLifetime срок жизни JWT для обмена на IAM токен (не больше часа)
+	yandexJWTLifetime = time.Hour
+)
+
+var (
+	_ Provider       = (*YandexGPTProvider)(nil)
+	_ Namer          = (*YandexGPTProvider)(nil)
+	_ ModelRefresher = (*YandexGPTProvider)(nil)
+)
+
+// yandexGPTPrices цены моделей в рублях по префиксу URI модели; более точные префиксы идут раньше.
+// Цены синхронного режима взяты из https://yandex.cloud/ru/docs/foundation-models/pricing.
+var yandexGPTPrices = []rublePrice{
+	{"yandexgpt-lite", 200},
+	{"yandexgpt", 1200},
+}
+
+// YandexGPTConfig содержит параметры провайдера YandexGPT.
+type YandexGPTConfig struct {
+	ServiceAccountKey []byte         // Авторизованный ключ сервисного аккаунта (содержимое authorized_key.json)
+	FolderID          string         // ID каталога, в котором вызываются модели
+	IAMURL            string         // Адрес обмена JWT на IAM токен (по умолчанию https://iam.api.cloud.yandex.net/iam/v1/tokens)
+	BaseURL           string         // Базовый URL API (по умолчанию https://llm.api.cloud.yandex.net/foundationModels/v1)
+	Options           []ClientOption // Настройки HTTP клиента
+}
+
+// YandexGPTProvider представляет провайдера для работы с YandexGPT (Foundation Models API).
+// IAM токен получается обменом JWT, подписанного ключом сервисного аккаунта, и кэшируется до истечения срока.
+type YandexGPTProvider struct {
+	baseURL     string                      // Базовый URL для API запросов
+	iamURL      string                      // Адрес обмена JWT на IAM токен
+	folderID    string                      // ID каталога
+	key         yandexgpt.ServiceAccountKey // Ключ сервисного аккаунта
+	privateKey  *rsa.PrivateKey             // Закрытый ключ для подписи JWT
+	tokens      *tokenCache                 // Кэш IAM токена
+	models      *modelCache                 // Кэш информации о моделях
+	toolsMapper *mappers.ToolsMapper        // Маппер для конвертации инструментов
+	httpClient  *http.Client                // HTTP клиент для запросов к API
+}
+
+// NewYandexGPTProvider создает провайдера YandexGPT.
+// Список моделей берется из раздела yandexgpt в models.yaml: API не отдает список моделей.
+func NewYandexGPTProvider(cfg YandexGPTConfig) (*YandexGPTProvider, error) {
+	if len(cfg.ServiceAccountKey) == 0 {
+		return nil, fmt.Errorf("YANDEX_SERVICE_ACCOUNT_KEY is not set")
+	}
+	if cfg.FolderID == "" {
+		return nil, fmt.Errorf("YANDEX_FOLDER_ID is not set")
+	}
+
+	var key yandexgpt.ServiceAccountKey
+	if err := json.Unmarshal(cfg.ServiceAccountKey, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	privateKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+
+	httpConfig, err := newClientConfig(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := httpConfig.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if httpConfig.baseURL != "" {
+		cfg.BaseURL = httpConfig.baseURL
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = yandexGPTDefaultBaseURL
+	}
+	if cfg.IAMURL == "" {
+		cfg.IAMURL = yandexGPTDefaultIAMURL
+	}
+
+	provider := &YandexGPTProvider{
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		iamURL:      cfg.IAMURL,
+		folderID:    cfg.FolderID,
+		key:         key,
+		privateKey:  privateKey,
+		models:      newModelCache(),
+		toolsMapper: mappers.NewToolsMapper(),
+		httpClient:  httpClient,
+	}
+	provider.tokens = newTokenCache(provider.requestIAMToken)
+	provider.loadModels()
+
+	return provider, nil
+}
+
+// Name возвращает название провайдера.
+func (p *YandexGPTProvider) Name() string {
+	return yandexGPTProviderName
+}
+
+// SendMessage отправляет сообщения в модель через метод completion.
+func (p *YandexGPTProvider) SendMessage(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (*entities.ProviderMessageResponseDTO, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", newTransportError(yandexGPTProviderName, err))
+	}
+
+	var completionResponse yandexgpt.CompletionResponse
+	if err := json.Unmarshal(responseBody, &completionResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", newDecodeError(yandexGPTProviderName, responseBody, err))
+	}
+	if len(completionResponse.Result.Alternatives) == 0 {
+		return nil, fmt.Errorf("no alternatives in response: %w", newDecodeError(yandexGPTProviderName, responseBody, nil))
+	}
+
+	alternative := completionResponse.Result.Alternatives[0]
+	accumulator := newStreamAccumulator()
+	accumulator.addText(alternative.Message.Text)
+	deltas, err := yandexToolCallDeltas(alternative.Message.ToolCallList)
+	if err != nil {
+		return nil, err
+	}
+	for _, delta := range deltas {
+		accumulator.addToolCallDelta(delta)
+	}
+	accumulator.setFinishReason(mapYandexGPTStatus(alternative.Status))
+
+	result, err := accumulator.response(p.toolsMapper)
+	if err != nil {
+		return nil, err
+	}
+	applyYandexGPTUsage(result, modelID, completionResponse.Result.Usage)
+
+	return result, nil
+}
+
+// SendMessageStream отправляет сообщения в модель и возвращает ответ потоком.
+// YandexGPT присылает строки JSON, в которых текст ответа накапливается с начала, поэтому дельта вычисляется по разнице.
+func (p *YandexGPTProvider) SendMessageStream(ctx context.Context, messages []*entities.Message, modelName entities.ModelName, opts ...options.SendMessageOption) (<-chan entities.StreamChunk, error) {
+	modelID, request, err := p.buildRequest(messages, modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	request.CompletionOptions.Stream = true
+
+	response, err := p.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan entities.StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer response.Body.Close()
+
+		accumulator := newStreamAccumulator()
+		var usage *yandexgpt.Usage
+		var text string
+		toolCallsSent := false
+
+		err := utils.ReadJSONLines(response.Body, func(line []byte) error {
+			if err := streamPayloadError(yandexGPTProviderName, line); err != nil {
+				return err
+			}
+
+			var chunk yandexgpt.CompletionResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				return newDecodeError(yandexGPTProviderName, line, err)
+			}
+			if chunk.Result.Usage != nil {
+				usage = chunk.Result.Usage
+			}
+			if len(chunk.Result.Alternatives) == 0 {
+				return nil
+			}
+
+			alternative := chunk.Result.Alternatives[0]
+			accumulator.setFinishReason(mapYandexGPTStatus(alternative.Status))
+			if delta, found := strings.CutPrefix(alternative.Message.Text, text); found && delta != "" {
+				text = alternative.Message.Text
+				accumulator.addText(delta)
+				if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkText, TextDelta: delta}) {
+					return ctx.Err()
+				}
+			}
+			// Вызовы инструментов приходят целиком, но могут повторяться в следующих строках
+			if alternative.Message.ToolCallList != nil && !toolCallsSent {
+				toolCallsSent = true
+				deltas, err := yandexToolCallDeltas(alternative.Message.ToolCallList)
+				if err != nil {
+					return err
+				}
+				for _, delta := range deltas {
+					accumulator.addToolCallDelta(delta)
+					if !sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkToolCall, ToolCallDelta: &delta}) {
+						return ctx.Err()
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) && ctx.Err() == nil {
+				err = newTransportError(yandexGPTProviderName, err)
+			}
+			sendStreamError(ctx, chunks, fmt.Errorf("failed to read stream: %w", err))
+			return
+		}
+
+		result, err := accumulator.response(p.toolsMapper)
+		if err != nil {
+			sendStreamError(ctx, chunks, err)
+			return
+		}
+		applyYandexGPTUsage(result, modelID, usage)
+
+		sendStreamChunk(ctx, chunks, entities.StreamChunk{Type: entities.StreamChunkFinal, Response: result})
+	}()
+
+	return chunks, nil
+}
+
+// GetModelInfo получает информацию о конкретной модели из кэша.
+func (p *YandexGPTProvider) GetModelInfo(modelName entities.ModelName) (*entities.ModelInfo, error) {
+	if modelInfo := p.models.get(modelName); modelInfo != nil {
+		return modelInfo, nil
+	}
+	return nil, newModelNotFoundError(yandexGPTProviderName, string(modelName))
+}
+
+// ListModels возвращает информацию обо всех моделях из кэша.
+func (p *YandexGPTProvider) ListModels() ([]*entities.ModelInfo, error) {
+	return p.models.list(), nil
+}
+
+// RefreshModels заново заполняет кэш моделей из models.yaml.
+func (p *YandexGPTProvider) RefreshModels(ctx context.Context) error {
+	p.loadModels()
+	return nil
+}
+
+// buildRequest конвертирует сообщения и опции в запрос completion.
+// Возвращает URI модели без каталога и тело запроса.
+func (p *YandexGPTProvider) buildRequest(messages []*entities.Message, modelName entities.ModelName, opts []options.SendMessageOption) (string, *yandexgpt.CompletionRequest, error) {
+	modelID, exists := config.YandexGPTNamesMap[modelName]
+	if !exists {
+		return "", nil, newModelNotSupportedError(yandexGPTProviderName, string(modelName))
+	}
+
+	prepared, err := prepareMessages(messages, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := checkImageInput(yandexGPTProviderName, prepared, modelName, p.models.get(modelName)); err != nil {
+		return "", nil, err
+	}
+
+	completionMessages, err := p.convertToMessages(prepared)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert messages: %w", err)
+	}
+
+	request := &yandexgpt.CompletionRequest{
+		ModelURI: "gpt://" + p.folderID + "/" + modelID,
+		Messages: completionMessages,
+	}
+
+	// Обрабатываем MCP tools опцию если она есть
+	if mcpTools, hasMCPTools := options.ExtractMCPToolsOption(opts); hasMCPTools {
+		openaiTools, err := p.toolsMapper.MCPToolsToOpenAI(mcpTools)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to convert MCP tools to YandexGPT: %w", err)
+		}
+		request.Tools = make([]yandexgpt.Tool, len(openaiTools))
+		for i, openaiTool := range openaiTools {
+			var description string
+			if openaiTool.Function.Description != nil {
+				description = *openaiTool.Function.Description
+			}
+			request.Tools[i] = yandexgpt.Tool{Function: yandexgpt.Function{
+				Name:        openaiTool.Function.Name,
+				Description: description,
+				Parameters:  openaiTool.Function.Parameters,
+			}}
+		}
+	}
+
+	if responseFormat, hasResponseFormat := options.ExtractResponseFormatOption(opts); hasResponseFormat {
+		if responseFormat.Type == options.ResponseFormatJSONSchema && responseFormat.Schema != nil {
+			request.JSONSchema = &yandexgpt.JSONSchema{Schema: responseFormat.Schema}
+		} else {
+			request.JSONObject = true
+		}
+	}
+
+	params := options.ExtractGenerationParams(opts)
+	unsupported := []struct {
+		parameter string
+		set       bool
+	}{
+		{options.OptionTypeTopP, params.TopP != nil},
+		{options.OptionTypeStop, len(params.Stop) > 0},
+		{options.OptionTypeSeed, params.Seed != nil},
+		{options.OptionTypePresencePenalty, params.PresencePenalty != nil},
+		{options.OptionTypeFrequencyPenalty, params.FrequencyPenalty != nil},
+		{options.OptionTypeLogitBias, len(params.LogitBias) > 0},
+	}
+	for _, param := range unsupported {
+		if param.set {
+			return "", nil, &UnsupportedParameterError{Provider: yandexGPTProviderName, Parameter: param.parameter, Reason: "not supported by YandexGPT API"}
+		}
+	}
+	request.CompletionOptions.Temperature = params.Temperature
+	if params.MaxTokens != nil {
+		request.CompletionOptions.MaxTokens = strconv.Itoa(*params.MaxTokens)
+	}
+
+	return modelID, request, nil
+}
+
+// convertToMessages конвертирует внутренние сообщения в формат YandexGPT.
+// Результаты инструментов подряд собираются в одно сообщение пользователя с toolResultList.
+func (p *YandexGPTProvider) convertToMessages(messages []*entities.Message) ([]yandexgpt.Message, error) {
+	completionMessages := make([]yandexgpt.Message, 0, len(messages))
+	toolNames := make(map[string]string)
+
+	for i, msg := range messages {
+		switch msg.AuthorType {
+		case entities.AuthorTypeSystem, entities.AuthorTypeDeveloper:
+			completionMessages = append(completionMessages, yandexgpt.Message{Role: yandexgpt.RoleSystem, Text: msg.MessageText})
+		case entities.AuthorTypeUser, "":
+			completionMessages = append(completionMessages, yandexgpt.Message{Role: yandexgpt.RoleUser, Text: msg.MessageText})
+		case entities.AuthorTypeRobot:
+			completionMessage := yandexgpt.Message{Role: yandexgpt.RoleAssistant, Text: msg.MessageText}
+			if len(msg.ToolCalls) > 0 && len(msg.ToolCallIDs) != len(msg.ToolCalls) {
+				return nil, fmt.Errorf("assistant message at index %d has %d tool calls but %d tool_call_ids", i, len(msg.ToolCalls), len(msg.ToolCallIDs))
+			}
+			for j, mcpCall := range msg.ToolCalls {
+				openaiToolCall, err := p.toolsMapper.MCPToolCallToOpenAI(mcpCall)
+				if err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to YandexGPT: %w", j, err)
+				}
+				var arguments map[string]interface{}
+				if err := json.Unmarshal([]byte(openaiToolCall.Function.Arguments), &arguments); err != nil {
+					return nil, fmt.Errorf("failed to convert MCP tool call %d to YandexGPT: %w", j, err)
+				}
+				toolNames[msg.ToolCallIDs[j]] = openaiToolCall.Function.Name
+				if completionMessage.ToolCallList == nil {
+					completionMessage.ToolCallList = &yandexgpt.ToolCallList{}
+				}
+				completionMessage.ToolCallList.ToolCalls = append(completionMessage.ToolCallList.ToolCalls, yandexgpt.ToolCall{
+					FunctionCall: yandexgpt.FunctionCall{Name: openaiToolCall.Function.Name, Arguments: arguments},
+				})
+			}
+			completionMessages = append(completionMessages, completionMessage)
+		case entities.AuthorTypeTool:
+			if len(msg.ToolCallIDs) == 0 || msg.ToolCallIDs[0] == "" {
+				return nil, fmt.Errorf("tool message at index %d missing tool_call_id", i)
+			}
+			name, exists := toolNames[msg.ToolCallIDs[0]]
+			if !exists {
+				return nil, fmt.Errorf("tool message at index %d references unknown tool call %q", i, msg.ToolCallIDs[0])
+			}
+			result := yandexgpt.ToolResult{FunctionResult: yandexgpt.FunctionResult{Name: name, Content: msg.MessageText}}
+			if last := len(completionMessages) - 1; last >= 0 && completionMessages[last].ToolResultList != nil {
+				completionMessages[last].ToolResultList.ToolResults = append(completionMessages[last].ToolResultList.ToolResults, result)
+				continue
+			}
+			completionMessages = append(completionMessages, yandexgpt.Message{
+				Role:           yandexgpt.RoleUser,
+				ToolResultList: &yandexgpt.ToolResultList{ToolResults: []yandexgpt.ToolResult{result}},
+			})
+		default:
+			return nil, fmt.Errorf("message at index %d has unknown author type %q", i, msg.AuthorType)
+		}
+	}
+
+	return completionMessages, nil
+}
+
+// post отправляет запрос completion с IAM токеном и возвращает ответ со статусом 200.
+// Если сервер отклонил токен до истечения срока, токен обновляется и запрос повторяется один раз.
+func (p *YandexGPTProvider) post(ctx context.Context, request *yandexgpt.CompletionRequest) (*http.Response, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := p.tokens.get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IAM token: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/completion", bytes.NewReader(requestBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("x-folder-id", p.folderID)
+
+		response, err := p.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", newTransportError(yandexGPTProviderName, err))
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			response.Body.Close()
+			p.tokens.invalidate(token)
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			defer response.Body.Close()
+			responseBody, _ := io.ReadAll(response.Body)
+			return nil, fmt.Errorf("API request failed: %w", newHTTPError(yandexGPTProviderName, response.StatusCode, response.Header, responseBody))
+		}
+
+		return response, nil
+	}
+}
+
+// requestIAMToken обменивает JWT, подписанный ключом сервисного аккаунта, на IAM токен.
+func (p *YandexGPTProvider) requestIAMToken(ctx context.Context) (string, time.Time, error) {
+	jwt, err := p.signedJWT(time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	requestBody, err := json.Marshal(yandexgpt.IAMTokenRequest{JWT: jwt})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.iamURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, newTransportError(yandexGPTProviderName, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", time.Time{}, newTransportError(yandexGPTProviderName, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", time.Time{}, newHTTPError(yandexGPTProviderName, response.StatusCode, response.Header, body)
+	}
+
+	var tokenResponse yandexgpt.IAMTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil || tokenResponse.IAMToken == "" {
+		return "", time.Time{}, newDecodeError(yandexGPTProviderName, body, err)
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, tokenResponse.ExpiresAt)
+	if err != nil {
+		return "", time.Time{}, newDecodeError(yandexGPTProviderName, body, err)
+	}
+	return tokenResponse.IAMToken, expiresAt, nil
+}
+
+// signedJWT создает JWT для обмена на IAM токен, подписанный алгоритмом PS256.
+func (p *YandexGPTProvider) signedJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": "PS256", "kid": p.key.ID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": yandexIAMAudience,
+		"iss": p.key.ServiceAccountID,
+		"iat": now.Unix(),
+		"exp": now.Add(yandexJWTLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPSS(rand.Reader, p.privateKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// loadModels заполняет кэш моделями из раздела yandexgpt в models.yaml.
+func (p *YandexGPTProvider) loadModels() {
+	modelsInfo := make(map[entities.ModelName]*entities.ModelInfo)
+	for ourModelName, modelID := range config.YandexGPTNamesMap {
+		price, _ := findRublePrice(yandexGPTPrices, modelID)
+		modelsInfo[ourModelName] = &entities.ModelInfo{
+			Name:            modelID,
+			Alias:           ourModelName,
+			PriceInRubles:   price.priceInRubles(),
+			InputModalities: []string{entities.ModalityText},
+		}
+	}
+	p.models.replace(modelsInfo)
+}
+
+// applyYandexGPTUsage заполняет токены и стоимость в рублях из usage.
+func applyYandexGPTUsage(result *entities.ProviderMessageResponseDTO, modelID string, usage *yandexgpt.Usage) {
+	if usage == nil {
+		return
+	}
+	result.TotalTokens = usage.TotalTokens
+	if price, exists := findRublePrice(yandexGPTPrices, modelID); exists {
+		result.PriceInRubles = price.cost(usage.TotalTokens)
+	}
+}
+
+// yandexToolCallDeltas конвертирует вызовы инструментов YandexGPT в дельты с новыми ID.
+func yandexToolCallDeltas(toolCallList *yandexgpt.ToolCallList) ([]entities.ToolCallDelta, error) {
+	if toolCallList == nil {
+		return nil, nil
+	}
+	deltas := make([]entities.ToolCallDelta, len(toolCallList.ToolCalls))
+	for i, toolCall := range toolCallList.ToolCalls {
+		arguments := toolCall.FunctionCall.Arguments
+		if arguments == nil {
+			arguments = map[string]interface{}{}
+		}
+		argumentsJSON, err := json.Marshal(arguments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tool call arguments: %w", err)
+		}
+		deltas[i] = entities.ToolCallDelta{
+			Index:          i,
+			ID:             newToolCallID(),
+			Name:           toolCall.FunctionCall.Name,
+			ArgumentsDelta: string(argumentsJSON),
+		}
+	}
+	return deltas, nil
+}
+
+// mapYandexGPTStatus маппит статус варианта ответа YandexGPT в общие константы entities.
+func mapYandexGPTStatus(status string) *string {
+	var mappedReason string
+	switch status {
+	case "", yandexgpt.StatusPartial:
+		return nil
+	case yandexgpt.StatusFinal:
+		mappedReason = entities.FinishReasonStop
+	case yandexgpt.StatusTruncatedFinal:
+		mappedReason = entities.FinishReasonLength
+	case yandexgpt.StatusToolCalls:
+		mappedReason = entities.FinishReasonToolCalls
+	case yandexgpt.StatusContentFilter:
+		mappedReason = entities.FinishReasonContentFilter
+	default:
+		mappedReason = status
+	}
+	return &mappedReason
+}
+
+// parseRSAPrivateKey разбирает закрытый ключ RSA в PEM (PKCS#8 или PKCS#1).
+// Строки перед блоком PEM (Yandex Cloud добавляет предупреждение в начало ключа) пропускаются.
+func parseRSAPrivateKey(pemData string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not RSA")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/internal/entities/yandexgpt"
+	"github.com/Murolando/m_ai_provider/options"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// newFakeYandexGPTServer создает IAM сервер, проверяющий подпись JWT открытым ключом, и completion endpoint.
+// Возвращает сервер и авторизованный ключ сервисного аккаунта в формате authorized_key.json.
+func newFakeYandexGPTServer(t *testing.T, handler func(w http.ResponseWriter, request yandexgpt.CompletionRequest)) (*httptest.Server, []byte) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	keyJSON, _ := json.Marshal(map[string]string{
+		"id":                 "key-id",
+		"service_account_id": "sa-id",
+		"key_algorithm":      "RSA_2048",
+		"private_key":        "PLEASE DO NOT REMOVE THIS LINE! Yandex.Cloud SA Key ID <key-id>\n" + string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iam/v1/tokens":
+			var request yandexgpt.IAMTokenRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			if err := verifyYandexJWT(&privateKey.PublicKey, request.JWT); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code": 16, "message": "` + err.Error() + `"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(yandexgpt.IAMTokenResponse{IAMToken: "iam-token", ExpiresAt: time.Now().Add(12 * time.Hour).Format(time.RFC3339Nano)})
+		case "/foundationModels/v1/completion":
+			if r.Header.Get("Authorization") != "Bearer iam-token" || r.Header.Get("x-folder-id") != "folder-id" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": {"grpcCode": 16, "httpCode": 401, "message": "The token is invalid", "httpStatus": "Unauthorized"}}`))
+				return
+			}
+			var request yandexgpt.CompletionRequest
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			handler(w, request)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, keyJSON
+}
+
+// verifyYandexJWT проверяет подпись PS256 и обязательные поля JWT так же, как IAM.
+func verifyYandexJWT(publicKey *rsa.PublicKey, jwt string) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed jwt")
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPSS(publicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+		return err
+	}
+
+	var header, claims map[string]interface{}
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(headerJSON, &header)
+	json.Unmarshal(claimsJSON, &claims)
+	if header["alg"] != "PS256" || header["kid"] != "key-id" || claims["iss"] != "sa-id" || claims["aud"] != yandexIAMAudience {
+		return errors.New("unexpected jwt header or claims")
+	}
+	return nil
+}
+
+func newTestYandexGPTProvider(t *testing.T, server *httptest.Server, key []byte) *YandexGPTProvider {
+	p, err := NewYandexGPTProvider(YandexGPTConfig{
+		ServiceAccountKey: key,
+		FolderID:          "folder-id",
+		IAMURL:            server.URL + "/iam/v1/tokens",
+		BaseURL:           server.URL + "/foundationModels/v1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return p
+}
+
+func TestYandexGPTSendMessageWithToolCalls(t *testing.T) {
+	var received yandexgpt.CompletionRequest
+	server, key := newFakeYandexGPTServer(t, func(w http.ResponseWriter, request yandexgpt.CompletionRequest) {
+		received = request
+		w.Write([]byte(`{"result": {
+			"alternatives": [{
+				"message": {"role": "assistant", "toolCallList": {"toolCalls": [{"functionCall": {"name": "get_weather", "arguments": {"city": "Казань"}}}]}},
+				"status": "ALTERNATIVE_STATUS_TOOL_CALLS"
+			}],
+			"usage": {"inputTextTokens": "1500", "completionTokens": "500", "totalTokens": "2000"},
+			"modelVersion": "23.10.2024"
+		}}`))
+	})
+	defer server.Close()
+
+	p := newTestYandexGPTProvider(t, server, key)
+
+	modelInfo, err := p.GetModelInfo("yandexgpt-lite")
+	if err != nil || modelInfo.Name != "yandexgpt-lite/latest" || modelInfo.PriceInRubles.String() != "400" {
+		t.Fatalf("Expected yandexgpt-lite with ruble price, got %+v, %v", modelInfo, err)
+	}
+
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Погода в Москве и Казани?", AuthorType: entities.AuthorTypeUser},
+		{
+			AuthorType: entities.AuthorTypeRobot,
+			ToolCalls: []mcpgo.CallToolRequest{
+				{Params: mcpgo.CallToolParams{Name: "get_weather", Arguments: map[string]interface{}{"city": "Москва"}}},
+				{Params: mcpgo.CallToolParams{Name: "get_time", Arguments: map[string]interface{}{"city": "Казань"}}},
+			},
+			ToolCallIDs: []string{"call_1", "call_2"},
+		},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+		{MessageText: "12:00", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_2"}},
+	}
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithString("city", mcpgo.Required()))
+
+	response, err := p.SendMessage(context.Background(), messages, "yandexgpt-pro", options.WithMCPTools([]mcpgo.Tool{tool}), options.WithMaxTokens(100), options.WithTemperature(0.3))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+
+	if received.ModelURI != "gpt://folder-id/yandexgpt/latest" || received.CompletionOptions.MaxTokens != "100" || *received.CompletionOptions.Temperature != 0.3 {
+		t.Errorf("Unexpected model or options: %s, %+v", received.ModelURI, received.CompletionOptions)
+	}
+	if len(received.Tools) != 1 || received.Tools[0].Function.Name != "get_weather" {
+		t.Errorf("Unexpected tools: %+v", received.Tools)
+	}
+	if len(received.Messages) != 4 {
+		t.Fatalf("Expected tool results merged into one message, got %+v", received.Messages)
+	}
+	if calls := received.Messages[2].ToolCallList; calls == nil || len(calls.ToolCalls) != 2 || calls.ToolCalls[0].FunctionCall.Arguments["city"] != "Москва" {
+		t.Errorf("Expected assistant tool calls, got %+v", received.Messages[2])
+	}
+	results := received.Messages[3].ToolResultList
+	if received.Messages[3].Role != yandexgpt.RoleUser || results == nil || len(results.ToolResults) != 2 || results.ToolResults[1].FunctionResult.Name != "get_time" {
+		t.Errorf("Expected tool results with names, got %+v", received.Messages[3])
+	}
+
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Params.Name != "get_weather" || response.ToolCallIDs[0] == "" {
+		t.Fatalf("Unexpected tool calls: %+v", response)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+	// 2000 токенов по 1200 рублей за миллион
+	if response.TotalTokens != 2000 || response.PriceInRubles.String() != "2.4" {
+		t.Errorf("Unexpected usage: tokens %d, price %s", response.TotalTokens, response.PriceInRubles)
+	}
+
+	if _, err := p.SendMessage(context.Background(), messages, "yandexgpt-pro", options.WithTopP(0.5)); !errors.Is(err, ErrUnsupportedParameter) {
+		t.Errorf("Expected ErrUnsupportedParameter for top_p, got %v", err)
+	}
+}
+
+func TestYandexGPTSendMessageStream(t *testing.T) {
+	server, key := newFakeYandexGPTServer(t, func(w http.ResponseWriter, request yandexgpt.CompletionRequest) {
+		if !request.CompletionOptions.Stream || !request.JSONObject {
+			t.Errorf("Expected stream request with json object, got %+v", request)
+		}
+		// Текст в каждой строке накапливается с начала ответа
+		w.Write([]byte(`{"result": {"alternatives": [{"message": {"role": "assistant", "text": "{\"a\""}, "status": "ALTERNATIVE_STATUS_PARTIAL"}], "usage": {"inputTextTokens": "10", "completionTokens": "2", "totalTokens": "12"}}}` + "\n"))
+		w.Write([]byte(`{"result": {"alternatives": [{"message": {"role": "assistant", "text": "{\"a\": 1}"}, "status": "ALTERNATIVE_STATUS_FINAL"}], "usage": {"inputTextTokens": "10", "completionTokens": "5", "totalTokens": "15"}}}` + "\n"))
+	})
+	defer server.Close()
+
+	p := newTestYandexGPTProvider(t, server, key)
+	messages := []*entities.Message{{MessageText: "Ответь JSON", AuthorType: entities.AuthorTypeUser}}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, "yandexgpt-lite", options.WithJSONObject())
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+
+	var deltas []string
+	var final *entities.ProviderMessageResponseDTO
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			deltas = append(deltas, chunk.TextDelta)
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+
+	if len(deltas) != 2 || deltas[1] != ": 1}" || final == nil || final.MessageText != `{"a": 1}` || final.TotalTokens != 15 {
+		t.Fatalf("Unexpected stream: deltas %q, final %+v", deltas, final)
+	}
+	if final.FinishReason == nil || *final.FinishReason != entities.FinishReasonStop {
+		t.Errorf("Expected finish reason stop, got %v", final.FinishReason)
+	}
+
+	// Ключ другого сервисного аккаунта не проходит проверку подписи
+	otherServer, otherKey := newFakeYandexGPTServer(t, nil)
+	otherServer.Close()
+	other := newTestYandexGPTProvider(t, server, otherKey)
+	if _, err := other.SendMessage(context.Background(), messages, "yandexgpt-lite"); !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected ErrAuth for invalid JWT signature, got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Murolando/m_ai_provider/entities"
+	"github.com/Murolando/m_ai_provider/options"
+	"github.com/Murolando/m_ai_provider/provider"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/shopspring/decimal"
+)
+
+// cancelTimeout время, за которое провайдер должен вернуть ошибку после отмены контекста.
+const cancelTimeout = 5 * time.Second
+
+// Backend сервер, с которым работает провайдер в проверках. Запросы и ответы описываются
+// в терминах OpenAI Chat Completions; фейковый сервер другого API должен приводить к ним свои.
+type Backend interface {
+	Enqueue(replies ...Reply) // Добавляет ответы на следующие запросы
+	LastRequest() *Request    // Возвращает последний запрос провайдера
+}
+
+var _ Backend = (*Server)(nil)
+
+// Suite описывает провайдера для общего набора проверок.
+type Suite struct {
+	Model entities.ModelName                              // Модель, на которой выполняются проверки
+	New   func(t *testing.T) (provider.Provider, Backend) // Создает провайдер и сервер для одной проверки (закрытие через t.Cleanup)
+	Usage map[string]interface{}                          // usage ответа в проверке стоимости (по умолчанию 12 + 8 токенов)
+	Price decimal.Decimal                                 // Ожидаемая стоимость ответа с Usage
+}
+
+// Run проверяет, что провайдер одинаково с остальными передает роли и вызовы инструментов,
+// считает токены и стоимость, классифицирует ошибки, прерывает запросы при отмене и отдает потоковые ответы.
+func Run(t *testing.T, suite Suite) {
+	if suite.Usage == nil {
+		suite.Usage = map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20}
+	}
+
+	t.Run("Roles", func(t *testing.T) { testRoles(t, suite) })
+	t.Run("Tools", func(t *testing.T) { testTools(t, suite) })
+	t.Run("Usage", func(t *testing.T) { testUsage(t, suite) })
+	t.Run("Errors", func(t *testing.T) { testErrors(t, suite) })
+	t.Run("Cancellation", func(t *testing.T) { testCancellation(t, suite) })
+	t.Run("Streaming", func(t *testing.T) { testStreaming(t, suite) })
+}
+
+// testRoles проверяет передачу ролей, вызовов инструментов из истории и результатов инструментов.
+func testRoles(t *testing.T, suite Suite) {
+	p, backend := suite.New(t)
+	backend.Enqueue(Text("В Москве +5"))
+
+	toolCall := mcpgo.CallToolRequest{}
+	toolCall.Params.Name = "get_weather"
+	toolCall.Params.Arguments = map[string]interface{}{"city": "Москва"}
+	messages := []*entities.Message{
+		{MessageText: "Ты синоптик", AuthorType: entities.AuthorTypeSystem},
+		{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser},
+		{AuthorType: entities.AuthorTypeRobot, ToolCalls: []mcpgo.CallToolRequest{toolCall}, ToolCallIDs: []string{"call_1"}},
+		{MessageText: "+5", AuthorType: entities.AuthorTypeTool, ToolCallIDs: []string{"call_1"}},
+	}
+
+	response, err := p.SendMessage(context.Background(), messages, suite.Model)
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	if response.MessageText != "В Москве +5" {
+		t.Errorf("Expected response text 'В Москве +5', got %q", response.MessageText)
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonStop {
+		t.Errorf("Expected finish reason stop, got %v", response.FinishReason)
+	}
+
+	request := backend.LastRequest()
+	if request == nil {
+		t.Fatal("Expected request to reach the server")
+	}
+	if roles := request.Roles(); !reflect.DeepEqual(roles, []string{"system", "user", "assistant", "tool"}) {
+		t.Fatalf("Expected roles system, user, assistant, tool, got %v", roles)
+	}
+	if request.Messages[0].Content != "Ты синоптик" || request.Messages[1].Content != "Какая погода в Москве?" {
+		t.Errorf("Expected message texts to be sent as is, got %+v", request.Messages[:2])
+	}
+	assistant := request.Messages[2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_1" || assistant.ToolCalls[0].Name != "get_weather" {
+		t.Errorf("Expected assistant tool call call_1/get_weather, got %+v", assistant.ToolCalls)
+	} else if !sameJSON(assistant.ToolCalls[0].Arguments, `{"city": "Москва"}`) {
+		t.Errorf("Expected tool call arguments to round-trip, got %s", assistant.ToolCalls[0].Arguments)
+	}
+	if request.Messages[3].ToolCallID != "call_1" || request.Messages[3].Content != "+5" {
+		t.Errorf("Expected tool result for call_1, got %+v", request.Messages[3])
+	}
+}
+
+// testTools проверяет передачу MCP инструментов и разбор вызовов инструментов в ответе.
+func testTools(t *testing.T, suite Suite) {
+	p, backend := suite.New(t)
+	backend.Enqueue(ToolCalls(ToolCall{ID: "call_abc", Name: "get_weather", Arguments: `{"city": "Москва"}`}))
+
+	tool := mcpgo.NewTool("get_weather",
+		mcpgo.WithDescription("Get the current weather"),
+		mcpgo.WithString("city", mcpgo.Required()),
+	)
+	messages := []*entities.Message{{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser}}
+
+	response, err := p.SendMessage(context.Background(), messages, suite.Model, options.WithMCPTools([]mcpgo.Tool{tool}))
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	if request := backend.LastRequest(); request == nil || !reflect.DeepEqual(request.Tools, []string{"get_weather"}) {
+		t.Errorf("Expected tool get_weather in request, got %+v", request)
+	}
+	checkToolCallResponse(t, response)
+}
+
+// testUsage проверяет количество токенов и стоимость ответа.
+func testUsage(t *testing.T, suite Suite) {
+	p, backend := suite.New(t)
+	backend.Enqueue(Reply{Text: "Готово", Usage: suite.Usage})
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	response, err := p.SendMessage(context.Background(), messages, suite.Model)
+	if err != nil {
+		t.Fatalf("SendMessage() failed with error: %v", err)
+	}
+	checkUsage(t, suite, response)
+}
+
+// testErrors проверяет категории HTTP ошибок для обычных и потоковых запросов.
+func testErrors(t *testing.T, suite Suite) {
+	tests := []struct {
+		name       string
+		reply      Reply
+		kind       error
+		retryAfter time.Duration
+	}{
+		{name: "Auth", reply: Error(401, "invalid_api_key", "Invalid API key"), kind: provider.ErrAuth},
+		{name: "RateLimit", reply: Reply{Status: 429, ErrorCode: "rate_limit_exceeded", ErrorMessage: "Too many requests", Header: map[string]string{"Retry-After": "7"}}, kind: provider.ErrRateLimit, retryAfter: 7 * time.Second},
+		{name: "Server", reply: Error(500, "server_error", "Internal error"), kind: provider.ErrServer},
+	}
+
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, backend := suite.New(t)
+			backend.Enqueue(tt.reply, tt.reply)
+
+			_, err := p.SendMessage(context.Background(), messages, suite.Model)
+			checkProviderError(t, err, tt.kind, tt.retryAfter)
+
+			chunks, err := p.SendMessageStream(context.Background(), messages, suite.Model)
+			if err == nil {
+				_, _, _, err = collectStream(chunks)
+			}
+			checkProviderError(t, err, tt.kind, tt.retryAfter)
+		})
+	}
+}
+
+// testCancellation проверяет, что отмена контекста прерывает ожидание ответа.
+func testCancellation(t *testing.T, suite Suite) {
+	p, backend := suite.New(t)
+	backend.Enqueue(Reply{Hang: true}, Reply{Hang: true})
+	messages := []*entities.Message{{MessageText: "Привет", AuthorType: entities.AuthorTypeUser}}
+
+	calls := map[string]func(ctx context.Context) error{
+		"SendMessage": func(ctx context.Context) error {
+			_, err := p.SendMessage(ctx, messages, suite.Model)
+			return err
+		},
+		"SendMessageStream": func(ctx context.Context) error {
+			chunks, err := p.SendMessageStream(ctx, messages, suite.Model)
+			if err != nil {
+				return err
+			}
+			_, _, _, err = collectStream(chunks)
+			return err
+		},
+	}
+	for name, call := range calls {
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(50*time.Millisecond, cancel)
+
+		done := make(chan error, 1)
+		go func() { done <- call(ctx) }()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%s: expected context.Canceled, got %v", name, err)
+			}
+		case <-time.After(cancelTimeout):
+			t.Errorf("%s did not return after context cancellation", name)
+		}
+		timer.Stop()
+		cancel()
+	}
+}
+
+// testStreaming проверяет потоковые ответы: текст, вызовы инструментов и ошибку посреди потока.
+func testStreaming(t *testing.T, suite Suite) {
+	p, backend := suite.New(t)
+	backend.Enqueue(
+		Reply{Text: "В Москве сейчас +5", Usage: suite.Usage},
+		ToolCalls(ToolCall{ID: "call_abc", Name: "get_weather", Arguments: `{"city": "Москва"}`}),
+		Reply{Text: "Начало ответа", StreamError: true, ErrorCode: "server_error", ErrorMessage: "Upstream failed"},
+	)
+	messages := []*entities.Message{{MessageText: "Какая погода в Москве?", AuthorType: entities.AuthorTypeUser}}
+
+	chunks, err := p.SendMessageStream(context.Background(), messages, suite.Model)
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	text, _, final, err := collectStream(chunks)
+	if err != nil {
+		t.Fatalf("Unexpected stream error: %v", err)
+	}
+	if text != "В Москве сейчас +5" || final == nil || final.MessageText != text {
+		t.Fatalf("Expected streamed and final text 'В Москве сейчас +5', got %q and %+v", text, final)
+	}
+	if request := backend.LastRequest(); request == nil || !request.Stream {
+		t.Errorf("Expected stream=true in request, got %+v", request)
+	}
+	checkUsage(t, suite, final)
+
+	tool := mcpgo.NewTool("get_weather", mcpgo.WithString("city", mcpgo.Required()))
+	chunks, err = p.SendMessageStream(context.Background(), messages, suite.Model, options.WithMCPTools([]mcpgo.Tool{tool}))
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	_, toolCallChunks, final, err := collectStream(chunks)
+	if err != nil {
+		t.Fatalf("Unexpected stream error: %v", err)
+	}
+	if toolCallChunks == 0 {
+		t.Error("Expected tool call chunks in stream")
+	}
+	if final == nil {
+		t.Fatal("Expected final chunk")
+	}
+	checkToolCallResponse(t, final)
+
+	chunks, err = p.SendMessageStream(context.Background(), messages, suite.Model)
+	if err != nil {
+		t.Fatalf("SendMessageStream() failed with error: %v", err)
+	}
+	_, _, final, err = collectStream(chunks)
+	var providerErr *provider.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Errorf("Expected *ProviderError for error event in stream, got %v", err)
+	}
+	if final != nil {
+		t.Errorf("Expected no final chunk after error, got %+v", final)
+	}
+}
+
+// checkToolCallResponse проверяет ответ с вызовом get_weather из ToolCalls(call_abc).
+func checkToolCallResponse(t *testing.T, response *entities.ProviderMessageResponseDTO) {
+	t.Helper()
+
+	if len(response.ToolCalls) != 1 || len(response.ToolCallIDs) != 1 {
+		t.Fatalf("Expected 1 tool call with ID, got %+v", response)
+	}
+	if response.ToolCalls[0].Params.Name != "get_weather" || response.ToolCalls[0].GetArguments()["city"] != "Москва" {
+		t.Errorf("Expected get_weather(city=Москва), got %+v", response.ToolCalls[0].Params)
+	}
+	if response.ToolCallIDs[0] != "call_abc" {
+		t.Errorf("Expected tool call ID call_abc, got %s", response.ToolCallIDs[0])
+	}
+	if response.FinishReason == nil || *response.FinishReason != entities.FinishReasonToolCalls {
+		t.Errorf("Expected finish reason tool_calls, got %v", response.FinishReason)
+	}
+}
+
+// checkUsage проверяет токены и стоимость ответа с usage из Suite.
+func checkUsage(t *testing.T, suite Suite, response *entities.ProviderMessageResponseDTO) {
+	t.Helper()
+
+	if totalTokens := usageTotalTokens(suite.Usage); response.TotalTokens != totalTokens {
+		t.Errorf("Expected %d total tokens, got %d", totalTokens, response.TotalTokens)
+	}
+	if !response.PriceInRubles.Equal(suite.Price) {
+		t.Errorf("Expected price %s, got %s", suite.Price, response.PriceInRubles)
+	}
+}
+
+// checkProviderError проверяет категорию ошибки и значение Retry-After.
+func checkProviderError(t *testing.T, err error, kind error, retryAfter time.Duration) {
+	t.Helper()
+
+	if !errors.Is(err, kind) {
+		t.Errorf("Expected %v, got %v", kind, err)
+		return
+	}
+	var providerErr *provider.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Errorf("Expected *ProviderError, got %T", err)
+		return
+	}
+	if providerErr.RetryAfter != retryAfter {
+		t.Errorf("Expected Retry-After %s, got %s", retryAfter, providerErr.RetryAfter)
+	}
+}
+
+// collectStream читает поток до конца и возвращает текст, количество чанков с вызовами инструментов,
+// итоговый ответ и ошибку из потока.
+func collectStream(chunks <-chan entities.StreamChunk) (string, int, *entities.ProviderMessageResponseDTO, error) {
+	var text string
+	var toolCallChunks int
+	var final *entities.ProviderMessageResponseDTO
+	var err error
+	for chunk := range chunks {
+		switch chunk.Type {
+		case entities.StreamChunkText:
+			text += chunk.TextDelta
+		case entities.StreamChunkToolCall:
+			toolCallChunks++
+		case entities.StreamChunkFinal:
+			final = chunk.Response
+		case entities.StreamChunkError:
+			err = chunk.Err
+		}
+	}
+	return text, toolCallChunks, final, err
+}
+
+// usageTotalTokens возвращает total_tokens из usage.
+func usageTotalTokens(usage map[string]interface{}) int64 {
+	switch total := usage["total_tokens"].(type) {
+	case int:
+		return int64(total)
+	case int64:
+		return total
+	case float64:
+		return int64(total)
+	default:
+		return 0
+	}
+}
+
+// sameJSON сравнивает два JSON документа без учета форматирования.
+func sameJSON(a string, b string) bool {
+	var left, right interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
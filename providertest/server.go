@@ -0,0 +1,452 @@
+// Package providertest содержит фейковый OpenAI-совместимый HTTP сервер и общий набор проверок,
+// который должен проходить любой провайдер.
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// defaultUSDToRUBRate курс доллара, который сервер отдает по адресу курсов ЦБ РФ.
+const defaultUSDToRUBRate = 90.0
+
+// ToolCall описывает вызов инструмента в ответе сервера или в запросе провайдера.
+type ToolCall struct {
+	ID        string // ID вызова (в ответе по умолчанию call_1, call_2, ...)
+	Name      string // Название инструмента
+	Arguments string // Аргументы в JSON
+}
+
+// Reply описывает ответ сервера на один запрос к /chat/completions.
+// Если в запросе stream=true, ответ отдается в формате SSE: текст по словам, аргументы инструментов по частям.
+type Reply struct {
+	Text         string                 // Текст ответа
+	ToolCalls    []ToolCall             // Вызовы инструментов
+	FinishReason string                 // Причина завершения (по умолчанию stop или tool_calls)
+	Usage        map[string]interface{} // Поле usage (по умолчанию токены считаются по словам ответа)
+	Header       map[string]string      // Дополнительные заголовки ответа (например, Retry-After)
+	Status       int                    // HTTP статус ошибки: сервер отвечает телом {"error": ...} вместо ответа модели
+	ErrorCode    string                 // Код ошибки в теле ответа
+	ErrorMessage string                 // Текст ошибки в теле ответа
+	StreamError  bool                   // В потоке отдать текст, затем событие {"error": ...} без завершения ответа
+	Hang         bool                   // Не отвечать, пока клиент не отменит запрос
+}
+
+// Text создает текстовый ответ.
+func Text(text string) Reply {
+	return Reply{Text: text}
+}
+
+// ToolCalls создает ответ с вызовами инструментов.
+func ToolCalls(calls ...ToolCall) Reply {
+	return Reply{ToolCalls: calls}
+}
+
+// Error создает ответ с HTTP ошибкой.
+func Error(status int, code string, message string) Reply {
+	return Reply{Status: status, ErrorCode: code, ErrorMessage: message}
+}
+
+// Message описывает сообщение из запроса провайдера.
+type Message struct {
+	Role       string     // Роль автора (system, user, assistant, tool)
+	Content    string     // Текст сообщения (текстовые части объединяются)
+	ToolCallID string     // ID вызова, на который отвечает сообщение с ролью tool
+	ToolCalls  []ToolCall // Вызовы инструментов в сообщении ассистента
+}
+
+// Request описывает запрос провайдера к /chat/completions.
+type Request struct {
+	Header   http.Header            // Заголовки запроса
+	Model    string                 // Модель
+	Messages []Message              // Сообщения
+	Tools    []string               // Названия переданных инструментов
+	Stream   bool                   // true для потокового запроса
+	Body     map[string]interface{} // Тело запроса целиком
+}
+
+// Roles возвращает роли сообщений запроса по порядку.
+func (r *Request) Roles() []string {
+	roles := make([]string, len(r.Messages))
+	for i, message := range r.Messages {
+		roles[i] = message.Role
+	}
+	return roles
+}
+
+// Server фейковый OpenAI-совместимый сервер. Отвечает на /models списком моделей,
+// на /chat/completions - ответами из очереди, а на /scripts/XML_daily.asp - курсом доллара в формате ЦБ РФ.
+// Пути сравниваются по окончанию, поэтому базовый URL провайдера может содержать префикс (например, /v1).
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	models    []byte        // Тело ответа на /models
+	usdRate   float64       // Курс доллара к рублю
+	replies   []Reply       // Очередь ответов
+	requests  []*Request    // Полученные запросы
+	release   chan struct{} // Закрывается в Close, чтобы завершить ответы с Hang
+	closeOnce sync.Once
+}
+
+// NewServer запускает фейковый сервер с пустым списком моделей. Сервер нужно закрыть через Close.
+func NewServer() *Server {
+	s := &Server{
+		models:  []byte(`{"object": "list", "data": []}`),
+		usdRate: defaultUSDToRUBRate,
+		release: make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close освобождает зависшие запросы и останавливает сервер.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.release) })
+	s.Server.Close()
+}
+
+// SetModels задает тело ответа на /models в формате провайдера.
+func (s *Server) SetModels(body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = []byte(body)
+}
+
+// SetUSDToRUBRate задает курс доллара к рублю, который отдается в формате ЦБ РФ.
+func (s *Server) SetUSDToRUBRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usdRate = rate
+}
+
+// Enqueue добавляет ответы в очередь. Каждый запрос к /chat/completions получает следующий ответ;
+// если очередь пуста, сервер отвечает ошибкой 500.
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Requests возвращает полученные запросы к /chat/completions по порядку.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// LastRequest возвращает последний запрос к /chat/completions или nil.
+func (s *Server) LastRequest() *Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// Transport возвращает транспорт, который отправляет на сервер запросы к любому хосту.
+// Нужен провайдерам, которые обращаются к нескольким сервисам (например, к ЦБ РФ за курсом доллара).
+func (s *Server) Transport() http.RoundTripper {
+	target, _ := url.Parse(s.URL)
+	return &redirectTransport{target: target, next: s.Client().Transport}
+}
+
+// handle выбирает обработчик по окончанию пути.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.handleChat(w, r)
+	case strings.HasSuffix(r.URL.Path, "/models"):
+		s.mu.Lock()
+		models := s.models
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(models)
+	case strings.HasSuffix(r.URL.Path, "/XML_daily.asp"):
+		s.handleRates(w)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "unknown path "+r.URL.Path)
+	}
+}
+
+// handleChat записывает запрос и отвечает следующим ответом из очереди.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	request, err := parseRequest(r.Header, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	if len(s.replies) == 0 {
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, "no_reply", "providertest: no reply queued")
+		return
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	s.mu.Unlock()
+
+	if reply.Hang {
+		select {
+		case <-r.Context().Done():
+		case <-s.release:
+		}
+		return
+	}
+
+	for key, value := range reply.Header {
+		w.Header().Set(key, value)
+	}
+	switch {
+	case reply.Status != 0:
+		writeError(w, reply.Status, reply.ErrorCode, reply.ErrorMessage)
+	case request.Stream:
+		writeStream(w, request, reply)
+	case reply.StreamError:
+		writeError(w, http.StatusInternalServerError, reply.ErrorCode, reply.ErrorMessage)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"model":   request.Model,
+			"choices": []interface{}{completionChoice(reply)},
+			"usage":   replyUsage(reply),
+		})
+	}
+}
+
+// handleRates отдает курс доллара в формате ЦБ РФ (windows-1251, запятая в качестве разделителя).
+func (s *Server) handleRates(w http.ResponseWriter) {
+	s.mu.Lock()
+	rate := strings.Replace(strconv.FormatFloat(s.usdRate, 'f', 4, 64), ".", ",", 1)
+	s.mu.Unlock()
+
+	body, _ := charmap.Windows1251.NewEncoder().String(`<?xml version="1.0" encoding="windows-1251"?>` +
+		`<ValCurs Date="01.01.2026" name="Foreign Currency Market">` +
+		`<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>Доллар США</Name><Value>` + rate + `</Value></Valute>` +
+		`</ValCurs>`)
+	w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+	w.Write([]byte(body))
+}
+
+// writeStream отдает ответ в формате SSE: текст по словам, вызовы инструментов с аргументами в двух частях,
+// затем причину завершения, usage и [DONE].
+func writeStream(w http.ResponseWriter, request *Request, reply Reply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	send := func(payload interface{}) {
+		data, _ := json.Marshal(payload)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(delta map[string]interface{}, finishReason interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion.chunk",
+			"model":   request.Model,
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	send(chunk(map[string]interface{}{"role": "assistant", "content": ""}, nil))
+	for _, word := range strings.SplitAfter(reply.Text, " ") {
+		if word != "" {
+			send(chunk(map[string]interface{}{"content": word}, nil))
+		}
+	}
+	for i, call := range replyToolCalls(reply) {
+		half := len(call.Arguments) / 2
+		send(chunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i, "id": call.ID, "type": "function",
+			"function": map[string]interface{}{"name": call.Name, "arguments": call.Arguments[:half]},
+		}}}, nil))
+		send(chunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i, "function": map[string]interface{}{"arguments": call.Arguments[half:]},
+		}}}, nil))
+	}
+
+	if reply.StreamError {
+		send(map[string]interface{}{"error": map[string]interface{}{"code": reply.ErrorCode, "message": reply.ErrorMessage}})
+		return
+	}
+
+	send(chunk(map[string]interface{}{}, replyFinishReason(reply)))
+	send(map[string]interface{}{"id": "chatcmpl-test", "object": "chat.completion.chunk", "model": request.Model, "choices": []interface{}{}, "usage": replyUsage(reply)})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// completionChoice собирает вариант ответа для обычного (не потокового) запроса.
+func completionChoice(reply Reply) map[string]interface{} {
+	message := map[string]interface{}{"role": "assistant", "content": reply.Text}
+	if calls := replyToolCalls(reply); len(calls) > 0 {
+		toolCalls := make([]interface{}, len(calls))
+		for i, call := range calls {
+			toolCalls[i] = map[string]interface{}{
+				"id": call.ID, "type": "function",
+				"function": map[string]interface{}{"name": call.Name, "arguments": call.Arguments},
+			}
+		}
+		message["tool_calls"] = toolCalls
+		if reply.Text == "" {
+			message["content"] = nil
+		}
+	}
+	return map[string]interface{}{"index": 0, "message": message, "finish_reason": replyFinishReason(reply)}
+}
+
+// replyToolCalls возвращает вызовы инструментов ответа с заполненными ID и аргументами.
+func replyToolCalls(reply Reply) []ToolCall {
+	calls := make([]ToolCall, len(reply.ToolCalls))
+	for i, call := range reply.ToolCalls {
+		calls[i] = call
+		if calls[i].ID == "" {
+			calls[i].ID = fmt.Sprintf("call_%d", i+1)
+		}
+		if calls[i].Arguments == "" {
+			calls[i].Arguments = "{}"
+		}
+	}
+	return calls
+}
+
+// replyFinishReason возвращает причину завершения ответа.
+func replyFinishReason(reply Reply) string {
+	switch {
+	case reply.FinishReason != "":
+		return reply.FinishReason
+	case len(reply.ToolCalls) > 0:
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
+
+// replyUsage возвращает usage ответа. По умолчанию запрос считается за 10 токенов, а каждое слово ответа
+// и каждый вызов инструмента - за один токен.
+func replyUsage(reply Reply) map[string]interface{} {
+	if reply.Usage != nil {
+		return reply.Usage
+	}
+	completionTokens := len(strings.Fields(reply.Text)) + len(reply.ToolCalls)
+	return map[string]interface{}{
+		"prompt_tokens":     10,
+		"completion_tokens": completionTokens,
+		"total_tokens":      10 + completionTokens,
+	}
+}
+
+// writeError отвечает ошибкой в формате OpenAI.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{"code": code, "message": message, "type": "providertest_error"},
+	})
+}
+
+// writeJSON отвечает телом в JSON.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// wireRequest тело запроса к /chat/completions в объеме, нужном для проверок.
+type wireRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCallID string          `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// parseRequest разбирает тело запроса к /chat/completions.
+func parseRequest(header http.Header, body []byte) (*Request, error) {
+	var wire wireRequest
+	if err := json.Unmarshal(body, &wire); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	request := &Request{Header: header.Clone(), Model: wire.Model, Stream: wire.Stream}
+	if err := json.Unmarshal(body, &request.Body); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+
+	for _, wireMessage := range wire.Messages {
+		message := Message{Role: wireMessage.Role, Content: contentText(wireMessage.Content), ToolCallID: wireMessage.ToolCallID}
+		for _, call := range wireMessage.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+		}
+		request.Messages = append(request.Messages, message)
+	}
+	for _, tool := range wire.Tools {
+		request.Tools = append(request.Tools, tool.Function.Name)
+	}
+	return request, nil
+}
+
+// contentText извлекает текст из content сообщения: строки или массива частей.
+func contentText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &parts)
+	var builder strings.Builder
+	for _, part := range parts {
+		if part.Type == "text" {
+			builder.WriteString(part.Text)
+		}
+	}
+	return builder.String()
+}
+
+// redirectTransport отправляет запросы к любому хосту на target.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+// RoundTrip заменяет схему и хост запроса и передает его дальше.
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = t.target.Scheme
+	redirected.URL.Host = t.target.Host
+	redirected.Host = t.target.Host
+	return t.next.RoundTrip(redirected)
+}